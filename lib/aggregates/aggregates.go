@@ -0,0 +1,270 @@
+package aggregates
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/bolt11"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+const (
+	KindRepost        = 6
+	KindReaction      = 7
+	KindGenericRepost = 16
+	KindZapReceipt    = 9735
+)
+
+var aggregatedKinds = []int{KindRepost, KindReaction, KindGenericRepost, KindZapReceipt}
+
+// Stores and deletes of aggregated kinds hold the read lock while they update the store and the totals,
+// a rebuild holds the write lock so an event is never missed or counted twice while the totals are recalculated
+var rebuildMutex sync.RWMutex
+
+// Changes returns what an event adds to the totals of each event it references, every distinct e tag is credited
+// so the totals match what a NIP-45 COUNT with #e would find, nil is returned for events that aren't aggregated
+// and the counts are negated when the event is being removed
+func Changes(event *nostr.Event, removed bool) []*types.EventAggregate {
+	if !slices.Contains(aggregatedKinds, event.Kind) {
+		return nil
+	}
+
+	var sign int64 = 1
+	if removed {
+		sign = -1
+	}
+
+	var zapMsats int64
+	if event.Kind == KindZapReceipt {
+		if invoiceTag := event.Tags.GetFirst([]string{"bolt11", ""}); invoiceTag != nil {
+			if invoice, err := bolt11.Decode(invoiceTag.Value()); err == nil {
+				zapMsats = invoice.AmountMsat
+			}
+		}
+	}
+
+	// An empty reaction is a like
+	content := event.Content
+	if content == "" {
+		content = "+"
+	}
+
+	var changes []*types.EventAggregate
+	for _, target := range referencedEvents(event) {
+		change := &types.EventAggregate{EventID: target}
+
+		switch event.Kind {
+		case KindReaction:
+			change.Reactions = sign
+			change.ReactionCounts = map[string]int64{content: sign}
+		case KindRepost:
+			change.Reposts = sign
+		case KindGenericRepost:
+			change.GenericReposts = sign
+		case KindZapReceipt:
+			change.Zaps = sign
+			change.ZapMsats = sign * zapMsats
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// Apply updates the totals of the events referenced by a stored or deleted event
+func Apply(stats stores.StatisticsStore, event *nostr.Event, removed bool) {
+	if stats == nil {
+		return
+	}
+
+	for _, change := range Changes(event, removed) {
+		if err := stats.UpdateEventAggregate(change); err != nil {
+			log.Printf("Error updating aggregates for event %s: %v", change.EventID, err)
+		}
+	}
+}
+
+// Exclude takes events that are left out of query results off an aggregate so the totals served match a REQ
+func Exclude(aggregate *types.EventAggregate, events []*nostr.Event) {
+	for _, event := range events {
+		for _, change := range Changes(event, true) {
+			if change.EventID != aggregate.EventID {
+				continue
+			}
+
+			aggregate.Reactions += change.Reactions
+			aggregate.Reposts += change.Reposts
+			aggregate.GenericReposts += change.GenericReposts
+			aggregate.Zaps += change.Zaps
+			aggregate.ZapMsats += change.ZapMsats
+
+			if aggregate.ReactionCounts == nil {
+				aggregate.ReactionCounts = map[string]int64{}
+			}
+			for content, count := range change.ReactionCounts {
+				aggregate.ReactionCounts[content] += count
+				if aggregate.ReactionCounts[content] <= 0 {
+					delete(aggregate.ReactionCounts, content)
+				}
+			}
+		}
+	}
+}
+
+// Kinds returns the kinds that are kept in the totals
+func Kinds() []int {
+	return slices.Clone(aggregatedKinds)
+}
+
+// StoreEvent stores an event and adds it to the totals of the events it references, handlers use it in place
+// of the store so events that were refused or are duplicates are never counted
+func StoreEvent(store stores.Store, event *nostr.Event) error {
+	if !slices.Contains(aggregatedKinds, event.Kind) {
+		return store.StoreEvent(event)
+	}
+
+	rebuildMutex.RLock()
+	defer rebuildMutex.RUnlock()
+
+	if err := store.StoreEvent(event); err != nil {
+		return err
+	}
+
+	Apply(store.GetStatsStore(), event, false)
+
+	return nil
+}
+
+// DeleteEvent deletes an event and takes it off the totals of the events it references
+func DeleteEvent(store stores.Store, event *nostr.Event) error {
+	if !slices.Contains(aggregatedKinds, event.Kind) {
+		return store.DeleteEvent(event.ID)
+	}
+
+	rebuildMutex.RLock()
+	defer rebuildMutex.RUnlock()
+
+	if err := store.DeleteEvent(event.ID); err != nil {
+		return err
+	}
+
+	Apply(store.GetStatsStore(), event, true)
+
+	return nil
+}
+
+// Count answers a COUNT filter from the aggregates, the second value is false when the filter asks for
+// more than the reactions, reposts or zaps of specific events and has to be counted with a query
+func Count(stats stores.StatisticsStore, filter nostr.Filter) (int64, bool) {
+	if stats == nil || len(filter.Kinds) == 0 || len(filter.IDs) > 0 || len(filter.Authors) > 0 {
+		return 0, false
+	}
+
+	if filter.Since != nil || filter.Until != nil || filter.Limit > 0 || filter.Search != "" {
+		return 0, false
+	}
+
+	// An event referencing several of the requested events is only counted once by a query but would be
+	// in the totals of each of them, so only filters for a single event are answered from the totals
+	if len(filter.Tags) != 1 || len(filter.Tags["e"]) != 1 {
+		return 0, false
+	}
+
+	for _, kind := range filter.Kinds {
+		if !slices.Contains(aggregatedKinds, kind) {
+			return 0, false
+		}
+	}
+
+	aggregate, err := stats.GetEventAggregate(filter.Tags["e"][0])
+	if err != nil {
+		return 0, false
+	}
+
+	var count int64
+	for _, kind := range uniqueKinds(filter.Kinds) {
+		switch kind {
+		case KindReaction:
+			count += aggregate.Reactions
+		case KindRepost:
+			count += aggregate.Reposts
+		case KindGenericRepost:
+			count += aggregate.GenericReposts
+		case KindZapReceipt:
+			count += aggregate.Zaps
+		}
+	}
+
+	return count, true
+}
+
+// Rebuild recalculates every aggregate from the stored reactions, reposts and zap receipts
+func Rebuild(store stores.Store) (int, error) {
+	stats := store.GetStatsStore()
+	if stats == nil {
+		return 0, fmt.Errorf("aggregates need a statistics store")
+	}
+
+	rebuildMutex.Lock()
+	defer rebuildMutex.Unlock()
+
+	if err := stats.ClearEventAggregates(); err != nil {
+		return 0, fmt.Errorf("failed to clear aggregates: %v", err)
+	}
+
+	filter := nostr.Filter{Kinds: aggregatedKinds}
+
+	events, err := store.QueryEvents(filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query events: %v", err)
+	}
+
+	seen := map[string]bool{}
+
+	applied := 0
+	for _, event := range events {
+		if seen[event.ID] || !filter.Matches(event) {
+			continue
+		}
+		seen[event.ID] = true
+
+		changes := Changes(event, false)
+		if len(changes) == 0 {
+			continue
+		}
+
+		for _, change := range changes {
+			if err := stats.UpdateEventAggregate(change); err != nil {
+				return applied, fmt.Errorf("failed to update aggregates for event %s: %v", change.EventID, err)
+			}
+		}
+
+		applied++
+	}
+
+	return applied, nil
+}
+
+func referencedEvents(event *nostr.Event) []string {
+	var ids []string
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "e" && !slices.Contains(ids, tag[1]) {
+			ids = append(ids, tag[1])
+		}
+	}
+
+	return ids
+}
+
+func uniqueKinds(kinds []int) []int {
+	unique := slices.Clone(kinds)
+	slices.Sort(unique)
+
+	return slices.Compact(unique)
+}
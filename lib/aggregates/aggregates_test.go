@@ -0,0 +1,276 @@
+package aggregates
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Keeps the totals in memory the way the statistics store adds them up
+type aggregateStats struct {
+	stores.StatisticsStore
+
+	mutex      sync.Mutex
+	aggregates map[string]*types.EventAggregate
+}
+
+func newStats() *aggregateStats {
+	return &aggregateStats{aggregates: map[string]*types.EventAggregate{}}
+}
+
+func (stats *aggregateStats) UpdateEventAggregate(change *types.EventAggregate) error {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	aggregate, ok := stats.aggregates[change.EventID]
+	if !ok {
+		aggregate = &types.EventAggregate{EventID: change.EventID, ReactionCounts: map[string]int64{}}
+		stats.aggregates[change.EventID] = aggregate
+	}
+
+	aggregate.Reactions += change.Reactions
+	aggregate.Reposts += change.Reposts
+	aggregate.GenericReposts += change.GenericReposts
+	aggregate.Zaps += change.Zaps
+	aggregate.ZapMsats += change.ZapMsats
+	for content, count := range change.ReactionCounts {
+		aggregate.ReactionCounts[content] += count
+	}
+
+	return nil
+}
+
+func (stats *aggregateStats) GetEventAggregate(eventID string) (*types.EventAggregate, error) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	if aggregate, ok := stats.aggregates[eventID]; ok {
+		copied := *aggregate
+		return &copied, nil
+	}
+
+	return &types.EventAggregate{EventID: eventID, ReactionCounts: map[string]int64{}}, nil
+}
+
+func (stats *aggregateStats) ClearEventAggregates() error {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	stats.aggregates = map[string]*types.EventAggregate{}
+	return nil
+}
+
+// Only the event methods of the store are implemented
+type eventStore struct {
+	stores.Store
+
+	stats  *aggregateStats
+	mutex  sync.Mutex
+	events map[string]*nostr.Event
+}
+
+func newStore() *eventStore {
+	return &eventStore{stats: newStats(), events: map[string]*nostr.Event{}}
+}
+
+func (store *eventStore) GetStatsStore() stores.StatisticsStore {
+	return store.stats
+}
+
+func (store *eventStore) StoreEvent(event *nostr.Event) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.events[event.ID] = event
+	return nil
+}
+
+func (store *eventStore) DeleteEvent(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.events, id)
+	return nil
+}
+
+func (store *eventStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	events := []*nostr.Event{}
+	for _, event := range store.events {
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func TestChanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   *nostr.Event
+		targets []string
+		check   func(change *types.EventAggregate) bool
+	}{
+		{
+			"like",
+			&nostr.Event{Kind: KindReaction, Tags: nostr.Tags{{"e", "note"}}},
+			[]string{"note"},
+			func(change *types.EventAggregate) bool {
+				return change.Reactions == 1 && change.ReactionCounts["+"] == 1
+			},
+		},
+		{
+			"reply reaction credits every referenced event",
+			&nostr.Event{Kind: KindReaction, Content: "🤙", Tags: nostr.Tags{{"e", "root"}, {"e", "note"}, {"e", "note"}}},
+			[]string{"root", "note"},
+			func(change *types.EventAggregate) bool { return change.ReactionCounts["🤙"] == 1 },
+		},
+		{
+			"repost",
+			&nostr.Event{Kind: KindRepost, Tags: nostr.Tags{{"e", "note"}, {"p", "author"}}},
+			[]string{"note"},
+			func(change *types.EventAggregate) bool { return change.Reposts == 1 && change.GenericReposts == 0 },
+		},
+		{
+			"generic repost",
+			&nostr.Event{Kind: KindGenericRepost, Tags: nostr.Tags{{"e", "note"}}},
+			[]string{"note"},
+			func(change *types.EventAggregate) bool { return change.GenericReposts == 1 && change.Reposts == 0 },
+		},
+		{
+			"zap without an invoice amount",
+			&nostr.Event{Kind: KindZapReceipt, Tags: nostr.Tags{{"e", "note"}, {"bolt11", "invalid"}}},
+			[]string{"note"},
+			func(change *types.EventAggregate) bool { return change.Zaps == 1 && change.ZapMsats == 0 },
+		},
+		{"profile zap", &nostr.Event{Kind: KindZapReceipt, Tags: nostr.Tags{{"p", "author"}}}, nil, nil},
+		{"not aggregated", &nostr.Event{Kind: 1, Tags: nostr.Tags{{"e", "note"}}}, nil, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes := Changes(test.event, false)
+			if len(changes) != len(test.targets) {
+				t.Fatalf("expected %d changes, got %d", len(test.targets), len(changes))
+			}
+
+			for i, change := range changes {
+				if change.EventID != test.targets[i] {
+					t.Errorf("expected %s to be credited, got %s", test.targets[i], change.EventID)
+				}
+
+				if !test.check(change) {
+					t.Errorf("unexpected change %+v", change)
+				}
+			}
+
+			// Removing the event takes off exactly what storing it added
+			for i, change := range Changes(test.event, true) {
+				added := changes[i]
+				if change.Reactions != -added.Reactions || change.Reposts != -added.Reposts ||
+					change.GenericReposts != -added.GenericReposts || change.Zaps != -added.Zaps {
+					t.Errorf("expected the removal to negate %+v, got %+v", added, change)
+				}
+			}
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	store := newStore()
+	StoreEvent(store, &nostr.Event{ID: "r1", Kind: KindReaction, Tags: nostr.Tags{{"e", "root"}, {"e", "note"}}})
+	StoreEvent(store, &nostr.Event{ID: "r2", Kind: KindReaction, Tags: nostr.Tags{{"e", "note"}}})
+	StoreEvent(store, &nostr.Event{ID: "s1", Kind: KindRepost, Tags: nostr.Tags{{"e", "note"}}})
+
+	tests := []struct {
+		name   string
+		filter nostr.Filter
+		count  int64
+		ok     bool
+	}{
+		{"reactions", nostr.Filter{Kinds: []int{KindReaction}, Tags: nostr.TagMap{"e": {"note"}}}, 2, true},
+		{"reactions to the root of a thread", nostr.Filter{Kinds: []int{KindReaction}, Tags: nostr.TagMap{"e": {"root"}}}, 1, true},
+		{"reactions and reposts", nostr.Filter{Kinds: []int{KindReaction, KindRepost, KindReaction}, Tags: nostr.TagMap{"e": {"note"}}}, 3, true},
+		{"several events", nostr.Filter{Kinds: []int{KindReaction}, Tags: nostr.TagMap{"e": {"note", "root"}}}, 0, false},
+		{"other kinds", nostr.Filter{Kinds: []int{1}, Tags: nostr.TagMap{"e": {"note"}}}, 0, false},
+		{"authors", nostr.Filter{Kinds: []int{KindReaction}, Authors: []string{"alice"}, Tags: nostr.TagMap{"e": {"note"}}}, 0, false},
+		{"other tags", nostr.Filter{Kinds: []int{KindReaction}, Tags: nostr.TagMap{"e": {"note"}, "p": {"alice"}}}, 0, false},
+		{"no kinds", nostr.Filter{Tags: nostr.TagMap{"e": {"note"}}}, 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			count, ok := Count(store.stats, test.filter)
+			if ok != test.ok || count != test.count {
+				t.Errorf("expected %d (%v), got %d (%v)", test.count, test.ok, count, ok)
+			}
+
+			// The totals agree with counting the matching events
+			if ok {
+				events, _ := store.QueryEvents(test.filter)
+				if int64(len(events)) != count {
+					t.Errorf("expected the query to find %d events, got %d", count, len(events))
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteEvent(t *testing.T) {
+	store := newStore()
+
+	reaction := &nostr.Event{ID: "r1", Kind: KindReaction, Tags: nostr.Tags{{"e", "note"}}}
+	StoreEvent(store, reaction)
+	StoreEvent(store, &nostr.Event{ID: "n1", Kind: 1, Tags: nostr.Tags{{"e", "note"}}})
+
+	if err := DeleteEvent(store, reaction); err != nil {
+		t.Fatalf("failed to delete event: %v", err)
+	}
+
+	aggregate, _ := store.stats.GetEventAggregate("note")
+	if aggregate.Reactions != 0 || aggregate.ReactionCounts["+"] != 0 {
+		t.Errorf("expected the reaction to be taken off, got %+v", aggregate)
+	}
+
+	if _, ok := store.events[reaction.ID]; ok {
+		t.Errorf("expected the reaction to be deleted")
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	store := newStore()
+	for _, id := range []string{"a", "b", "c"} {
+		store.StoreEvent(&nostr.Event{ID: id, Kind: KindReaction, Tags: nostr.Tags{{"e", "note"}}})
+	}
+	store.StoreEvent(&nostr.Event{ID: "d", Kind: KindReaction})
+
+	// Totals that drifted are replaced
+	store.stats.UpdateEventAggregate(&types.EventAggregate{EventID: "note", Reactions: 10})
+
+	// Events stored while the totals are rebuilt are counted exactly once
+	var wait sync.WaitGroup
+	for _, id := range []string{"e", "f", "g", "h"} {
+		wait.Add(1)
+		go func(id string) {
+			defer wait.Done()
+			StoreEvent(store, &nostr.Event{ID: id, Kind: KindReaction, Tags: nostr.Tags{{"e", "note"}}})
+		}(id)
+	}
+
+	if _, err := Rebuild(store); err != nil {
+		t.Fatalf("failed to rebuild: %v", err)
+	}
+	wait.Wait()
+
+	count, _ := Count(store.stats, nostr.Filter{Kinds: []int{KindReaction}, Tags: nostr.TagMap{"e": {"note"}}})
+	if count != 7 {
+		t.Errorf("expected 7 reactions, got %d", count)
+	}
+}
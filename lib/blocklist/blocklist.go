@@ -0,0 +1,314 @@
+package blocklist
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	merkle_dag "github.com/HORNET-Storage/scionic-merkletree/dag"
+)
+
+const (
+	HashTypeSha256  = "sha256"  // sha256 of a blossom blob or leaf content
+	HashTypeScionic = "scionic" // scionic merkle tree leaf or root hash
+	HashTypeDHash   = "dhash"   // 64 bit perceptual difference hash of an image
+)
+
+// CheckData returns an error if the sha256 of the data is blocked or if the data is an image
+// that is perceptually similar to a blocked image
+func CheckData(stats stores.StatisticsStore, data []byte) error {
+	// Stores without a statistics database (such as the memory store) have no blocklist
+	if stats == nil {
+		return nil
+	}
+
+	hash := sha256.Sum256(data)
+	if err := CheckHash(stats, hex.EncodeToString(hash[:])); err != nil {
+		return err
+	}
+
+	blockedDHashes, err := stats.GetBlockedHashes(HashTypeDHash)
+	if err != nil {
+		return fmt.Errorf("failed to check blocklist: %v", err)
+	}
+
+	// Only images need to be decoded and only when there are perceptual hashes to compare against
+	if len(blockedDHashes) <= 0 {
+		return nil
+	}
+
+	dHash, err := ComputeDHash(data)
+	if err != nil {
+		return nil
+	}
+
+	threshold := viper.GetInt("blocklist_dhash_threshold")
+	for _, blocked := range blockedDHashes {
+		blockedHash, err := strconv.ParseUint(blocked.Hash, 16, 64)
+		if err != nil {
+			continue
+		}
+
+		if bits.OnesCount64(dHash^blockedHash) <= threshold {
+			return fmt.Errorf("blocked: image matches a blocked image")
+		}
+	}
+
+	return nil
+}
+
+// CheckLeaf returns an error if the leaf hash or the hash of its content is blocked
+func CheckLeaf(stats stores.StatisticsStore, leaf *merkle_dag.DagLeaf) error {
+	if err := CheckHash(stats, strings.ToLower(merkle_dag.GetHash(leaf.Hash))); err != nil {
+		return err
+	}
+
+	if leaf.ContentHash != nil {
+		if err := CheckHash(stats, hex.EncodeToString(leaf.ContentHash)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckHash returns an error if the exact hash is blocked
+func CheckHash(stats stores.StatisticsStore, hash string) error {
+	if stats == nil {
+		return nil
+	}
+
+	blocked, err := stats.IsHashBlocked(strings.ToLower(hash))
+	if err != nil {
+		return fmt.Errorf("failed to check blocklist: %v", err)
+	}
+
+	if blocked {
+		return fmt.Errorf("blocked: %s is on the blocklist", hash)
+	}
+
+	return nil
+}
+
+// Add a hash to the blocklist and record who added it
+func Add(stats stores.StatisticsStore, hashType string, hash string, reason string, actor string) error {
+	hash, err := normalizeHash(hashType, hash)
+	if err != nil {
+		return err
+	}
+
+	err = stats.AddBlockedHash(&types.BlockedHash{
+		Hash:     hash,
+		HashType: hashType,
+		Reason:   reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	return stats.SaveBlocklistAudit(&types.BlocklistAudit{
+		Action:   "add",
+		Hash:     hash,
+		HashType: hashType,
+		Reason:   reason,
+		Actor:    actor,
+	})
+}
+
+// Remove a hash from the blocklist and record who removed it
+func Remove(stats stores.StatisticsStore, hash string, actor string) error {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+
+	if err := stats.RemoveBlockedHash(hash); err != nil {
+		return err
+	}
+
+	return stats.SaveBlocklistAudit(&types.BlocklistAudit{
+		Action: "remove",
+		Hash:   hash,
+		Actor:  actor,
+	})
+}
+
+// Import hashes from a text file with one entry per line in the form "<type> <hash> [reason]"
+// A line containing only a hash is treated as a sha256 hash, empty lines and lines starting with # are ignored
+// Hashes that are already blocked are skipped so importing the same file again changes nothing and isn't audited
+func Import(stats stores.StatisticsStore, reader io.Reader, actor string) (int, error) {
+	scanner := bufio.NewScanner(reader)
+
+	imported := 0
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		hashType := HashTypeSha256
+		hash := fields[0]
+		reason := ""
+
+		if len(fields) > 1 {
+			hashType = strings.ToLower(fields[0])
+			hash = fields[1]
+			reason = strings.Join(fields[2:], " ")
+		}
+
+		hash, err := normalizeHash(hashType, hash)
+		if err != nil {
+			log.Printf("Skipping blocklist entry on line %d: %v", lineNumber, err)
+			continue
+		}
+
+		blocked, err := stats.IsHashBlocked(hash)
+		if err != nil {
+			return imported, err
+		}
+
+		if blocked {
+			continue
+		}
+
+		err = stats.AddBlockedHash(&types.BlockedHash{
+			Hash:     hash,
+			HashType: hashType,
+			Reason:   reason,
+		})
+		if err != nil {
+			return imported, err
+		}
+
+		imported++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return imported, err
+	}
+
+	if imported <= 0 {
+		return 0, nil
+	}
+
+	err := stats.SaveBlocklistAudit(&types.BlocklistAudit{
+		Action: "import",
+		Reason: fmt.Sprintf("imported %d entries", imported),
+		Actor:  actor,
+	})
+
+	return imported, err
+}
+
+// ComputeDHash calculates the difference hash of an image by shrinking it to 9x8 grayscale pixels
+// and setting a bit for every pixel that is brighter than its right hand neighbour
+func ComputeDHash(data []byte) (uint64, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() <= 0 || bounds.Dy() <= 0 {
+		return 0, fmt.Errorf("image has no pixels")
+	}
+
+	const width, height = 9, 8
+
+	var pixels [height][width]float64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixels[y][x] = averageLuminance(img, bounds, x, y, width, height)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width-1; x++ {
+			hash <<= 1
+			if pixels[y][x] > pixels[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash, nil
+}
+
+// Average the luminance of every source pixel that falls within the target cell (box sampling)
+func averageLuminance(img image.Image, bounds image.Rectangle, x int, y int, width int, height int) float64 {
+	x0 := bounds.Min.X + x*bounds.Dx()/width
+	x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+	y0 := bounds.Min.Y + y*bounds.Dy()/height
+	y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+
+	// Images smaller than the target still need at least one pixel per cell
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+
+	var total float64
+	var count float64
+	for py := y0; py < y1 && py < bounds.Max.Y; py++ {
+		for px := x0; px < x1 && px < bounds.Max.X; px++ {
+			r, g, b, _ := img.At(px, py).RGBA()
+			total += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			count++
+		}
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	return total / count
+}
+
+// Hashes are stored lower case, scionic hashes have their label removed and dhashes are zero padded
+func normalizeHash(hashType string, hash string) (string, error) {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+
+	switch hashType {
+	case HashTypeSha256:
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 64 {
+			return "", fmt.Errorf("invalid sha256 hash: %s", hash)
+		}
+
+		return hash, nil
+	case HashTypeScionic:
+		if hash == "" {
+			return "", fmt.Errorf("invalid scionic hash")
+		}
+
+		return merkle_dag.GetHash(hash), nil
+	case HashTypeDHash:
+		value, err := strconv.ParseUint(hash, 16, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid dhash: %s", hash)
+		}
+
+		return fmt.Sprintf("%016x", value), nil
+	}
+
+	return "", fmt.Errorf("unknown hash type: %s", hashType)
+}
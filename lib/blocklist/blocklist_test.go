@@ -0,0 +1,164 @@
+package blocklist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	merkle_dag "github.com/HORNET-Storage/scionic-merkletree/dag"
+)
+
+// Only the blocklist part of the statistics store is implemented
+type blocklistStats struct {
+	stores.StatisticsStore
+
+	hashes map[string]types.BlockedHash
+	audit  []types.BlocklistAudit
+}
+
+func newStats() *blocklistStats {
+	return &blocklistStats{hashes: map[string]types.BlockedHash{}}
+}
+
+func (stats *blocklistStats) AddBlockedHash(blockedHash *types.BlockedHash) error {
+	stats.hashes[blockedHash.Hash] = *blockedHash
+	return nil
+}
+
+func (stats *blocklistStats) RemoveBlockedHash(hash string) error {
+	delete(stats.hashes, hash)
+	return nil
+}
+
+func (stats *blocklistStats) IsHashBlocked(hash string) (bool, error) {
+	_, ok := stats.hashes[hash]
+	return ok, nil
+}
+
+func (stats *blocklistStats) GetBlockedHashes(hashType string) ([]types.BlockedHash, error) {
+	blockedHashes := []types.BlockedHash{}
+	for _, blockedHash := range stats.hashes {
+		if hashType == "" || blockedHash.HashType == hashType {
+			blockedHashes = append(blockedHashes, blockedHash)
+		}
+	}
+
+	return blockedHashes, nil
+}
+
+func (stats *blocklistStats) SaveBlocklistAudit(entry *types.BlocklistAudit) error {
+	stats.audit = append(stats.audit, *entry)
+	return nil
+}
+
+func sha256Hex(data string) string {
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:])
+}
+
+func TestNormalizeHash(t *testing.T) {
+	tests := []struct {
+		name     string
+		hashType string
+		hash     string
+		expected string
+		valid    bool
+	}{
+		{"sha256 upper case", HashTypeSha256, strings.ToUpper(sha256Hex("a")), sha256Hex("a"), true},
+		{"sha256 too short", HashTypeSha256, "abcd", "", false},
+		{"sha256 not hex", HashTypeSha256, strings.Repeat("z", 64), "", false},
+		{"scionic with label", HashTypeScionic, "3:abcdef", merkle_dag.GetHash("3:abcdef"), true},
+		{"scionic empty", HashTypeScionic, " ", "", false},
+		{"dhash zero padded", HashTypeDHash, "ff", "00000000000000ff", true},
+		{"dhash not hex", HashTypeDHash, "xyz", "", false},
+		{"unknown type", "md5", sha256Hex("a"), "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hash, err := normalizeHash(test.hashType, test.hash)
+			if (err == nil) != test.valid {
+				t.Fatalf("expected valid %v, got error %v", test.valid, err)
+			}
+
+			if hash != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, hash)
+			}
+		})
+	}
+}
+
+func TestCheckLeaf(t *testing.T) {
+	stats := newStats()
+	Add(stats, HashTypeScionic, "blocked-leaf", "", "test")
+	Add(stats, HashTypeSha256, sha256Hex("blocked content"), "", "test")
+
+	blockedContent := sha256.Sum256([]byte("blocked content"))
+	allowedContent := sha256.Sum256([]byte("allowed content"))
+
+	tests := []struct {
+		name    string
+		stats   stores.StatisticsStore
+		leaf    merkle_dag.DagLeaf
+		blocked bool
+	}{
+		{"allowed leaf", stats, merkle_dag.DagLeaf{Hash: "allowed-leaf", ContentHash: allowedContent[:]}, false},
+		{"blocked leaf hash", stats, merkle_dag.DagLeaf{Hash: "blocked-leaf"}, true},
+		{"blocked leaf hash with a label", stats, merkle_dag.DagLeaf{Hash: "2:blocked-leaf"}, true},
+		{"blocked content", stats, merkle_dag.DagLeaf{Hash: "allowed-leaf", ContentHash: blockedContent[:]}, true},
+		{"store without a blocklist", nil, merkle_dag.DagLeaf{Hash: "blocked-leaf"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckLeaf(test.stats, &test.leaf)
+			if (err != nil) != test.blocked {
+				t.Errorf("expected blocked %v, got error %v", test.blocked, err)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	stats := newStats()
+
+	file := strings.Join([]string{
+		"# known bad hashes",
+		sha256Hex("a"),
+		"scionic 1:root spam",
+		"dhash ff",
+		"",
+		"sha256 not-a-hash",
+	}, "\n")
+
+	tests := []struct {
+		name     string
+		file     string
+		imported int
+		audit    int
+	}{
+		{"first import", file, 3, 1},
+		{"same file again", file, 0, 1},
+		{"one new entry", file + "\n" + sha256Hex("b"), 1, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			imported, err := Import(stats, strings.NewReader(test.file), "config")
+			if err != nil {
+				t.Fatalf("failed to import: %v", err)
+			}
+
+			if imported != test.imported {
+				t.Errorf("expected %d entries imported, got %d", test.imported, imported)
+			}
+
+			if len(stats.audit) != test.audit {
+				t.Errorf("expected %d audit entries, got %d", test.audit, len(stats.audit))
+			}
+		})
+	}
+}
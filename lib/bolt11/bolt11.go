@@ -0,0 +1,167 @@
+package bolt11
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+)
+
+// Invoice holds the parts of a bolt11 invoice needed to validate a zap receipt
+type Invoice struct {
+	AmountMsat      int64
+	Timestamp       int64
+	PaymentHash     []byte
+	Description     string
+	DescriptionHash []byte
+}
+
+// Tagged field types from bolt11
+const (
+	fieldPaymentHash     = 1
+	fieldDescription     = 13
+	fieldDescriptionHash = 23
+)
+
+// The timestamp is 35 bits and the signature is 520 bits plus the recovery id, both in 5 bit groups
+const (
+	timestampLength = 7
+	signatureLength = 104
+)
+
+// Millisatoshis per unit of each amount multiplier, pico bitcoin is a tenth of a millisatoshi
+var multipliers = map[byte]int64{
+	'm': 100_000_000,
+	'u': 100_000,
+	'n': 100,
+}
+
+// Decode parses a bolt11 invoice, the signature is not checked as callers only need the amount and descriptions
+func Decode(invoice string) (*Invoice, error) {
+	invoice = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(invoice)), "lightning:")
+
+	hrp, data, err := bech32.DecodeNoLimit(invoice)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bolt11 invoice: %v", err)
+	}
+
+	if !strings.HasPrefix(hrp, "ln") {
+		return nil, fmt.Errorf("invalid bolt11 invoice: unexpected prefix %s", hrp)
+	}
+
+	amount, err := parseAmount(hrp[2:])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < timestampLength+signatureLength {
+		return nil, fmt.Errorf("invalid bolt11 invoice: too short")
+	}
+
+	decoded := &Invoice{
+		AmountMsat: amount,
+		Timestamp:  int64(groupsToUint(data[:timestampLength])),
+	}
+
+	fields := data[timestampLength : len(data)-signatureLength]
+	for len(fields) >= 3 {
+		fieldType := fields[0]
+		fieldLength := int(groupsToUint(fields[1:3]))
+		fields = fields[3:]
+
+		if fieldLength > len(fields) {
+			return nil, fmt.Errorf("invalid bolt11 invoice: field length exceeds data")
+		}
+
+		value := fields[:fieldLength]
+		fields = fields[fieldLength:]
+
+		switch fieldType {
+		case fieldPaymentHash, fieldDescriptionHash:
+			// Fields with the wrong length must be skipped rather than rejected
+			if fieldLength != 52 {
+				continue
+			}
+
+			bytes, err := bech32.ConvertBits(value, 5, 8, false)
+			if err != nil {
+				return nil, fmt.Errorf("invalid bolt11 invoice: %v", err)
+			}
+
+			if fieldType == fieldPaymentHash {
+				decoded.PaymentHash = bytes
+			} else {
+				decoded.DescriptionHash = bytes
+			}
+		case fieldDescription:
+			bytes, err := bech32.ConvertBits(value, 5, 8, false)
+			if err != nil {
+				return nil, fmt.Errorf("invalid bolt11 invoice: %v", err)
+			}
+
+			decoded.Description = string(bytes)
+		}
+	}
+
+	return decoded, nil
+}
+
+// The human readable part is the network followed by an optional amount and multiplier
+func parseAmount(hrp string) (int64, error) {
+	start := strings.IndexAny(hrp, "0123456789")
+	if start == -1 {
+		return 0, nil
+	}
+
+	amount := hrp[start:]
+	multiplier := amount[len(amount)-1]
+
+	if multiplier >= '0' && multiplier <= '9' {
+		value, err := strconv.ParseInt(amount, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid bolt11 amount: %s", amount)
+		}
+
+		return toMsats(amount, value, 100_000_000_000)
+	}
+
+	value, err := strconv.ParseInt(amount[:len(amount)-1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bolt11 amount: %s", amount)
+	}
+
+	if multiplier == 'p' {
+		if value%10 != 0 {
+			return 0, fmt.Errorf("invalid bolt11 amount: %s is not a whole number of millisatoshis", amount)
+		}
+
+		return value / 10, nil
+	}
+
+	msats, ok := multipliers[multiplier]
+	if !ok {
+		return 0, fmt.Errorf("invalid bolt11 amount multiplier: %c", multiplier)
+	}
+
+	return toMsats(amount, value, msats)
+}
+
+// Amounts too large to fit in millisatoshis are refused rather than wrapping around
+func toMsats(amount string, value int64, msats int64) (int64, error) {
+	if value > math.MaxInt64/msats {
+		return 0, fmt.Errorf("invalid bolt11 amount: %s is too large", amount)
+	}
+
+	return value * msats, nil
+}
+
+func groupsToUint(groups []byte) uint64 {
+	var value uint64
+	for _, group := range groups {
+		value = value<<5 | uint64(group)
+	}
+
+	return value
+}
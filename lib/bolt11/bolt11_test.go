@@ -0,0 +1,130 @@
+package bolt11
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+)
+
+func field(t *testing.T, fieldType byte, value []byte) []byte {
+	groups, err := bech32.ConvertBits(value, 8, 5, true)
+	if err != nil {
+		t.Fatalf("failed to convert field: %v", err)
+	}
+
+	return append([]byte{fieldType, byte(len(groups) >> 5), byte(len(groups) & 31)}, groups...)
+}
+
+// Builds an invoice with a zeroed signature as the signature isn't checked
+func encode(t *testing.T, hrp string, fields ...[]byte) string {
+	data := []byte{0, 0, 0, 0, 0, 0, 1}
+	for _, field := range fields {
+		data = append(data, field...)
+	}
+	data = append(data, make([]byte, signatureLength)...)
+
+	invoice, err := bech32.Encode(hrp, data)
+	if err != nil {
+		t.Fatalf("failed to encode invoice: %v", err)
+	}
+
+	return invoice
+}
+
+func TestDecode(t *testing.T) {
+	paymentHash := sha256.Sum256([]byte("preimage"))
+	descriptionHash := sha256.Sum256([]byte("zap request"))
+
+	tests := []struct {
+		name            string
+		invoice         string
+		valid           bool
+		amount          int64
+		description     string
+		descriptionHash []byte
+	}{
+		{
+			"description hash",
+			encode(t, "lnbc2500u", field(t, fieldPaymentHash, paymentHash[:]), field(t, fieldDescriptionHash, descriptionHash[:])),
+			true, 250_000_000, "", descriptionHash[:],
+		},
+		{
+			"description",
+			encode(t, "lntb10n", field(t, fieldDescription, []byte("coffee"))),
+			true, 1000, "coffee", nil,
+		},
+		{
+			"lightning uri without an amount",
+			"lightning:" + encode(t, "lnbc", field(t, fieldPaymentHash, paymentHash[:])),
+			true, 0, "", nil,
+		},
+		{
+			"hash with the wrong length is skipped",
+			encode(t, "lnbc1m", field(t, fieldDescriptionHash, descriptionHash[:16])),
+			true, 100_000_000, "", nil,
+		},
+		{"not an invoice", encode(t, "bc", field(t, fieldPaymentHash, paymentHash[:])), false, 0, "", nil},
+		{"too short", func() string { invoice, _ := bech32.Encode("lnbc", []byte{1, 2, 3}); return invoice }(), false, 0, "", nil},
+		{"bad checksum", "lnbc1qqqqqqqq", false, 0, "", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			invoice, err := Decode(test.invoice)
+			if (err == nil) != test.valid {
+				t.Fatalf("expected valid %v, got error %v", test.valid, err)
+			}
+
+			if !test.valid {
+				return
+			}
+
+			if invoice.AmountMsat != test.amount {
+				t.Errorf("expected amount %d, got %d", test.amount, invoice.AmountMsat)
+			}
+
+			if invoice.Description != test.description {
+				t.Errorf("expected description %q, got %q", test.description, invoice.Description)
+			}
+
+			if !bytes.Equal(invoice.DescriptionHash, test.descriptionHash) {
+				t.Errorf("expected description hash %x, got %x", test.descriptionHash, invoice.DescriptionHash)
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		hrp    string
+		amount int64
+		valid  bool
+	}{
+		{"bc", 0, true},
+		{"bc1", 100_000_000_000, true},
+		{"bc25m", 2_500_000_000, true},
+		{"bc2500u", 250_000_000, true},
+		{"bc10n", 1000, true},
+		{"bc10p", 1, true},
+		{"bc15p", 0, false},
+		{"bc10x", 0, false},
+		{"bc92233720368", 0, false},
+		{"bc92233720368547758m", 0, false},
+		{"bc99999999999999999999n", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.hrp, func(t *testing.T) {
+			amount, err := parseAmount(test.hrp)
+			if (err == nil) != test.valid {
+				t.Fatalf("expected valid %v, got error %v", test.valid, err)
+			}
+
+			if amount != test.amount {
+				t.Errorf("expected %d, got %d", test.amount, amount)
+			}
+		})
+	}
+}
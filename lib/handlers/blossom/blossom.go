@@ -4,10 +4,16 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"log"
+	"strings"
 
+	"github.com/HORNET-Storage/hornet-storage/lib/blocklist"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind1063"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/uploads"
 	"github.com/gofiber/fiber/v2"
 	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
 )
 
 type Server struct {
@@ -19,13 +25,14 @@ func NewServer(store stores.Store) *Server {
 }
 
 func (s *Server) SetupRoutes(app *fiber.App) {
+	app.Get("/blossom/metadata", s.getFileMetadata)
 	app.Get("/blossom/:hash", s.getBlob)
 	app.Put("/blossom/upload", s.uploadBlob)
 }
 
 func (s *Server) getBlob(c *fiber.Ctx) error {
 	hash := c.Params("hash")
-	data, err := s.storage.GetBlob(hash)
+	data, err := uploads.GetBlob(s.storage, hash)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Blob not found"})
 	}
@@ -48,6 +55,19 @@ func (s *Server) uploadBlob(c *fiber.Ctx) error {
 
 	fmt.Println("Recieved a blossom blob")
 
+	mimeType := strings.TrimSpace(strings.Split(c.Get(fiber.HeaderContentType), ";")[0])
+
+	// Apply the same size, file type and quota checks as the other upload paths
+	err := uploads.CheckUpload(s.storage, pubkey, "", mimeType, int64(len(data)))
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	err = blocklist.CheckData(s.storage.GetStatsStore(), data)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": err.Error()})
+	}
+
 	events, err := s.storage.QueryEvents(filter)
 	if err != nil {
 		return err
@@ -68,12 +88,36 @@ func (s *Server) uploadBlob(c *fiber.Ctx) error {
 	}
 
 	// Store the blob
-	err = s.storage.StoreBlob(data, checkHash[:], pubkey)
+	_, err = uploads.StoreBlob(s.storage, data, mimeType, pubkey)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "failed to store blob"})
 	}
 
+	if err := uploads.RecordUpload(s.storage, pubkey, int64(len(data))); err != nil {
+		log.Printf("Failed to record storage usage for %s: %v", pubkey, err)
+	}
+
+	// Publish a relay signed NIP-94 event describing the blob if enabled
+	if viper.GetBool("nip94_auto_generate") {
+		url := fmt.Sprintf("%s/blossom/%s", c.BaseURL(), encodedHash)
+
+		_, err := kind1063.CreateBlobMetadataEvent(s.storage, data, mimeType, url, pubkey)
+		if err != nil {
+			log.Printf("Failed to create file metadata event for blob %s: %v", encodedHash, err)
+		}
+	}
+
 	fmt.Println("Finished a blossom blob")
 
 	return c.SendStatus(fiber.StatusOK)
 }
+
+// Query NIP-94 file metadata by file hash (x), mime type (m) and uploader pubkey
+func (s *Server) getFileMetadata(c *fiber.Ctx) error {
+	events, err := kind1063.QueryFileMetadata(s.storage, c.Query("x"), c.Query("m"), c.Query("uploader"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "failed to query file metadata"})
+	}
+
+	return c.JSON(events)
+}
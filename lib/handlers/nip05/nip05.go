@@ -0,0 +1,258 @@
+package nip05
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/spf13/viper"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/subscribers"
+)
+
+// NIP-05 only allows a-z0-9-_. in the local part, names are stored lowercase
+var namePattern = regexp.MustCompile(`^[a-z0-9\-_.]+$`)
+
+const maxNameLength = 64
+
+type Server struct {
+	storage stores.Store
+}
+
+// The response format for /.well-known/nostr.json
+type Response struct {
+	Names  map[string]string   `json:"names"`
+	Relays map[string][]string `json:"relays,omitempty"`
+}
+
+type registerRequest struct {
+	Name string `json:"name"`
+}
+
+func NewServer(store stores.Store) *Server {
+	return &Server{storage: store}
+}
+
+func (s *Server) SetupRoutes(app *fiber.App) {
+	app.Get("/.well-known/nostr.json", s.getNames)
+	app.Post("/nip05", s.registerName)
+	app.Delete("/nip05", s.releaseName)
+}
+
+// Browser based clients fetch this from other origins so it must always be served with an open CORS policy
+func (s *Server) getNames(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderAccessControlAllowOrigin, "*")
+
+	name := strings.ToLower(c.Query("name"))
+
+	var named []*types.Subscriber
+	var err error
+	if name != "" {
+		var subscriber *types.Subscriber
+		if subscriber, err = s.storage.GetSubscriberByName(name); err == nil {
+			named = append(named, subscriber)
+		}
+	} else {
+		named, err = s.storage.GetNamedSubscribers()
+	}
+
+	response := Response{
+		Names:  make(map[string]string),
+		Relays: make(map[string][]string),
+	}
+
+	// An unknown name is answered with an empty list of names
+	if err != nil {
+		if name == "" {
+			log.Printf("Error fetching subscribers for nip-05 lookup: %v", err)
+		}
+
+		return c.JSON(response)
+	}
+
+	relays := relayUrls(c)
+	for _, subscriber := range named {
+		if viper.GetBool("nip05_require_active_subscription") && !isActive(subscriber) {
+			continue
+		}
+
+		response.Names[subscriber.Nip05Name] = subscriber.Npub
+		if len(relays) > 0 {
+			response.Relays[subscriber.Npub] = relays
+		}
+	}
+
+	return c.JSON(response)
+}
+
+// Subscribers register their own name with a NIP-98 authorized request
+func (s *Server) registerName(c *fiber.Ctx) error {
+	event, err := s.authenticate(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	var request registerRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid request body"})
+	}
+
+	if IsReserved(request.Name) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "name is reserved"})
+	}
+
+	subscriber, err := s.storage.GetSubscriber(event.PubKey)
+	if err != nil || !isActive(subscriber) {
+		return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{"message": "an active subscription is required to register a name"})
+	}
+
+	if err := Register(s.storage, event.PubKey, request.Name); err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"name": NormalizeName(request.Name), "pubkey": event.PubKey})
+}
+
+func (s *Server) releaseName(c *fiber.Ctx) error {
+	event, err := s.authenticate(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	if err := Release(s.storage, event.PubKey); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (s *Server) authenticate(c *fiber.Ctx) (*nostr.Event, error) {
+	url := c.BaseURL() + c.OriginalURL()
+
+	return lib_nostr.ValidateHttpAuth(c.Get(fiber.HeaderAuthorization), url, c.Method(), c.Body())
+}
+
+// NormalizeName lowercases a name so lookups and uniqueness checks are case insensitive
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ValidateName checks a name only uses the characters NIP-05 allows
+func ValidateName(name string) error {
+	name = NormalizeName(name)
+
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	if len(name) > maxNameLength {
+		return fmt.Errorf("name must be %d characters or less", maxNameLength)
+	}
+
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("name may only contain a-z, 0-9, '-', '_' and '.'")
+	}
+
+	return nil
+}
+
+// IsReserved reports whether a name can only be assigned by the relay admin
+func IsReserved(name string) bool {
+	return slices.Contains(viper.GetStringSlice("nip05_reserved_names"), NormalizeName(name))
+}
+
+// Register assigns a name to a subscriber, replacing any name they already had
+// Reserved names are not checked here so the panel admin can assign them
+func Register(store stores.Store, pubkey string, name string) error {
+	pubkey, err := NormalizePubkey(pubkey)
+	if err != nil {
+		return err
+	}
+
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	name = NormalizeName(name)
+
+	subscribers.Lock.Lock()
+	defer subscribers.Lock.Unlock()
+
+	subscriber, err := store.GetSubscriber(pubkey)
+	if err != nil {
+		return fmt.Errorf("subscriber not found for %s", pubkey)
+	}
+
+	owner, err := GetSubscriberByName(store, name)
+	if err == nil && owner.Npub != pubkey {
+		return fmt.Errorf("name is already taken")
+	}
+
+	subscriber.Nip05Name = name
+
+	return store.SaveSubscriber(subscriber)
+}
+
+// Release removes the name registered by a subscriber
+func Release(store stores.Store, pubkey string) error {
+	pubkey, err := NormalizePubkey(pubkey)
+	if err != nil {
+		return err
+	}
+
+	subscribers.Lock.Lock()
+	defer subscribers.Lock.Unlock()
+
+	subscriber, err := store.GetSubscriber(pubkey)
+	if err != nil || subscriber.Nip05Name == "" {
+		return fmt.Errorf("no name registered for %s", pubkey)
+	}
+
+	subscriber.Nip05Name = ""
+
+	return store.SaveSubscriber(subscriber)
+}
+
+// GetSubscriberByName finds the subscriber a name is registered to
+func GetSubscriberByName(store stores.Store, name string) (*types.Subscriber, error) {
+	return store.GetSubscriberByName(NormalizeName(name))
+}
+
+// NormalizePubkey accepts either a hex pubkey or an npub and returns the hex pubkey subscribers are stored under
+func NormalizePubkey(pubkey string) (string, error) {
+	if strings.HasPrefix(pubkey, "npub") {
+		_, value, err := nip19.Decode(pubkey)
+		if err != nil {
+			return "", fmt.Errorf("invalid npub: %v", err)
+		}
+
+		return value.(string), nil
+	}
+
+	if !nostr.IsValid32ByteHex(pubkey) {
+		return "", fmt.Errorf("invalid pubkey: %s", pubkey)
+	}
+
+	return pubkey, nil
+}
+
+func isActive(subscriber *types.Subscriber) bool {
+	return subscriber.Tier != "" && time.Now().Before(subscriber.EndDate)
+}
+
+// The relays advertised for each name default to this relay when none are configured
+func relayUrls(c *fiber.Ctx) []string {
+	if relays := viper.GetStringSlice("nip05_relays"); len(relays) > 0 {
+		return relays
+	}
+
+	return []string{"ws" + strings.TrimPrefix(c.BaseURL(), "http")}
+}
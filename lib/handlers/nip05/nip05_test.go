@@ -0,0 +1,114 @@
+package nip05
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deroproject/graviton"
+	"github.com/spf13/viper"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/subscribers"
+)
+
+// Only the subscriber methods of the store are implemented
+type subscriberStore struct {
+	stores.Store
+
+	database *graviton.Store
+}
+
+func (store *subscriberStore) GetSubscriber(npub string) (*types.Subscriber, error) {
+	return subscribers.Get(store.database, npub)
+}
+
+func (store *subscriberStore) GetSubscriberByName(name string) (*types.Subscriber, error) {
+	return subscribers.GetByName(store.database, name)
+}
+
+func (store *subscriberStore) SaveSubscriber(subscriber *types.Subscriber) error {
+	return subscribers.Save(store.database, subscriber)
+}
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"alice", true},
+		{"Alice", true},
+		{"alice.smith-1_", true},
+		{"", false},
+		{"alice smith", false},
+		{"alice@relay", false},
+		{strings.Repeat("a", maxNameLength), true},
+		{strings.Repeat("a", maxNameLength+1), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := ValidateName(test.name); (err == nil) != test.valid {
+				t.Errorf("expected valid %v, got error %v", test.valid, err)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	viper.Set("nip05_reserved_names", []string{"admin"})
+	defer viper.Set("nip05_reserved_names", nil)
+
+	alice := strings.Repeat("a", 64)
+	bob := strings.Repeat("b", 64)
+
+	database, _ := graviton.NewMemStore()
+	store := &subscriberStore{database: database}
+	store.SaveSubscriber(&types.Subscriber{Npub: alice})
+	store.SaveSubscriber(&types.Subscriber{Npub: bob})
+
+	tests := []struct {
+		name   string
+		pubkey string
+		nip05  string
+		valid  bool
+		owner  string
+	}{
+		{"register", alice, "Alice", true, alice},
+		{"taken by another subscriber", bob, "alice", false, alice},
+		{"register again", alice, "alice", true, alice},
+		{"invalid name", bob, "bob smith", false, ""},
+		{"unknown subscriber", strings.Repeat("c", 64), "carol", false, ""},
+		{"invalid pubkey", "carol", "carol", false, ""},
+		{"reserved names are assigned by the admin", bob, "admin", true, bob},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Register(store, test.pubkey, test.nip05)
+			if (err == nil) != test.valid {
+				t.Fatalf("expected valid %v, got error %v", test.valid, err)
+			}
+
+			subscriber, err := GetSubscriberByName(store, test.nip05)
+			if test.owner == "" {
+				if err == nil {
+					t.Errorf("expected the name to be free, got %s", subscriber.Npub)
+				}
+				return
+			}
+
+			if err != nil || subscriber.Npub != test.owner {
+				t.Errorf("expected the name to belong to %s, got %v (%v)", test.owner, subscriber, err)
+			}
+		})
+	}
+
+	if err := Release(store, alice); err != nil {
+		t.Fatalf("failed to release the name: %v", err)
+	}
+
+	if err := Register(store, bob, "alice"); err != nil {
+		t.Errorf("expected a released name to be available, got %v", err)
+	}
+}
@@ -0,0 +1,414 @@
+package nip86
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/aggregates"
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+const contentType = "application/nostr+json+rpc"
+
+type Server struct {
+	storage stores.Store
+}
+
+type request struct {
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+type response struct {
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+type pubkeyReason struct {
+	Pubkey string `json:"pubkey"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type eventReason struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type ipReason struct {
+	IP     string `json:"ip"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type method func(s *Server, params []interface{}) (interface{}, error)
+
+var methods = map[string]method{
+	"banpubkey":                   banPubkey,
+	"allowpubkey":                 allowPubkey,
+	"listbannedpubkeys":           listBannedPubkeys,
+	"listallowedpubkeys":          listAllowedPubkeys,
+	"banevent":                    banEvent,
+	"allowevent":                  allowEvent,
+	"listbannedevents":            listBannedEvents,
+	"listeventsneedingmoderation": listEventsNeedingModeration,
+	"changerelayname":             changeRelayName,
+	"changerelaydescription":      changeRelayDescription,
+	"changerelayicon":             changeRelayIcon,
+	"allowkind":                   allowKind,
+	"disallowkind":                disallowKind,
+	"listallowedkinds":            listAllowedKinds,
+	"blockip":                     blockIP,
+	"unblockip":                   unblockIP,
+	"listblockedips":              listBlockedIPs,
+}
+
+func NewServer(store stores.Store) *Server {
+	return &Server{storage: store}
+}
+
+// Management requests are sent to the relay url itself with the json rpc content type
+func (s *Server) SetupRoutes(app *fiber.App) {
+	app.Post("/", s.handleRequest)
+}
+
+func (s *Server) handleRequest(c *fiber.Ctx) error {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), contentType) {
+		return c.Next()
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+
+	body := c.Body()
+
+	event, err := s.authenticate(c, body)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(response{Error: err.Error()})
+	}
+
+	var rpcRequest request
+	if err := json.Unmarshal(body, &rpcRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(response{Error: "invalid request"})
+	}
+
+	log.Printf("Relay management request %s from %s", rpcRequest.Method, event.PubKey)
+
+	if rpcRequest.Method == "supportedmethods" {
+		return c.JSON(response{Result: supportedMethods()})
+	}
+
+	handler, ok := methods[rpcRequest.Method]
+	if !ok {
+		return c.JSON(response{Error: fmt.Sprintf("unsupported method: %s", rpcRequest.Method)})
+	}
+
+	result, err := handler(s, rpcRequest.Params)
+	if err != nil {
+		return c.JSON(response{Error: err.Error()})
+	}
+
+	return c.JSON(response{Result: result})
+}
+
+// Requests must carry a NIP-98 authorization event with a payload tag signed by one of the admin_pubkeys
+// The u tag may be the http or the websocket url of the relay
+func (s *Server) authenticate(c *fiber.Ctx, body []byte) (*nostr.Event, error) {
+	header := c.Get(fiber.HeaderAuthorization)
+	url := c.BaseURL() + c.OriginalURL()
+
+	event, err := lib_nostr.ValidateHttpAuth(header, url, c.Method(), body)
+	if err != nil {
+		wsEvent, wsErr := lib_nostr.ValidateHttpAuth(header, "ws"+strings.TrimPrefix(url, "http"), c.Method(), body)
+		if wsErr != nil {
+			return nil, err
+		}
+
+		event = wsEvent
+	}
+
+	if event.Tags.GetFirst([]string{"payload", ""}) == nil {
+		return nil, fmt.Errorf("authorization event must include a payload tag")
+	}
+
+	if !slices.Contains(viper.GetStringSlice("admin_pubkeys"), event.PubKey) {
+		return nil, fmt.Errorf("pubkey is not a relay admin")
+	}
+
+	return event, nil
+}
+
+func supportedMethods() []string {
+	supported := []string{"supportedmethods"}
+	for name := range methods {
+		supported = append(supported, name)
+	}
+	slices.Sort(supported)
+
+	return supported
+}
+
+func banPubkey(s *Server, params []interface{}) (interface{}, error) {
+	pubkey, reason := getParam(params, 0), getParam(params, 1)
+
+	if err := moderation.Add(s.storage.GetStatsStore(), moderation.ListBannedPubkey, pubkey, reason); err != nil {
+		return nil, err
+	}
+
+	// A ban overrides any previous allow
+	moderation.Remove(s.storage.GetStatsStore(), moderation.ListAllowedPubkey, pubkey)
+
+	return true, nil
+}
+
+func allowPubkey(s *Server, params []interface{}) (interface{}, error) {
+	pubkey, reason := getParam(params, 0), getParam(params, 1)
+
+	if err := moderation.Remove(s.storage.GetStatsStore(), moderation.ListBannedPubkey, pubkey); err != nil {
+		return nil, err
+	}
+
+	if err := moderation.Add(s.storage.GetStatsStore(), moderation.ListAllowedPubkey, pubkey, reason); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
+
+func listBannedPubkeys(s *Server, params []interface{}) (interface{}, error) {
+	return listPubkeys(s, moderation.ListBannedPubkey)
+}
+
+func listAllowedPubkeys(s *Server, params []interface{}) (interface{}, error) {
+	return listPubkeys(s, moderation.ListAllowedPubkey)
+}
+
+func listPubkeys(s *Server, list string) (interface{}, error) {
+	entries, err := s.storage.GetStatsStore().GetModerationEntries(list)
+	if err != nil {
+		return nil, err
+	}
+
+	pubkeys := []pubkeyReason{}
+	for _, entry := range entries {
+		pubkeys = append(pubkeys, pubkeyReason{Pubkey: entry.Value, Reason: entry.Reason})
+	}
+
+	return pubkeys, nil
+}
+
+// Banned events are also removed from the store so they stop being served straight away
+func banEvent(s *Server, params []interface{}) (interface{}, error) {
+	id, reason := getParam(params, 0), getParam(params, 1)
+
+	if err := moderation.Add(s.storage.GetStatsStore(), moderation.ListBannedEvent, id, reason); err != nil {
+		return nil, err
+	}
+
+	id = strings.ToLower(id)
+
+	events, err := s.storage.QueryEvents(nostr.Filter{IDs: []string{id}})
+	if err != nil {
+		log.Printf("Banned event %s could not be looked up: %v", id, err)
+	}
+
+	for _, event := range events {
+		if event.ID != id {
+			continue
+		}
+
+		if err := aggregates.DeleteEvent(s.storage, event); err != nil {
+			log.Printf("Banned event %s could not be deleted: %v", id, err)
+		}
+	}
+
+	return true, nil
+}
+
+func allowEvent(s *Server, params []interface{}) (interface{}, error) {
+	if err := moderation.Remove(s.storage.GetStatsStore(), moderation.ListBannedEvent, getParam(params, 0)); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
+
+func listBannedEvents(s *Server, params []interface{}) (interface{}, error) {
+	entries, err := s.storage.GetStatsStore().GetModerationEntries(moderation.ListBannedEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []eventReason{}
+	for _, entry := range entries {
+		events = append(events, eventReason{ID: entry.Value, Reason: entry.Reason})
+	}
+
+	return events, nil
+}
+
+// Reported events waiting for review in the moderation queue, highest report weight first
+func listEventsNeedingModeration(s *Server, params []interface{}) (interface{}, error) {
+	events := []eventReason{}
+	for _, status := range []string{moderation.StatusHidden, moderation.StatusPending} {
+		targets, err := s.storage.GetStatsStore().GetReportTargets(status)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, target := range targets {
+			if target.TargetType != moderation.TargetEvent {
+				continue
+			}
+
+			reportTypes := []string{}
+			for reportType := range target.ReportTypes {
+				reportTypes = append(reportTypes, reportType)
+			}
+			slices.Sort(reportTypes)
+
+			events = append(events, eventReason{ID: target.Target, Reason: strings.Join(reportTypes, ", ")})
+		}
+	}
+
+	return events, nil
+}
+
+func changeRelayName(s *Server, params []interface{}) (interface{}, error) {
+	return updateConfig("RelayName", getParam(params, 0))
+}
+
+func changeRelayDescription(s *Server, params []interface{}) (interface{}, error) {
+	return updateConfig("RelayDescription", getParam(params, 0))
+}
+
+func changeRelayIcon(s *Server, params []interface{}) (interface{}, error) {
+	return updateConfig("RelayIcon", getParam(params, 0))
+}
+
+func updateConfig(key string, value string) (interface{}, error) {
+	if value == "" {
+		return nil, fmt.Errorf("missing value for %s", key)
+	}
+
+	viper.Set(key, value)
+	if err := viper.WriteConfig(); err != nil {
+		return nil, fmt.Errorf("failed to save config: %v", err)
+	}
+
+	return true, nil
+}
+
+func allowKind(s *Server, params []interface{}) (interface{}, error) {
+	return setKindAllowed(params, true)
+}
+
+func disallowKind(s *Server, params []interface{}) (interface{}, error) {
+	return setKindAllowed(params, false)
+}
+
+// In smart mode the kinds list holds the allowed kinds while in unlimited mode it holds the blocked kinds
+func setKindAllowed(params []interface{}, allowed bool) (interface{}, error) {
+	kind, err := strconv.Atoi(getParam(params, 0))
+	if err != nil {
+		return nil, fmt.Errorf("invalid kind")
+	}
+
+	settings, err := lib_nostr.LoadRelaySettings()
+	if err != nil {
+		return nil, err
+	}
+
+	kindStr := "kind" + strconv.Itoa(kind)
+	addToList := allowed == (settings.Mode == "smart")
+
+	kinds := slices.DeleteFunc(slices.Clone(settings.Kinds), func(k string) bool {
+		return k == kindStr
+	})
+	if addToList {
+		kinds = append(kinds, kindStr)
+	}
+
+	viper.Set("relay_settings.Kinds", kinds)
+	if err := viper.WriteConfig(); err != nil {
+		return nil, fmt.Errorf("failed to save config: %v", err)
+	}
+
+	return true, nil
+}
+
+func listAllowedKinds(s *Server, params []interface{}) (interface{}, error) {
+	settings, err := lib_nostr.LoadRelaySettings()
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.Mode != "smart" {
+		return nil, fmt.Errorf("every kind is allowed in %s mode unless it has been disallowed", settings.Mode)
+	}
+
+	kinds := []int{}
+	for _, k := range settings.Kinds {
+		if kind, err := strconv.Atoi(strings.TrimPrefix(k, "kind")); err == nil {
+			kinds = append(kinds, kind)
+		}
+	}
+	slices.Sort(kinds)
+
+	return kinds, nil
+}
+
+func blockIP(s *Server, params []interface{}) (interface{}, error) {
+	if err := moderation.Add(s.storage.GetStatsStore(), moderation.ListBlockedIP, getParam(params, 0), getParam(params, 1)); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
+
+func unblockIP(s *Server, params []interface{}) (interface{}, error) {
+	if err := moderation.Remove(s.storage.GetStatsStore(), moderation.ListBlockedIP, getParam(params, 0)); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
+
+func listBlockedIPs(s *Server, params []interface{}) (interface{}, error) {
+	entries, err := s.storage.GetStatsStore().GetModerationEntries(moderation.ListBlockedIP)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := []ipReason{}
+	for _, entry := range entries {
+		ips = append(ips, ipReason{IP: entry.Value, Reason: entry.Reason})
+	}
+
+	return ips, nil
+}
+
+// Params are positional, numbers are accepted for kinds so everything is returned as a string
+func getParam(params []interface{}, index int) string {
+	if index >= len(params) || params[index] == nil {
+		return ""
+	}
+
+	switch value := params[index].(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(params[index])
+}
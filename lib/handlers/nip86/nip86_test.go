@@ -0,0 +1,317 @@
+package nip86
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Requests are sent to the root of the relay, the authorization event has to name its full url
+const relayURL = "http://relay.test/"
+
+// Only the moderation lists and report targets of the statistics store are implemented
+type moderationStats struct {
+	stores.StatisticsStore
+
+	entries []types.ModerationEntry
+	targets []types.ReportTarget
+}
+
+func (stats *moderationStats) AddModerationEntry(entry *types.ModerationEntry) error {
+	stats.RemoveModerationEntry(entry.List, entry.Value)
+	stats.entries = append(stats.entries, *entry)
+	return nil
+}
+
+func (stats *moderationStats) RemoveModerationEntry(list string, value string) error {
+	stats.entries = slices.DeleteFunc(stats.entries, func(entry types.ModerationEntry) bool {
+		return entry.List == list && entry.Value == value
+	})
+	return nil
+}
+
+func (stats *moderationStats) GetModerationEntries(list string) ([]types.ModerationEntry, error) {
+	entries := []types.ModerationEntry{}
+	for _, entry := range stats.entries {
+		if entry.List == list {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func (stats *moderationStats) GetReportTargets(status string) ([]types.ReportTarget, error) {
+	targets := []types.ReportTarget{}
+	for _, target := range stats.targets {
+		if target.Status == status {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets, nil
+}
+
+// Only the event methods of the store are implemented
+type eventStore struct {
+	stores.Store
+
+	stats  *moderationStats
+	events []*nostr.Event
+}
+
+func (store *eventStore) GetStatsStore() stores.StatisticsStore {
+	return store.stats
+}
+
+func (store *eventStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	events := []*nostr.Event{}
+	for _, event := range store.events {
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (store *eventStore) DeleteEvent(id string) error {
+	store.events = slices.DeleteFunc(store.events, func(event *nostr.Event) bool {
+		return event.ID == id
+	})
+	return nil
+}
+
+func hexKey(character string) string {
+	return strings.Repeat(character, 64)
+}
+
+func useAdmins(t *testing.T, pubkeys ...string) {
+	previous := viper.Get("admin_pubkeys")
+	viper.Set("admin_pubkeys", pubkeys)
+	t.Cleanup(func() { viper.Set("admin_pubkeys", previous) })
+}
+
+// Writes the config to a temporary directory and points viper at it so methods that save the config
+// never touch the working directory
+func useConfig(t *testing.T, config string) {
+	directory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(directory, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	workingDirectory, _ := os.Getwd()
+	if err := os.Chdir(directory); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	viper.SetConfigFile(filepath.Join(directory, "config.json"))
+
+	t.Cleanup(func() {
+		os.Chdir(workingDirectory)
+		viper.Reset()
+	})
+}
+
+// Sends a management request signed with the NIP-98 event the relay expects from the key
+func call(t *testing.T, app *fiber.App, secretKey string, method string, params ...interface{}) (int, response) {
+	body, _ := jsoniter.Marshal(request{Method: method, Params: params})
+	hash := sha256.Sum256(body)
+
+	auth := nostr.Event{
+		Kind:      27235,
+		CreatedAt: nostr.Now(),
+		Tags:      nostr.Tags{{"u", relayURL}, {"method", "POST"}, {"payload", hex.EncodeToString(hash[:])}},
+	}
+	if err := auth.Sign(secretKey); err != nil {
+		t.Fatalf("failed to sign authorization: %v", err)
+	}
+	authJSON, _ := jsoniter.Marshal(auth)
+
+	httpRequest := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+	httpRequest.Host = "relay.test"
+	httpRequest.Header.Set(fiber.HeaderContentType, contentType)
+	httpRequest.Header.Set(fiber.HeaderAuthorization, "Nostr "+base64.StdEncoding.EncodeToString(authJSON))
+
+	httpResponse, err := app.Test(httpRequest)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	var result response
+	jsoniter.NewDecoder(httpResponse.Body).Decode(&result)
+
+	return httpResponse.StatusCode, result
+}
+
+func TestAuthenticate(t *testing.T) {
+	admin := nostr.GeneratePrivateKey()
+	adminPubkey, _ := nostr.GetPublicKey(admin)
+	useAdmins(t, adminPubkey)
+
+	app := fiber.New()
+	NewServer(&eventStore{stats: &moderationStats{}}).SetupRoutes(app)
+
+	tests := []struct {
+		name      string
+		secretKey string
+		method    string
+		status    int
+		error     string
+	}{
+		{"admin", admin, "supportedmethods", fiber.StatusOK, ""},
+		{"not an admin", nostr.GeneratePrivateKey(), "supportedmethods", fiber.StatusUnauthorized, "pubkey is not a relay admin"},
+		{"unsupported method", admin, "deleteeverything", fiber.StatusOK, "unsupported method: deleteeverything"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, result := call(t, app, test.secretKey, test.method)
+			if status != test.status {
+				t.Fatalf("expected status %d, got %d", test.status, status)
+			}
+
+			if result.Error != test.error {
+				t.Errorf("expected error %q, got %q", test.error, result.Error)
+			}
+		})
+	}
+
+	t.Run("other content types are passed on", func(t *testing.T) {
+		httpRequest := httptest.NewRequest("POST", "/", strings.NewReader("{}"))
+		httpRequest.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+		httpResponse, _ := app.Test(httpRequest)
+		if httpResponse.StatusCode != fiber.StatusNotFound {
+			t.Errorf("expected the request to reach the next route, got status %d", httpResponse.StatusCode)
+		}
+	})
+}
+
+func TestMethods(t *testing.T) {
+	admin := nostr.GeneratePrivateKey()
+	adminPubkey, _ := nostr.GetPublicKey(admin)
+	useAdmins(t, adminPubkey)
+
+	store := &eventStore{
+		stats: &moderationStats{
+			targets: []types.ReportTarget{
+				{TargetType: moderation.TargetEvent, Target: hexKey("4"), Status: moderation.StatusPending, ReportTypes: map[string]int{"spam": 2, "nudity": 1}},
+				{TargetType: moderation.TargetPubkey, Target: hexKey("5"), Status: moderation.StatusHidden},
+				{TargetType: moderation.TargetEvent, Target: hexKey("6"), Status: moderation.StatusDismissed},
+			},
+		},
+		events: []*nostr.Event{{ID: hexKey("3"), Kind: 1, PubKey: hexKey("b")}},
+	}
+
+	app := fiber.New()
+	NewServer(store).SetupRoutes(app)
+
+	// The methods run in order against the same lists
+	tests := []struct {
+		name   string
+		method string
+		params []interface{}
+		result string
+		error  string
+	}{
+		{"ban a pubkey", "banpubkey", []interface{}{strings.ToUpper(hexKey("a")), "spam"}, `true`, ""},
+		{"banned pubkeys are stored in lower case", "listbannedpubkeys", nil, `[{"pubkey":"` + hexKey("a") + `","reason":"spam"}]`, ""},
+		{"allowing a pubkey lifts its ban", "allowpubkey", []interface{}{hexKey("a")}, `true`, ""},
+		{"no pubkeys are banned", "listbannedpubkeys", nil, `[]`, ""},
+		{"allowed pubkeys", "listallowedpubkeys", nil, `[{"pubkey":"` + hexKey("a") + `"}]`, ""},
+		{"banning a pubkey drops its allow", "banpubkey", []interface{}{hexKey("a")}, `true`, ""},
+		{"no pubkeys are allowed", "listallowedpubkeys", nil, `[]`, ""},
+		{"invalid pubkey", "banpubkey", []interface{}{"alice"}, ``, "invalid hex value: alice"},
+		{"ban an event", "banevent", []interface{}{hexKey("3"), "illegal"}, `true`, ""},
+		{"banned events", "listbannedevents", nil, `[{"id":"` + hexKey("3") + `","reason":"illegal"}]`, ""},
+		{"allow an event", "allowevent", []interface{}{hexKey("3")}, `true`, ""},
+		{"reported events", "listeventsneedingmoderation", nil, `[{"id":"` + hexKey("4") + `","reason":"nudity, spam"}]`, ""},
+		{"block an ip", "blockip", []interface{}{"::ffff:10.0.0.1", "abuse"}, `true`, ""},
+		{"blocked ips are stored in their canonical form", "listblockedips", nil, `[{"ip":"10.0.0.1","reason":"abuse"}]`, ""},
+		{"unblock an ip", "unblockip", []interface{}{"10.0.0.1"}, `true`, ""},
+		{"invalid ip", "blockip", []interface{}{"localhost"}, ``, "invalid ip address: localhost"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, result := call(t, app, admin, test.method, test.params...)
+			if result.Error != test.error {
+				t.Fatalf("expected error %q, got %q", test.error, result.Error)
+			}
+
+			if test.error != "" {
+				return
+			}
+
+			resultJSON, _ := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(result.Result)
+			if string(resultJSON) != test.result {
+				t.Errorf("expected %s, got %s", test.result, resultJSON)
+			}
+		})
+	}
+
+	if len(store.events) != 0 {
+		t.Errorf("expected the banned event to be deleted")
+	}
+}
+
+func TestSetKindAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		kinds   string
+		allowed bool
+		expects []string
+	}{
+		{"allow in smart mode", "smart", `["kind1"]`, true, []string{"kind1", "kind7"}},
+		{"allow twice in smart mode", "smart", `["kind7"]`, true, []string{"kind7"}},
+		{"disallow in smart mode", "smart", `["kind1", "kind7"]`, false, []string{"kind1"}},
+		{"disallow in unlimited mode", "unlimited", `[]`, false, []string{"kind7"}},
+		{"allow in unlimited mode", "unlimited", `["kind7"]`, true, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useConfig(t, `{"relay_settings": {"mode": "`+test.mode+`", "kinds": `+test.kinds+`}}`)
+
+			if _, err := setKindAllowed([]interface{}{float64(7)}, test.allowed); err != nil {
+				t.Fatalf("failed to change kind: %v", err)
+			}
+
+			// The saved config is read back so the change outlives the process
+			viper.Reset()
+			settings, err := lib_nostr.LoadRelaySettings()
+			if err != nil {
+				t.Fatalf("failed to load settings: %v", err)
+			}
+
+			if !slices.Equal(settings.Kinds, test.expects) {
+				t.Errorf("expected kinds %v, got %v", test.expects, settings.Kinds)
+			}
+		})
+	}
+
+	t.Run("invalid kind", func(t *testing.T) {
+		if _, err := setKindAllowed([]interface{}{"seven"}, true); err == nil {
+			t.Errorf("expected an invalid kind to be refused")
+		}
+	})
+}
@@ -0,0 +1,309 @@
+package nip96
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/blocklist"
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind1063"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/uploads"
+)
+
+type Server struct {
+	storage stores.Store
+}
+
+func NewServer(store stores.Store) *Server {
+	return &Server{storage: store}
+}
+
+func (s *Server) SetupRoutes(app *fiber.App) {
+	app.Get("/.well-known/nostr/nip96.json", s.getServerInfo)
+	app.Get("/nip96", s.listFiles)
+	app.Post("/nip96", s.uploadFile)
+	app.Delete("/nip96/:hash", s.deleteFile)
+}
+
+// Files are served by the blossom routes as both use the same blob store
+func (s *Server) getServerInfo(c *fiber.Ctx) error {
+	settings, err := lib_nostr.LoadRelaySettings()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "failed to load relay settings"})
+	}
+
+	plans := fiber.Map{
+		"free": fiber.Map{
+			"name":              "Free",
+			"is_nip98_required": true,
+			"max_byte_size":     uploads.MaxUploadSize(),
+			"file_expiration":   []int{0, 0},
+		},
+	}
+
+	var subscriptionTiers []types.SubscriptionTier
+	if err := viper.UnmarshalKey("subscription_tiers", &subscriptionTiers); err == nil {
+		for _, tier := range subscriptionTiers {
+			plans[tier.DataLimit] = fiber.Map{
+				"name":              tier.DataLimit,
+				"is_nip98_required": true,
+				"max_byte_size":     planMaxByteSize(tier.DataLimit),
+				"file_expiration":   []int{0, 0},
+			}
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"api_url":        c.BaseURL() + "/nip96",
+		"download_url":   c.BaseURL() + "/blossom",
+		"supported_nips": []int{94, 96, 98},
+		"tos_url":        "",
+		"content_types":  getContentTypes(settings),
+		"plans":          plans,
+	})
+}
+
+func (s *Server) uploadFile(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return sendError(c, fiber.StatusBadRequest, "missing file")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return sendError(c, fiber.StatusBadRequest, "failed to read file")
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return sendError(c, fiber.StatusBadRequest, "failed to read file")
+	}
+
+	// The payload tag of a multipart upload covers the file rather than the whole form
+	event, err := lib_nostr.ValidateHttpAuth(c.Get(fiber.HeaderAuthorization), requestURL(c), c.Method(), data)
+	if err != nil {
+		return sendError(c, fiber.StatusUnauthorized, err.Error())
+	}
+
+	mimeType := c.FormValue("content_type")
+	if mimeType == "" {
+		mimeType = strings.TrimSpace(strings.Split(fileHeader.Header.Get(fiber.HeaderContentType), ";")[0])
+	}
+
+	err = uploads.CheckUpload(s.storage, event.PubKey, fileHeader.Filename, mimeType, int64(len(data)))
+	if err != nil {
+		return sendError(c, fiber.StatusForbidden, err.Error())
+	}
+
+	err = blocklist.CheckData(s.storage.GetStatsStore(), data)
+	if err != nil {
+		return sendError(c, fiber.StatusForbidden, err.Error())
+	}
+
+	checkHash := sha256.Sum256(data)
+	encodedHash := hex.EncodeToString(checkHash[:])
+
+	// Uploading the same file twice returns the existing metadata
+	existing, err := kind1063.QueryFileMetadata(s.storage, encodedHash, "", event.PubKey)
+	if err == nil && len(existing) > 0 {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":      "success",
+			"message":     "File already uploaded",
+			"nip94_event": buildNip94Event(existing[0]),
+		})
+	}
+
+	_, err = uploads.StoreBlob(s.storage, data, mimeType, event.PubKey)
+	if err != nil {
+		return sendError(c, fiber.StatusInternalServerError, "failed to store file")
+	}
+
+	if err := uploads.RecordUpload(s.storage, event.PubKey, int64(len(data))); err != nil {
+		log.Printf("Failed to record storage usage for %s: %v", event.PubKey, err)
+	}
+
+	url := fmt.Sprintf("%s/blossom/%s", c.BaseURL(), encodedHash)
+
+	metadata, err := kind1063.CreateBlobMetadataEvent(s.storage, data, mimeType, url, event.PubKey)
+	if err != nil {
+		log.Printf("Failed to create file metadata event for file %s: %v", encodedHash, err)
+		return sendError(c, fiber.StatusInternalServerError, "failed to create file metadata")
+	}
+
+	// Optional descriptive fields supplied with the upload are included in the returned metadata
+	nip94Event := buildNip94Event(metadata)
+	for _, field := range []string{"alt", "caption"} {
+		if value := c.FormValue(field); value != "" {
+			nip94Event["tags"] = append(nip94Event["tags"].(nostr.Tags), nostr.Tag{field, value})
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status":      "success",
+		"message":     "Upload successful",
+		"nip94_event": nip94Event,
+	})
+}
+
+// Only the original uploader can delete a file
+func (s *Server) deleteFile(c *fiber.Ctx) error {
+	hash := c.Params("hash")
+
+	event, err := lib_nostr.ValidateHttpAuth(c.Get(fiber.HeaderAuthorization), requestURL(c), c.Method(), nil)
+	if err != nil {
+		return sendError(c, fiber.StatusUnauthorized, err.Error())
+	}
+
+	events, err := kind1063.QueryFileMetadata(s.storage, hash, "", event.PubKey)
+	if err != nil {
+		return sendError(c, fiber.StatusInternalServerError, "failed to query file metadata")
+	}
+
+	if len(events) <= 0 {
+		return sendError(c, fiber.StatusNotFound, "file not found")
+	}
+
+	// The blob is shared when other users have uploaded the same file so only the reference of the uploader is
+	// removed, the blob itself goes with the last one
+	data, err := uploads.GetBlob(s.storage, hash)
+	if err == nil && data != nil {
+		if err := s.storage.DeleteBlob(hash, event.PubKey); err != nil {
+			log.Printf("Failed to delete file %s for %s: %v", hash, event.PubKey, err)
+			return sendError(c, fiber.StatusInternalServerError, "failed to delete file")
+		}
+
+		if err := uploads.RecordDelete(s.storage, event.PubKey, int64(len(data))); err != nil {
+			log.Printf("Failed to record storage usage for %s: %v", event.PubKey, err)
+		}
+	}
+
+	for _, metadata := range events {
+		if err := s.storage.DeleteEvent(metadata.ID); err != nil {
+			log.Printf("Failed to delete file metadata event %s: %v", metadata.ID, err)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "File deleted",
+	})
+}
+
+// List the files uploaded by the authenticated user, newest first
+func (s *Server) listFiles(c *fiber.Ctx) error {
+	event, err := lib_nostr.ValidateHttpAuth(c.Get(fiber.HeaderAuthorization), requestURL(c), c.Method(), nil)
+	if err != nil {
+		return sendError(c, fiber.StatusUnauthorized, err.Error())
+	}
+
+	page, err := strconv.Atoi(c.Query("page", "0"))
+	if err != nil || page < 0 {
+		page = 0
+	}
+
+	count, err := strconv.Atoi(c.Query("count", "10"))
+	if err != nil || count <= 0 {
+		count = 10
+	}
+
+	events, err := kind1063.QueryFileMetadata(s.storage, "", "", event.PubKey)
+	if err != nil {
+		return sendError(c, fiber.StatusInternalServerError, "failed to query file metadata")
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].CreatedAt > events[j].CreatedAt
+	})
+
+	files := []fiber.Map{}
+	for i := page * count; i < len(events) && i < (page+1)*count; i++ {
+		files = append(files, buildNip94Event(events[i]))
+	}
+
+	return c.JSON(fiber.Map{
+		"count": len(files),
+		"total": len(events),
+		"page":  page,
+		"files": files,
+	})
+}
+
+// A plan can't accept a single file larger than its data limit or the relay wide upload limit
+func planMaxByteSize(dataLimit string) int64 {
+	maxSize := uploads.MaxUploadSize()
+
+	limit, err := uploads.ParseDataLimit(dataLimit)
+	if err != nil {
+		return maxSize
+	}
+
+	if maxSize <= 0 || limit < maxSize {
+		return limit
+	}
+
+	return maxSize
+}
+
+func buildNip94Event(event *nostr.Event) fiber.Map {
+	return fiber.Map{
+		"tags":       event.Tags,
+		"content":    event.Content,
+		"created_at": event.CreatedAt,
+	}
+}
+
+// Convert the permitted file extensions from the relay settings into mime types
+func getContentTypes(settings *types.RelaySettings) []string {
+	contentTypes := []string{}
+	seen := map[string]bool{}
+
+	addTypes := func(active bool, extensions []string) {
+		if !active {
+			return
+		}
+
+		for _, extension := range extensions {
+			mimeType := mime.TypeByExtension("." + extension)
+			if mimeType == "" {
+				continue
+			}
+
+			mimeType = strings.TrimSpace(strings.Split(mimeType, ";")[0])
+			if !seen[mimeType] {
+				seen[mimeType] = true
+				contentTypes = append(contentTypes, mimeType)
+			}
+		}
+	}
+
+	addTypes(settings.IsPhotosActive, settings.PhotoTypes)
+	addTypes(settings.IsVideosActive, settings.VideoTypes)
+	addTypes(settings.IsAudioActive, settings.AudioTypes)
+
+	return contentTypes
+}
+
+func requestURL(c *fiber.Ctx) string {
+	return c.BaseURL() + c.OriginalURL()
+}
+
+func sendError(c *fiber.Ctx, status int, message string) error {
+	return c.Status(status).JSON(fiber.Map{
+		"status":  "error",
+		"message": message,
+	})
+}
@@ -0,0 +1,33 @@
+package nip96
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestPlanMaxByteSize(t *testing.T) {
+	defer viper.Set("max_upload_size", nil)
+
+	tests := []struct {
+		name          string
+		maxUploadSize int64
+		dataLimit     string
+		expected      int64
+	}{
+		{"plan smaller than upload limit", 1 << 30, "100 MB per month", 100 << 20},
+		{"upload limit smaller than plan", 50 << 20, "5 GB per month", 50 << 20},
+		{"no upload limit", 0, "5 GB per month", 5 << 30},
+		{"invalid plan limit", 50 << 20, "unlimited", 50 << 20},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			viper.Set("max_upload_size", test.maxUploadSize)
+
+			if size := planMaxByteSize(test.dataLimit); size != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, size)
+			}
+		})
+	}
+}
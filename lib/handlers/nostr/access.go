@@ -0,0 +1,115 @@
+package nostr
+
+import (
+	"log"
+	"slices"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+)
+
+// IsRestrictedKind reports if events of the kind can only be read by their author and tagged recipients
+// The list of restricted kinds is configured with restricted_read_kinds
+func IsRestrictedKind(kind int) bool {
+	return slices.Contains(viper.GetIntSlice("restricted_read_kinds"), kind)
+}
+
+// CanReadEvent reports if the authenticated pubkey is allowed to receive the event,
+// an empty pubkey means the connection has not authenticated
+func CanReadEvent(event *nostr.Event, pubkey string) bool {
+	if IsRestrictedKind(event.Kind) && !isAuthorOrRecipient(event, pubkey) {
+		return false
+	}
+
+	for _, check := range readChecks {
+		if !check(event, pubkey) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FilterPublicResults applies the query filters and read checks a REQ from a connection that hasn't authenticated
+// goes through, routes that serve stored events over http use it so they never show more than a REQ would
+func FilterPublicResults(events []*nostr.Event) []*nostr.Event {
+	readable := []*nostr.Event{}
+	for _, event := range FilterQueryResults(events) {
+		if CanReadEvent(event, "") {
+			readable = append(readable, event)
+		}
+	}
+
+	return readable
+}
+
+func isAuthorOrRecipient(event *nostr.Event, pubkey string) bool {
+	if pubkey == "" {
+		return false
+	}
+
+	if event.PubKey == pubkey {
+		return true
+	}
+
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "p" && tag[1] == pubkey {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckReadAccess returns the CLOSED reason for filters that explicitly ask for restricted kinds the
+// pubkey can't read, an empty string is returned when the request can be served
+// Filters that don't name a restricted kind are still served but restricted events are removed from the results
+func CheckReadAccess(filters nostr.Filters, pubkey string) string {
+	for _, filter := range filters {
+		if !slices.ContainsFunc(filter.Kinds, IsRestrictedKind) {
+			continue
+		}
+
+		if pubkey == "" {
+			return Reason(PrefixAuthRequired, "this relay only serves direct messages to their author or recipients")
+		}
+
+		// The filter has to be limited to messages the user sent or received
+		if slices.Contains(filter.Authors, pubkey) && len(filter.Authors) == 1 {
+			continue
+		}
+
+		if recipients, ok := filter.Tags["p"]; ok && len(recipients) == 1 && recipients[0] == pubkey {
+			continue
+		}
+
+		return Reason(PrefixRestricted, "you can only request direct messages you sent or received")
+	}
+
+	return ""
+}
+
+// RestrictReads wraps a writer so that EVENT messages the pubkey isn't allowed to read are never sent
+// Handlers pass the event they already parsed so it is checked as it is and only serialized once allowed
+func RestrictReads(write KindWriter, pubkey string) KindWriter {
+	return func(messageType string, params ...interface{}) {
+		if messageType == "EVENT" && len(params) >= 2 {
+			if event, ok := params[1].(*nostr.Event); ok {
+				if !CanReadEvent(event, pubkey) {
+					return
+				}
+
+				eventJSON, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(event)
+				if err != nil {
+					log.Printf("Error marshaling event: %v", err)
+					return
+				}
+
+				params = append([]interface{}{params[0], string(eventJSON)}, params[2:]...)
+			}
+		}
+
+		write(messageType, params...)
+	}
+}
@@ -0,0 +1,57 @@
+package nostr
+
+import (
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+)
+
+func TestRestrictReads(t *testing.T) {
+	viper.Set("restricted_read_kinds", []int{4})
+	defer viper.Set("restricted_read_kinds", nil)
+
+	tests := []struct {
+		name   string
+		pubkey string
+		event  *nostr.Event
+		sent   bool
+	}{
+		{"unrestricted kind", "", &nostr.Event{ID: "1", Kind: 1, PubKey: "alice"}, true},
+		{"restricted kind without auth", "", &nostr.Event{ID: "2", Kind: 4, PubKey: "alice"}, false},
+		{"restricted kind read by its author", "alice", &nostr.Event{ID: "3", Kind: 4, PubKey: "alice"}, true},
+		{"restricted kind read by its recipient", "bob", &nostr.Event{ID: "4", Kind: 4, PubKey: "alice", Tags: nostr.Tags{{"p", "bob"}}}, true},
+		{"restricted kind read by someone else", "carol", &nostr.Event{ID: "5", Kind: 4, PubKey: "alice", Tags: nostr.Tags{{"p", "bob"}}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sent []interface{}
+			write := RestrictReads(func(messageType string, params ...interface{}) {
+				sent = params
+			}, test.pubkey)
+
+			write("EVENT", "sub", test.event)
+
+			if (sent != nil) != test.sent {
+				t.Fatalf("expected sent %v, got %v", test.sent, sent != nil)
+			}
+
+			if sent == nil {
+				return
+			}
+
+			// Transports receive the event serialized the same way as before
+			eventJSON, ok := sent[1].(string)
+			if !ok {
+				t.Fatalf("expected the event to be serialized, got %T", sent[1])
+			}
+
+			var event nostr.Event
+			if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(eventJSON), &event); err != nil || event.ID != test.event.ID {
+				t.Errorf("expected event %s, got %s (%v)", test.event.ID, event.ID, err)
+			}
+		})
+	}
+}
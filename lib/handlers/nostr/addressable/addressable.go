@@ -0,0 +1,204 @@
+package addressable
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Coordinate addresses the latest version of a replaceable or addressable event
+type Coordinate struct {
+	Kind       int    `json:"kind"`
+	PubKey     string `json:"pubkey"`
+	Identifier string `json:"identifier"`
+}
+
+// Version is one stored version of the event at a coordinate
+type Version struct {
+	ID        string          `json:"id"`
+	CreatedAt nostr.Timestamp `json:"created_at"`
+}
+
+// Address lists the stored versions at a coordinate, newest first, along with the latest event
+type Address struct {
+	Coordinate string       `json:"coordinate"`
+	Naddr      string       `json:"naddr"`
+	Latest     *nostr.Event `json:"latest"`
+	Versions   []Version    `json:"versions"`
+}
+
+// String formats the coordinate the way it is referenced in a tags, kind:pubkey:d
+func (c Coordinate) String() string {
+	return fmt.Sprintf("%d:%s:%s", c.Kind, c.PubKey, c.Identifier)
+}
+
+// Naddr encodes the coordinate as a NIP-19 naddr
+func (c Coordinate) Naddr() (string, error) {
+	return nip19.EncodeEntity(c.PubKey, c.Kind, c.Identifier, nil)
+}
+
+// ParseCoordinate parses an a tag value of the form kind:pubkey:d, the d tag is empty for replaceable kinds
+func ParseCoordinate(value string) (Coordinate, error) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) < 2 {
+		return Coordinate{}, fmt.Errorf("invalid coordinate: %s", value)
+	}
+
+	kind, err := strconv.Atoi(parts[0])
+	if err != nil || (!lib_nostr.IsReplaceableKind(kind) && !lib_nostr.IsAddressableKind(kind)) {
+		return Coordinate{}, fmt.Errorf("coordinate must reference a replaceable or addressable kind: %s", value)
+	}
+
+	if !nostr.IsValid32ByteHex(parts[1]) {
+		return Coordinate{}, fmt.Errorf("invalid pubkey in coordinate: %s", value)
+	}
+
+	coordinate := Coordinate{Kind: kind, PubKey: parts[1]}
+	if len(parts) == 3 {
+		coordinate.Identifier = parts[2]
+	}
+
+	return coordinate, nil
+}
+
+// DecodeNaddr decodes a NIP-19 naddr into a coordinate
+func DecodeNaddr(naddr string) (Coordinate, error) {
+	prefix, value, err := nip19.Decode(naddr)
+	if err != nil || prefix != "naddr" {
+		return Coordinate{}, fmt.Errorf("invalid naddr: %s", naddr)
+	}
+
+	pointer, ok := value.(nostr.EntityPointer)
+	if !ok {
+		return Coordinate{}, fmt.Errorf("invalid naddr: %s", naddr)
+	}
+
+	return ParseCoordinate(Coordinate{Kind: pointer.Kind, PubKey: pointer.PublicKey, Identifier: pointer.Identifier}.String())
+}
+
+// Get retrieves the latest event stored at a coordinate
+func Get(store stores.Store, coordinate Coordinate) (*nostr.Event, error) {
+	events, err := getVersions(store, coordinate)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		return nil, fmt.Errorf("event not found: %s", coordinate)
+	}
+
+	return events[0], nil
+}
+
+// GetAddresses lists every coordinate an author has published replaceable or addressable events at,
+// kinds can be provided to only list some of them
+// Only the versions a REQ would return are listed
+func GetAddresses(store stores.Store, pubkey string, kinds []int) ([]Address, error) {
+	filter := nostr.Filter{
+		Authors: []string{pubkey},
+		Kinds:   kinds,
+	}
+
+	events, err := store.QueryEvents(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	events = lib_nostr.FilterPublicResults(events)
+
+	addresses := map[string]*Address{}
+	for _, event := range events {
+		if !filter.Matches(event) {
+			continue
+		}
+
+		if !lib_nostr.IsReplaceableKind(event.Kind) && !lib_nostr.IsAddressableKind(event.Kind) {
+			continue
+		}
+
+		coordinate := coordinateOf(event)
+
+		address, ok := addresses[coordinate.String()]
+		if !ok {
+			naddr, _ := coordinate.Naddr()
+			address = &Address{Coordinate: coordinate.String(), Naddr: naddr}
+			addresses[coordinate.String()] = address
+		}
+
+		address.Versions = append(address.Versions, Version{ID: event.ID, CreatedAt: event.CreatedAt})
+		if address.Latest == nil || isNewer(event, address.Latest) {
+			address.Latest = event
+		}
+	}
+
+	results := make([]Address, 0, len(addresses))
+	for _, address := range addresses {
+		sort.Slice(address.Versions, func(i, j int) bool {
+			return address.Versions[i].CreatedAt > address.Versions[j].CreatedAt
+		})
+
+		results = append(results, *address)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Coordinate < results[j].Coordinate
+	})
+
+	return results, nil
+}
+
+// Returns the versions stored at a coordinate a REQ would return with the latest first,
+// the d tag index is used for addressable kinds
+func getVersions(store stores.Store, coordinate Coordinate) ([]*nostr.Event, error) {
+	filter := nostr.Filter{
+		Authors: []string{coordinate.PubKey},
+		Kinds:   []int{coordinate.Kind},
+	}
+
+	if lib_nostr.IsAddressableKind(coordinate.Kind) && coordinate.Identifier != "" {
+		filter.Tags = nostr.TagMap{"d": []string{coordinate.Identifier}}
+	}
+
+	events, err := store.QueryEvents(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []*nostr.Event
+	for _, event := range lib_nostr.FilterPublicResults(events) {
+		if filter.Matches(event) && coordinateOf(event) == coordinate {
+			versions = append(versions, event)
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return isNewer(versions[i], versions[j])
+	})
+
+	return versions, nil
+}
+
+func coordinateOf(event *nostr.Event) Coordinate {
+	coordinate := Coordinate{Kind: event.Kind, PubKey: event.PubKey}
+	if lib_nostr.IsAddressableKind(event.Kind) {
+		coordinate.Identifier = event.Tags.GetD()
+	}
+
+	return coordinate
+}
+
+// When the timestamps match the event with the lowest id is the latest
+func isNewer(event *nostr.Event, other *nostr.Event) bool {
+	if event.CreatedAt != other.CreatedAt {
+		return event.CreatedAt > other.CreatedAt
+	}
+
+	return event.ID < other.ID
+}
@@ -0,0 +1,113 @@
+package addressable
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Only the event queries of the store are implemented
+type eventStore struct {
+	stores.Store
+
+	events []*nostr.Event
+}
+
+func (store *eventStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	events := []*nostr.Event{}
+	for _, event := range store.events {
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func hexKey(character string) string {
+	return strings.Repeat(character, 64)
+}
+
+func TestFiltersLikeReq(t *testing.T) {
+	author := hexKey("a")
+
+	// The latest article is hidden so the version before it is served instead
+	hidden := &nostr.Event{ID: hexKey("3"), PubKey: author, Kind: 30023, CreatedAt: 30, Tags: nostr.Tags{{"d", "article"}}}
+	previous := &nostr.Event{ID: hexKey("2"), PubKey: author, Kind: 30023, CreatedAt: 20, Tags: nostr.Tags{{"d", "article"}}}
+	restricted := &nostr.Event{ID: hexKey("4"), PubKey: author, Kind: 10050, CreatedAt: 10}
+	profile := &nostr.Event{ID: hexKey("5"), PubKey: author, Kind: 0, CreatedAt: 10}
+
+	store := &eventStore{events: []*nostr.Event{hidden, previous, restricted, profile}}
+
+	t.Cleanup(lib_nostr.ResetRegistrations)
+	lib_nostr.RegisterQueryFilter(func(events []*nostr.Event) []*nostr.Event {
+		filtered := []*nostr.Event{}
+		for _, event := range events {
+			if event.ID != hidden.ID {
+				filtered = append(filtered, event)
+			}
+		}
+
+		return filtered
+	})
+
+	restrictedKinds := viper.Get("restricted_read_kinds")
+	viper.Set("restricted_read_kinds", []int{10050})
+	defer viper.Set("restricted_read_kinds", restrictedKinds)
+
+	tests := []struct {
+		name       string
+		coordinate Coordinate
+		expected   string
+	}{
+		{"hidden version skipped", Coordinate{Kind: 30023, PubKey: author, Identifier: "article"}, previous.ID},
+		{"restricted kind not served", Coordinate{Kind: 10050, PubKey: author}, ""},
+		{"public event served", Coordinate{Kind: 0, PubKey: author}, profile.ID},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event, err := Get(store, test.coordinate)
+			if test.expected == "" {
+				if err == nil {
+					t.Fatalf("expected no event, got %s", event.ID)
+				}
+				return
+			}
+
+			if err != nil || event.ID != test.expected {
+				t.Fatalf("expected %s, got %v %v", test.expected, event, err)
+			}
+		})
+	}
+
+	addresses, err := GetAddresses(store, author, nil)
+	if err != nil {
+		t.Fatalf("failed to list addresses: %v", err)
+	}
+
+	listed := map[string]int{}
+	for _, address := range addresses {
+		listed[address.Coordinate] = len(address.Versions)
+	}
+
+	expected := map[string]int{
+		Coordinate{Kind: 0, PubKey: author}.String():                            1,
+		Coordinate{Kind: 30023, PubKey: author, Identifier: "article"}.String(): 1,
+	}
+
+	if len(listed) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, listed)
+	}
+
+	for coordinate, versions := range expected {
+		if listed[coordinate] != versions {
+			t.Errorf("expected %d versions at %s, got %d", versions, coordinate, listed[coordinate])
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package addressable
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+type Server struct {
+	storage stores.Store
+}
+
+func NewServer(store stores.Store) *Server {
+	return &Server{storage: store}
+}
+
+func (s *Server) SetupRoutes(app *fiber.App) {
+	app.Get("/addressable/:pubkey", s.getAddresses)
+	app.Get("/naddr/:naddr", s.getNaddr)
+}
+
+// Lists the coordinates and stored versions of an author's replaceable and addressable events,
+// ?kinds=30023,30009 limits the listing to those kinds
+func (s *Server) getAddresses(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderAccessControlAllowOrigin, "*")
+
+	pubkey := c.Params("pubkey")
+	if !nostr.IsValid32ByteHex(pubkey) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid pubkey"})
+	}
+
+	var kinds []int
+	if value := c.Query("kinds"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			kind, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid kind: " + part})
+			}
+
+			kinds = append(kinds, kind)
+		}
+	}
+
+	addresses, err := GetAddresses(s.storage, pubkey, kinds)
+	if err != nil {
+		log.Printf("Error listing addressable events for %s: %v", pubkey, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "failed to list addressable events"})
+	}
+
+	return c.JSON(addresses)
+}
+
+// Returns the latest event at the coordinate of a naddr
+func (s *Server) getNaddr(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderAccessControlAllowOrigin, "*")
+
+	coordinate, err := DecodeNaddr(c.Params("naddr"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	event, err := Get(s.storage, coordinate)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	return c.JSON(event)
+}
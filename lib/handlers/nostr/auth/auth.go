@@ -31,24 +31,24 @@ func BuildAuthHandler(store stores.Store) func(read lib_nostr.KindReader, write
 		}
 
 		if request.Event.Kind != 22242 {
-			write("OK", request.Event.ID, false, "Error auth event kind must be 22242")
+			lib_nostr.Reject(write, request.Event.ID, lib_nostr.PrefixInvalid, "auth event kind must be 22242")
 			return
 		}
 
 		isValid, errMsg := lib_nostr.AuthTimeCheck(request.Event.CreatedAt.Time().Unix())
 		if !isValid {
-			write("OK", request.Event.ID, false, errMsg)
+			lib_nostr.Reject(write, request.Event.ID, lib_nostr.PrefixInvalid, errMsg)
 			return
 		}
 
 		result, err := request.Event.CheckSignature()
 		if err != nil {
-			write("OK", request.Event.ID, false, "Error checking event signature")
+			lib_nostr.Reject(write, request.Event.ID, lib_nostr.PrefixInvalid, "failed to check signature")
 			return
 		}
 
 		if !result {
-			write("OK", request.Event.ID, false, "Error signature verification failed")
+			lib_nostr.Reject(write, request.Event.ID, lib_nostr.PrefixInvalid, "signature failed to verify")
 			return
 		}
 
@@ -66,12 +66,12 @@ func BuildAuthHandler(store stores.Store) func(read lib_nostr.KindReader, write
 		}
 
 		if !hasRelayTag || !hasChallengeTag {
-			write("CLOSE", request.Event.ID, false, "Error event does not have required tags")
+			lib_nostr.Reject(write, request.Event.ID, lib_nostr.PrefixInvalid, "auth event must have 'relay' and 'challenge' tags")
 			return
 		}
 
 		// GET SESSION AND SET IT TO AUTHORIZED
 
-		write("OK", request.Event.ID, true, "")
+		lib_nostr.Accept(write, request.Event.ID, "")
 	}
 }
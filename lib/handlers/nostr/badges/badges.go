@@ -0,0 +1,256 @@
+package badges
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+const (
+	KindBadgeAward      = 8
+	KindProfileBadges   = 30008
+	KindBadgeDefinition = 30009
+)
+
+// Badge is a badge a user has chosen to display along with the award that gave it to them
+type Badge struct {
+	Definition *nostr.Event `json:"definition"`
+	Award      *nostr.Event `json:"award"`
+}
+
+// ParseAddress splits a badge definition address of the form 30009:<pubkey>:<d tag>
+func ParseAddress(address string) (pubkey string, identifier string, err error) {
+	parts := strings.SplitN(address, ":", 3)
+	if len(parts) != 3 {
+		return "", "", fmt.Errorf("invalid badge address: %s", address)
+	}
+
+	if kind, err := strconv.Atoi(parts[0]); err != nil || kind != KindBadgeDefinition {
+		return "", "", fmt.Errorf("badge address must reference a kind 30009 badge definition: %s", address)
+	}
+
+	if !nostr.IsValid32ByteHex(parts[1]) {
+		return "", "", fmt.Errorf("invalid pubkey in badge address: %s", address)
+	}
+
+	return parts[1], parts[2], nil
+}
+
+// GetDefinition retrieves the badge definition an address points to
+func GetDefinition(store stores.Store, address string) (*nostr.Event, error) {
+	pubkey, identifier, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := nostr.Filter{
+		Authors: []string{pubkey},
+		Kinds:   []int{KindBadgeDefinition},
+	}
+
+	events, err := store.QueryEvents(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		if filter.Matches(event) && event.Tags.GetD() == identifier {
+			return event, nil
+		}
+	}
+
+	return nil, fmt.Errorf("badge definition not found: %s", address)
+}
+
+// ValidateAward checks a badge award references a definition that exists and was created by the awarder
+func ValidateAward(store stores.Store, award *nostr.Event) error {
+	addressTag := award.Tags.GetFirst([]string{"a", ""})
+	if addressTag == nil {
+		return fmt.Errorf("invalid: badge award must reference a badge definition with an 'a' tag")
+	}
+
+	if award.Tags.GetFirst([]string{"p", ""}) == nil {
+		return fmt.Errorf("invalid: badge award must name at least one recipient with a 'p' tag")
+	}
+
+	pubkey, _, err := ParseAddress(addressTag.Value())
+	if err != nil {
+		return fmt.Errorf("invalid: %v", err)
+	}
+
+	if pubkey != award.PubKey {
+		return fmt.Errorf("invalid: badges can only be awarded by the author of the badge definition")
+	}
+
+	if _, err := GetDefinition(store, addressTag.Value()); err != nil {
+		return fmt.Errorf("invalid: %v", err)
+	}
+
+	return nil
+}
+
+// ValidateProfileBadges checks every a and e pair in a profile badges event references an award
+// of that badge which names the profile owner
+func ValidateProfileBadges(store stores.Store, profile *nostr.Event) error {
+	pairs, err := badgePairs(profile)
+	if err != nil {
+		return fmt.Errorf("invalid: %v", err)
+	}
+
+	for _, pair := range pairs {
+		if _, err := getAward(store, pair[0], pair[1], profile.PubKey); err != nil {
+			return fmt.Errorf("invalid: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// GetDisplayableBadges resolves the badges a user has chosen to display, badges whose award or definition
+// no longer exists or wouldn't be returned by a REQ are skipped
+func GetDisplayableBadges(store stores.Store, pubkey string) ([]Badge, error) {
+	filter := nostr.Filter{
+		Authors: []string{pubkey},
+		Kinds:   []int{KindProfileBadges},
+	}
+
+	events, err := store.QueryEvents(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile *nostr.Event
+	for _, event := range lib_nostr.FilterPublicResults(events) {
+		if !filter.Matches(event) || event.Tags.GetD() != "profile_badges" {
+			continue
+		}
+
+		if profile == nil || event.CreatedAt > profile.CreatedAt {
+			profile = event
+		}
+	}
+
+	badges := []Badge{}
+	if profile == nil {
+		return badges, nil
+	}
+
+	pairs, err := badgePairs(profile)
+	if err != nil {
+		return badges, nil
+	}
+
+	for _, pair := range pairs {
+		award, err := getAward(store, pair[0], pair[1], pubkey)
+		if err != nil {
+			continue
+		}
+
+		definition, err := GetDefinition(store, pair[0])
+		if err != nil {
+			continue
+		}
+
+		badges = append(badges, Badge{Definition: definition, Award: award})
+	}
+
+	// The awards and definitions are filtered together so the query filters look up what they need once
+	resolved := []*nostr.Event{}
+	for _, badge := range badges {
+		resolved = append(resolved, badge.Definition, badge.Award)
+	}
+
+	visible := map[string]bool{}
+	for _, event := range lib_nostr.FilterPublicResults(resolved) {
+		visible[event.ID] = true
+	}
+
+	return slices.DeleteFunc(badges, func(badge Badge) bool {
+		return !visible[badge.Definition.ID] || !visible[badge.Award.ID]
+	}), nil
+}
+
+// Badges are listed as consecutive a and e tags, the a tag being the definition and the e tag the award
+func badgePairs(profile *nostr.Event) ([][2]string, error) {
+	var pairs [][2]string
+
+	var address string
+	for _, tag := range profile.Tags {
+		if len(tag) == 0 || (tag[0] != "a" && tag[0] != "e") {
+			continue
+		}
+
+		if len(tag) < 2 || tag[1] == "" {
+			return nil, fmt.Errorf("'%s' tag is missing a value", tag[0])
+		}
+
+		if tag[0] == "a" {
+			if address != "" {
+				return nil, fmt.Errorf("badge %s is not followed by its award", address)
+			}
+
+			address = tag[1]
+			continue
+		}
+
+		if address == "" {
+			return nil, fmt.Errorf("award %s is not preceded by its badge", tag[1])
+		}
+
+		pairs = append(pairs, [2]string{address, tag[1]})
+		address = ""
+	}
+
+	if address != "" {
+		return nil, fmt.Errorf("badge %s is not followed by its award", address)
+	}
+
+	return pairs, nil
+}
+
+func getAward(store stores.Store, address string, awardID string, recipient string) (*nostr.Event, error) {
+	filter := nostr.Filter{
+		IDs:   []string{awardID},
+		Kinds: []int{KindBadgeAward},
+	}
+
+	events, err := store.QueryEvents(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, award := range events {
+		if !filter.Matches(award) {
+			continue
+		}
+
+		if !hasTag(award.Tags, "a", address) {
+			return nil, fmt.Errorf("award %s is not an award of badge %s", awardID, address)
+		}
+
+		if !hasTag(award.Tags, "p", recipient) {
+			return nil, fmt.Errorf("award %s was not given to %s", awardID, recipient)
+		}
+
+		return award, nil
+	}
+
+	return nil, fmt.Errorf("badge award not found: %s", awardID)
+}
+
+// Tags.GetFirst matches values by prefix so it can't be used to compare full addresses
+func hasTag(tags nostr.Tags, name string, value string) bool {
+	for _, tag := range tags {
+		if len(tag) >= 2 && tag[0] == name && tag[1] == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,184 @@
+package badges
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Only the event queries of the store are implemented
+type eventStore struct {
+	stores.Store
+
+	events []*nostr.Event
+}
+
+func (store *eventStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	events := []*nostr.Event{}
+	for _, event := range store.events {
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func hexKey(character string) string {
+	return strings.Repeat(character, 64)
+}
+
+func TestGetDisplayableBadges(t *testing.T) {
+	issuer := hexKey("a")
+	bannedIssuer := hexKey("b")
+	owner := hexKey("c")
+	hiddenOwner := hexKey("d")
+
+	banned := map[string]bool{bannedIssuer: true, hiddenOwner: true}
+	t.Cleanup(lib_nostr.ResetRegistrations)
+	lib_nostr.RegisterQueryFilter(func(events []*nostr.Event) []*nostr.Event {
+		filtered := []*nostr.Event{}
+		for _, event := range events {
+			if !banned[event.PubKey] {
+				filtered = append(filtered, event)
+			}
+		}
+
+		return filtered
+	})
+
+	var events []*nostr.Event
+	badge := func(id string, awarder string, identifier string, recipient string) [2]string {
+		address := "30009:" + awarder + ":" + identifier
+		events = append(events,
+			&nostr.Event{ID: hexKey(id), PubKey: awarder, Kind: KindBadgeDefinition, Tags: nostr.Tags{{"d", identifier}}},
+			&nostr.Event{ID: hexKey(id) + "award", PubKey: awarder, Kind: KindBadgeAward, Tags: nostr.Tags{{"a", address}, {"p", recipient}}},
+		)
+
+		return [2]string{address, hexKey(id) + "award"}
+	}
+
+	profile := func(id string, pubkey string, pairs ...[2]string) {
+		tags := nostr.Tags{{"d", "profile_badges"}}
+		for _, pair := range pairs {
+			tags = append(tags, nostr.Tag{"a", pair[0]}, nostr.Tag{"e", pair[1]})
+		}
+
+		events = append(events, &nostr.Event{ID: hexKey(id), PubKey: pubkey, Kind: KindProfileBadges, Tags: tags})
+	}
+
+	bravery := badge("1", issuer, "bravery", owner)
+	spam := badge("2", bannedIssuer, "spam", owner)
+	profile("3", owner, bravery, spam)
+	profile("4", hiddenOwner, badge("5", issuer, "bravery", hiddenOwner))
+
+	store := &eventStore{events: events}
+
+	tests := []struct {
+		name     string
+		pubkey   string
+		expected []string
+	}{
+		{"badges from banned issuers are skipped", owner, []string{"bravery"}},
+		{"hidden profiles show no badges", hiddenOwner, nil},
+		{"no profile badges", issuer, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			badges, err := GetDisplayableBadges(store, test.pubkey)
+			if err != nil {
+				t.Fatalf("failed to resolve badges: %v", err)
+			}
+
+			if len(badges) != len(test.expected) {
+				t.Fatalf("expected %v, got %d badges", test.expected, len(badges))
+			}
+
+			for i, badge := range badges {
+				if badge.Definition.Tags.GetD() != test.expected[i] {
+					t.Errorf("expected %s, got %s", test.expected[i], badge.Definition.Tags.GetD())
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAward(t *testing.T) {
+	issuer := hexKey("a")
+	address := "30009:" + issuer + ":bravery"
+
+	store := &eventStore{events: []*nostr.Event{
+		{ID: hexKey("1"), PubKey: issuer, Kind: KindBadgeDefinition, Tags: nostr.Tags{{"d", "bravery"}}},
+	}}
+
+	tests := []struct {
+		name  string
+		award *nostr.Event
+		valid bool
+	}{
+		{"awarded by the issuer", &nostr.Event{PubKey: issuer, Tags: nostr.Tags{{"a", address}, {"p", hexKey("c")}}}, true},
+		{"no definition", &nostr.Event{PubKey: issuer, Tags: nostr.Tags{{"p", hexKey("c")}}}, false},
+		{"no recipient", &nostr.Event{PubKey: issuer, Tags: nostr.Tags{{"a", address}}}, false},
+		{"awarded by someone else", &nostr.Event{PubKey: hexKey("b"), Tags: nostr.Tags{{"a", address}, {"p", hexKey("c")}}}, false},
+		{"definition not stored", &nostr.Event{PubKey: issuer, Tags: nostr.Tags{{"a", address + "2"}, {"p", hexKey("c")}}}, false},
+		{"not a badge definition", &nostr.Event{PubKey: issuer, Tags: nostr.Tags{{"a", "30023:" + issuer + ":bravery"}, {"p", hexKey("c")}}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateAward(store, test.award)
+			if test.valid && err != nil {
+				t.Errorf("expected the award to be accepted, got %v", err)
+			}
+
+			if !test.valid && err == nil {
+				t.Errorf("expected the award to be rejected")
+			}
+		})
+	}
+}
+
+func TestValidateProfileBadges(t *testing.T) {
+	issuer := hexKey("a")
+	owner := hexKey("c")
+	bravery := "30009:" + issuer + ":bravery"
+	kindness := "30009:" + issuer + ":kindness"
+
+	store := &eventStore{events: []*nostr.Event{
+		{ID: hexKey("1"), PubKey: issuer, Kind: KindBadgeAward, Tags: nostr.Tags{{"a", bravery}, {"p", owner}}},
+		{ID: hexKey("2"), PubKey: issuer, Kind: KindBadgeAward, Tags: nostr.Tags{{"a", kindness}, {"p", hexKey("d")}}},
+	}}
+
+	tests := []struct {
+		name  string
+		tags  nostr.Tags
+		valid bool
+	}{
+		{"no badges", nostr.Tags{{"d", "profile_badges"}}, true},
+		{"award given to the owner", nostr.Tags{{"d", "profile_badges"}, {"a", bravery}, {"e", hexKey("1")}}, true},
+		{"award given to someone else", nostr.Tags{{"a", kindness}, {"e", hexKey("2")}}, false},
+		{"award of another badge", nostr.Tags{{"a", kindness}, {"e", hexKey("1")}}, false},
+		{"award not stored", nostr.Tags{{"a", bravery}, {"e", hexKey("3")}}, false},
+		{"badge without its award", nostr.Tags{{"a", bravery}}, false},
+		{"award without its badge", nostr.Tags{{"e", hexKey("1")}}, false},
+		{"two badges in a row", nostr.Tags{{"a", bravery}, {"a", kindness}, {"e", hexKey("1")}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateProfileBadges(store, &nostr.Event{PubKey: owner, Kind: KindProfileBadges, Tags: test.tags})
+			if test.valid && err != nil {
+				t.Errorf("expected the profile badges to be accepted, got %v", err)
+			}
+
+			if !test.valid && err == nil {
+				t.Errorf("expected the profile badges to be rejected")
+			}
+		})
+	}
+}
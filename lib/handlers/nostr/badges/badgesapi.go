@@ -0,0 +1,40 @@
+package badges
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+type Server struct {
+	storage stores.Store
+}
+
+func NewServer(store stores.Store) *Server {
+	return &Server{storage: store}
+}
+
+func (s *Server) SetupRoutes(app *fiber.App) {
+	app.Get("/badges/:pubkey", s.getBadges)
+}
+
+// Resolves the profile badges, awards and definitions for a user so clients don't need three round trips
+func (s *Server) getBadges(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderAccessControlAllowOrigin, "*")
+
+	pubkey := c.Params("pubkey")
+	if !nostr.IsValid32ByteHex(pubkey) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid pubkey"})
+	}
+
+	badges, err := GetDisplayableBadges(s.storage, pubkey)
+	if err != nil {
+		log.Printf("Error resolving badges for %s: %v", pubkey, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "failed to resolve badges"})
+	}
+
+	return c.JSON(badges)
+}
@@ -2,7 +2,10 @@ package count
 
 import (
 	"log"
+	"slices"
 
+	"github.com/HORNET-Storage/hornet-storage/lib/aggregates"
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/nbd-wtf/go-nostr"
@@ -10,7 +13,14 @@ import (
 	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
 )
 
+// BuildCountsHandler constructs a COUNT handler for connections that haven't authenticated, such as libp2p streams
 func BuildCountsHandler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	return BuildAuthenticatedCountsHandler(store, "")
+}
+
+// BuildAuthenticatedCountsHandler constructs a COUNT handler that only counts the events the authenticated pubkey
+// would receive from a REQ with the same filters
+func BuildAuthenticatedCountsHandler(store stores.Store, pubkey string) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
 	return func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
 		var json = jsoniter.ConfigCompatibleWithStandardLibrary
 
@@ -28,21 +38,34 @@ func BuildCountsHandler(store stores.Store) func(read lib_nostr.KindReader, writ
 			return
 		}
 
-		// Check if the request is for counting restricted content
-		if isRestrictedCountRequest(request.Filters) {
+		// Direct messages and gift wraps are only counted for their author and recipients, like a REQ
+		if reason := lib_nostr.CheckReadAccess(request.Filters, pubkey); reason != "" {
 			log.Printf("Refusing to count restricted content for subscription ID: %s\n", request.SubscriptionID)
-			write("CLOSED", request.SubscriptionID, "auth-required: cannot count other people's DMs")
+			write("CLOSED", request.SubscriptionID, reason)
 			return
 		}
 
 		var totalCount int
 		for _, filter := range request.Filters {
-			count, err := store.QueryEvents(filter) //CountEvents(filter) // Assume QueryEvents now returns both events and counts or adjust accordingly
+			// Reactions, reposts and zaps of specific events are answered from the running totals
+			if aggregateCount, ok := aggregates.Count(store.GetStatsStore(), filter); ok && canReadTarget(store, filter, pubkey) {
+				totalCount += int(aggregateCount) - len(moderatedEvents(store, filter))
+				continue
+			}
+
+			events, err := store.QueryEvents(filter)
 			if err != nil {
 				log.Printf("Error counting events for filter: %v", err)
 				continue
 			}
-			totalCount += len(count)
+
+			// Events left out of query results, such as hidden channel messages, or that the pubkey can't read,
+			// such as the events of private groups, aren't counted either
+			for _, event := range lib_nostr.FilterQueryResults(events) {
+				if lib_nostr.CanReadEvent(event, pubkey) {
+					totalCount++
+				}
+			}
 		}
 
 		log.Printf("Total count: %d", totalCount)
@@ -52,14 +75,68 @@ func BuildCountsHandler(store stores.Store) func(read lib_nostr.KindReader, writ
 	}
 }
 
-// isRestrictedCountRequest remains the same as in your original handleCounts
-func isRestrictedCountRequest(filters []nostr.Filter) bool {
-	for _, filter := range filters {
-		for _, kind := range filter.Kinds {
-			if kind == 4 { // Assuming '4' is for direct messages or similar
-				return true
+// Moderated events are still in the totals so they're looked up to be taken off, only the events by moderated
+// pubkeys or with moderated ids are queried and the ones the query filters leave out are returned
+func moderatedEvents(store stores.Store, filter nostr.Filter) []*nostr.Event {
+	pubkeys, eventIDs := moderation.Excluded(store.GetStatsStore())
+
+	candidates := map[string]*nostr.Event{}
+	for _, excluded := range []nostr.Filter{{Authors: pubkeys}, {IDs: eventIDs}} {
+		if len(excluded.Authors) == 0 && len(excluded.IDs) == 0 {
+			continue
+		}
+
+		moderatedFilter := filter
+		moderatedFilter.Authors = excluded.Authors
+		moderatedFilter.IDs = excluded.IDs
+
+		events, err := store.QueryEvents(moderatedFilter)
+		if err != nil {
+			log.Printf("Error querying moderated events for filter: %v", err)
+			continue
+		}
+
+		for _, event := range events {
+			if moderatedFilter.Matches(event) {
+				candidates[event.ID] = event
 			}
 		}
 	}
-	return false
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	events := make([]*nostr.Event, 0, len(candidates))
+	for _, event := range candidates {
+		events = append(events, event)
+	}
+
+	served := map[string]bool{}
+	for _, event := range lib_nostr.FilterQueryResults(events) {
+		served[event.ID] = true
+	}
+
+	return slices.DeleteFunc(events, func(event *nostr.Event) bool {
+		return served[event.ID]
+	})
+}
+
+// The totals include every reaction, repost and zap so they're only used when the pubkey can read the event
+// they were given to, reactions to events of a private group belong to the group and are counted with a query
+func canReadTarget(store stores.Store, filter nostr.Filter, pubkey string) bool {
+	ids := filter.Tags["e"]
+
+	events, err := store.QueryEvents(nostr.Filter{IDs: ids})
+	if err != nil {
+		return false
+	}
+
+	for _, event := range events {
+		if slices.Contains(ids, event.ID) && !lib_nostr.CanReadEvent(event, pubkey) {
+			return false
+		}
+	}
+
+	return true
 }
@@ -0,0 +1,209 @@
+package count
+
+import (
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Only the totals and the moderation lists of the statistics store are implemented
+type countStats struct {
+	stores.StatisticsStore
+
+	aggregates map[string]*types.EventAggregate
+	banned     []types.ModerationEntry
+	hidden     []types.ReportTarget
+}
+
+func (stats *countStats) GetEventAggregate(eventID string) (*types.EventAggregate, error) {
+	if aggregate, ok := stats.aggregates[eventID]; ok {
+		return aggregate, nil
+	}
+
+	return &types.EventAggregate{EventID: eventID}, nil
+}
+
+func (stats *countStats) GetModerationEntries(list string) ([]types.ModerationEntry, error) {
+	entries := []types.ModerationEntry{}
+	for _, entry := range stats.banned {
+		if entry.List == list {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func (stats *countStats) GetReportTargets(status string) ([]types.ReportTarget, error) {
+	return stats.hidden, nil
+}
+
+// Only the event queries of the store are implemented
+type countStore struct {
+	stores.Store
+
+	stats  *countStats
+	events []*nostr.Event
+}
+
+func (store *countStore) GetStatsStore() stores.StatisticsStore {
+	return store.stats
+}
+
+func (store *countStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	events := []*nostr.Event{}
+	for _, event := range store.events {
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func hexKey(character string) string {
+	return strings.Repeat(character, 64)
+}
+
+func TestCountAppliesModeration(t *testing.T) {
+	note := hexKey("0")
+	reaction := func(id string, pubkey string) *nostr.Event {
+		return &nostr.Event{ID: id, Kind: 7, PubKey: pubkey, Tags: nostr.Tags{{"e", note}}}
+	}
+
+	store := &countStore{
+		stats: &countStats{
+			aggregates: map[string]*types.EventAggregate{note: {EventID: note, Reactions: 5}},
+			banned: []types.ModerationEntry{
+				{List: moderation.ListBannedPubkey, Value: hexKey("b")},
+				{List: moderation.ListBannedEvent, Value: hexKey("3")},
+			},
+			hidden: []types.ReportTarget{{TargetType: moderation.TargetPubkey, Target: hexKey("c"), Status: moderation.StatusHidden}},
+		},
+		events: []*nostr.Event{
+			reaction(hexKey("1"), hexKey("a")),
+			reaction(hexKey("2"), hexKey("b")),
+			reaction(hexKey("3"), hexKey("a")),
+			reaction(hexKey("4"), hexKey("c")),
+			reaction(hexKey("5"), hexKey("a")),
+			// Banned but not a reaction to the note
+			{ID: hexKey("6"), Kind: 1, PubKey: hexKey("b"), Tags: nostr.Tags{{"e", note}}},
+		},
+	}
+
+	t.Cleanup(lib_nostr.ResetRegistrations)
+	lib_nostr.RegisterQueryFilter(moderation.BuildQueryFilter(store.stats))
+
+	tests := []struct {
+		name   string
+		filter nostr.Filter
+		count  int
+	}{
+		{"answered from the totals", nostr.Filter{Kinds: []int{7}, Tags: nostr.TagMap{"e": {note}}}, 2},
+		{"answered with a query", nostr.Filter{Kinds: []int{1, 7}, Tags: nostr.TagMap{"e": {note}}}, 2},
+	}
+
+	handler := BuildCountsHandler(store)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, _ := jsoniter.Marshal(nostr.CountEnvelope{SubscriptionID: "sub", Filters: nostr.Filters{test.filter}})
+
+			var response string
+			handler(func() ([]byte, error) { return request, nil }, func(messageType string, params ...interface{}) {
+				response = params[1].(string)
+			})
+
+			var result struct {
+				Count int `json:"count"`
+			}
+			jsoniter.Unmarshal([]byte(response), &result)
+
+			if result.Count != test.count {
+				t.Errorf("expected %d, got %d", test.count, result.Count)
+			}
+		})
+	}
+}
+
+func TestCountAppliesReadChecks(t *testing.T) {
+	victim, member := hexKey("a"), hexKey("b")
+	groupNote := hexKey("0")
+
+	restrictedKinds := viper.Get("restricted_read_kinds")
+	viper.Set("restricted_read_kinds", []int{4})
+	defer viper.Set("restricted_read_kinds", restrictedKinds)
+
+	store := &countStore{
+		stats: &countStats{
+			aggregates: map[string]*types.EventAggregate{groupNote: {EventID: groupNote, Reactions: 2}},
+		},
+		events: []*nostr.Event{
+			{ID: hexKey("1"), Kind: 1, PubKey: hexKey("c"), Tags: nostr.Tags{{"p", victim}}},
+			{ID: hexKey("2"), Kind: 4, PubKey: hexKey("c"), Tags: nostr.Tags{{"p", victim}}},
+			{ID: groupNote, Kind: 11, PubKey: member, Tags: nostr.Tags{{"h", "private"}}},
+			{ID: hexKey("3"), Kind: 7, PubKey: member, Tags: nostr.Tags{{"h", "private"}, {"e", groupNote}}},
+			{ID: hexKey("4"), Kind: 7, PubKey: member, Tags: nostr.Tags{{"h", "private"}, {"e", groupNote}}},
+		},
+	}
+
+	// Events of the private group are only readable by its member
+	t.Cleanup(lib_nostr.ResetRegistrations)
+	lib_nostr.RegisterReadCheck(func(event *nostr.Event, pubkey string) bool {
+		return event.Tags.GetFirst([]string{"h", "private"}) == nil || pubkey == member
+	})
+
+	tests := []struct {
+		name        string
+		pubkey      string
+		filter      nostr.Filter
+		messageType string
+		count       int
+	}{
+		{"kindless filter unauthenticated", "", nostr.Filter{Tags: nostr.TagMap{"p": {victim}}}, "COUNT", 1},
+		{"kindless filter by the recipient", victim, nostr.Filter{Tags: nostr.TagMap{"p": {victim}}}, "COUNT", 2},
+		{"restricted kind unauthenticated", "", nostr.Filter{Kinds: []int{4}, Tags: nostr.TagMap{"p": {victim}}}, "CLOSED", 0},
+		{"restricted kind by the recipient", victim, nostr.Filter{Kinds: []int{4}, Tags: nostr.TagMap{"p": {victim}}}, "COUNT", 1},
+		{"private group events unauthenticated", "", nostr.Filter{Authors: []string{member}}, "COUNT", 0},
+		{"private group events by a member", member, nostr.Filter{Authors: []string{member}}, "COUNT", 3},
+		{"private group reactions unauthenticated", "", nostr.Filter{Kinds: []int{7}, Tags: nostr.TagMap{"e": {groupNote}}}, "COUNT", 0},
+		{"private group reactions by a member", member, nostr.Filter{Kinds: []int{7}, Tags: nostr.TagMap{"e": {groupNote}}}, "COUNT", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, _ := jsoniter.Marshal(nostr.CountEnvelope{SubscriptionID: "sub", Filters: nostr.Filters{test.filter}})
+
+			var messageType, response string
+			BuildAuthenticatedCountsHandler(store, test.pubkey)(func() ([]byte, error) { return request, nil }, func(responseType string, params ...interface{}) {
+				messageType = responseType
+				response = params[1].(string)
+			})
+
+			if messageType != test.messageType {
+				t.Fatalf("expected %s, got %s: %s", test.messageType, messageType, response)
+			}
+
+			if messageType != "COUNT" {
+				return
+			}
+
+			var result struct {
+				Count int `json:"count"`
+			}
+			jsoniter.Unmarshal([]byte(response), &result)
+
+			if result.Count != test.count {
+				t.Errorf("expected %d, got %d", test.count, result.Count)
+			}
+		})
+	}
+}
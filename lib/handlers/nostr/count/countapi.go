@@ -0,0 +1,65 @@
+package count
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/aggregates"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+)
+
+type Server struct {
+	storage stores.Store
+}
+
+func NewServer(store stores.Store) *Server {
+	return &Server{storage: store}
+}
+
+func (s *Server) SetupRoutes(app *fiber.App) {
+	app.Get("/aggregates/:id", s.getAggregate)
+}
+
+// Returns the reaction, repost and zap totals for an event, the totals are only served for events a REQ would
+// return and reactions, reposts and zaps a REQ would leave out are taken off them
+func (s *Server) getAggregate(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderAccessControlAllowOrigin, "*")
+
+	eventID := c.Params("id")
+	if !nostr.IsValid32ByteHex(eventID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid event id"})
+	}
+
+	stats := s.storage.GetStatsStore()
+	if stats == nil {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"message": "aggregates are not available"})
+	}
+
+	// Totals are also kept for events stored elsewhere, only an event that is stored here can be hidden
+	events, err := s.storage.QueryEvents(nostr.Filter{IDs: []string{eventID}})
+	if err != nil {
+		log.Printf("Error fetching event %s: %v", eventID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "failed to fetch aggregates"})
+	}
+
+	for _, event := range events {
+		if event.ID == eventID && len(lib_nostr.FilterPublicResults([]*nostr.Event{event})) == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "event not found"})
+		}
+	}
+
+	aggregate, err := stats.GetEventAggregate(eventID)
+	if err != nil {
+		log.Printf("Error fetching aggregates for %s: %v", eventID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "failed to fetch aggregates"})
+	}
+
+	filter := nostr.Filter{Kinds: aggregates.Kinds(), Tags: nostr.TagMap{"e": []string{eventID}}}
+	aggregates.Exclude(aggregate, moderatedEvents(s.storage, filter))
+
+	return c.JSON(aggregate)
+}
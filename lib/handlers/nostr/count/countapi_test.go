@@ -0,0 +1,95 @@
+package count
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation"
+)
+
+func TestGetAggregate(t *testing.T) {
+	note := hexKey("7")
+	bannedNote := hexKey("8")
+	elsewhere := hexKey("9")
+
+	reaction := func(id string, pubkey string, target string, content string) *nostr.Event {
+		return &nostr.Event{ID: id, Kind: 7, PubKey: pubkey, Content: content, Tags: nostr.Tags{{"e", target}}}
+	}
+
+	store := &countStore{
+		stats: &countStats{
+			aggregates: map[string]*types.EventAggregate{
+				note:       {EventID: note, Reactions: 3, ReactionCounts: map[string]int64{"+": 2, "🔥": 1}},
+				bannedNote: {EventID: bannedNote, Reactions: 1, ReactionCounts: map[string]int64{"+": 1}},
+				elsewhere:  {EventID: elsewhere, Reactions: 1, ReactionCounts: map[string]int64{"+": 1}},
+			},
+			banned: []types.ModerationEntry{{List: moderation.ListBannedPubkey, Value: hexKey("d")}},
+		},
+		events: []*nostr.Event{
+			{ID: note, Kind: 1, PubKey: hexKey("e")},
+			{ID: bannedNote, Kind: 1, PubKey: hexKey("d")},
+			reaction(hexKey("f"), hexKey("e"), note, "+"),
+			reaction(hexKey("a"), hexKey("e"), note, "+"),
+			reaction(hexKey("1"), hexKey("d"), note, "🔥"),
+			reaction(hexKey("2"), hexKey("e"), bannedNote, "+"),
+			reaction(hexKey("5"), hexKey("e"), elsewhere, "+"),
+		},
+	}
+
+	t.Cleanup(lib_nostr.ResetRegistrations)
+	lib_nostr.RegisterQueryFilter(moderation.BuildQueryFilter(store.stats))
+
+	app := fiber.New()
+	NewServer(store).SetupRoutes(app)
+
+	tests := []struct {
+		name      string
+		eventID   string
+		status    int
+		reactions int64
+		counts    map[string]int64
+	}{
+		{"banned reactions are taken off", note, fiber.StatusOK, 2, map[string]int64{"+": 2}},
+		{"banned events have no totals", bannedNote, fiber.StatusNotFound, 0, nil},
+		{"events stored elsewhere keep their totals", elsewhere, fiber.StatusOK, 1, map[string]int64{"+": 1}},
+		{"invalid id", "note", fiber.StatusBadRequest, 0, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := app.Test(httptest.NewRequest("GET", "/aggregates/"+test.eventID, nil))
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+
+			if response.StatusCode != test.status {
+				t.Fatalf("expected status %d, got %d", test.status, response.StatusCode)
+			}
+
+			if test.status != fiber.StatusOK {
+				return
+			}
+
+			var aggregate types.EventAggregate
+			if err := jsoniter.NewDecoder(response.Body).Decode(&aggregate); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if aggregate.Reactions != test.reactions || len(aggregate.ReactionCounts) != len(test.counts) {
+				t.Fatalf("expected %d reactions %v, got %d %v", test.reactions, test.counts, aggregate.Reactions, aggregate.ReactionCounts)
+			}
+
+			for content, count := range test.counts {
+				if aggregate.ReactionCounts[content] != count {
+					t.Errorf("expected %d %s reactions, got %d", count, content, aggregate.ReactionCounts[content])
+				}
+			}
+		})
+	}
+}
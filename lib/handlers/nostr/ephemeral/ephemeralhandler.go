@@ -0,0 +1,55 @@
+package ephemeral
+
+import (
+	"fmt"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+)
+
+// BuildEphemeralHandler constructs and returns a handler function for ephemeral (20000-29999) events.
+// Ephemeral events are validated like any other event and broadcast to subscribers without being stored.
+func BuildEphemeralHandler() func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+		// Read data from the stream.
+		data, err := read()
+		if err != nil {
+			write("NOTICE", "Error reading from stream.")
+			return
+		}
+
+		// Unmarshal the received data into a Nostr event
+		var env nostr.EventEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			write("NOTICE", "Error unmarshaling event.")
+			return
+		}
+
+		if !lib_nostr.IsEphemeralKind(env.Event.Kind) {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, fmt.Sprintf("kind %d is not ephemeral", env.Event.Kind))
+			return
+		}
+
+		// Auth events prove who a connection belongs to so they are sent with AUTH and never broadcast
+		if env.Event.Kind == 22242 {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, "auth events must be sent with AUTH")
+			return
+		}
+
+		// Check relay settings for allowed events whilst also verifying signatures
+		success := lib_nostr.ValidateEvent(write, env, -1)
+		if !success {
+			return
+		}
+
+		lib_nostr.Broadcast(&env.Event)
+
+		lib_nostr.Accept(write, env.Event.ID, "Event broadcast to subscribers")
+	}
+
+	return handler
+}
@@ -0,0 +1,101 @@
+package ephemeral
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Counts the queries made so the duplicate check can be seen to be skipped
+type queryStore struct {
+	stores.Store
+
+	queries int
+}
+
+func (store *queryStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	store.queries++
+	return nil, nil
+}
+
+func (store *queryStore) GetStatsStore() stores.StatisticsStore {
+	return nil
+}
+
+// ValidateEvent loads the relay settings from a config file in the working directory
+func useConfig(t *testing.T, config string) {
+	directory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(directory, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	workingDirectory, _ := os.Getwd()
+	if err := os.Chdir(directory); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	t.Cleanup(func() { os.Chdir(workingDirectory) })
+}
+
+func TestEphemeralHandler(t *testing.T) {
+	useConfig(t, `{"relay_settings": {"mode": "unlimited"}}`)
+
+	store := &queryStore{}
+	lib_nostr.SetValidationStore(store)
+	defer lib_nostr.SetValidationStore(nil)
+
+	var broadcast []string
+	t.Cleanup(lib_nostr.ResetRegistrations)
+	lib_nostr.RegisterBroadcaster(func(event *nostr.Event) {
+		broadcast = append(broadcast, event.ID)
+	})
+
+	tests := []struct {
+		name     string
+		kind     int
+		accepted bool
+	}{
+		{"ephemeral event", 20001, true},
+		{"auth event", 22242, false},
+		{"regular event", 1, false},
+	}
+
+	handler := BuildEphemeralHandler()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store.queries = 0
+			broadcast = nil
+
+			event := nostr.Event{Kind: test.kind, CreatedAt: nostr.Now(), Tags: nostr.Tags{}}
+			event.Sign(nostr.GeneratePrivateKey())
+
+			request, _ := jsoniter.Marshal(nostr.EventEnvelope{Event: event})
+
+			var accepted bool
+			handler(func() ([]byte, error) { return request, nil }, func(messageType string, params ...interface{}) {
+				if messageType == "OK" && len(params) >= 2 {
+					accepted, _ = params[1].(bool)
+				}
+			})
+
+			if accepted != test.accepted {
+				t.Fatalf("expected accepted %v, got %v", test.accepted, accepted)
+			}
+
+			if test.accepted != (len(broadcast) == 1) {
+				t.Errorf("expected broadcast %v, got %v", test.accepted, broadcast)
+			}
+
+			if store.queries != 0 {
+				t.Errorf("expected no store queries for an event that is never stored, got %d", store.queries)
+			}
+		})
+	}
+}
@@ -43,14 +43,12 @@ func BuildFilterHandler(store stores.Store) func(read lib_nostr.KindReader, writ
 		// Deduplicate events
 		uniqueEvents := deduplicateEvents(combinedEvents)
 
-		// Send each unique event to the client
+		// Banned and hidden content is left out by the registered query filters
+		uniqueEvents = lib_nostr.FilterQueryResults(uniqueEvents)
+
+		// Send each unique event to the client, the writer checks the event can be read before serializing it
 		for _, event := range uniqueEvents {
-			eventJSON, err := json.Marshal(event)
-			if err != nil {
-				log.Printf("Error marshaling event: %v", err)
-				continue
-			}
-			write("EVENT", request.SubscriptionID, string(eventJSON))
+			write("EVENT", request.SubscriptionID, event)
 		}
 
 		write("EOSE", request.SubscriptionID, "End of stored events")
@@ -0,0 +1,509 @@
+package groups
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/aggregates"
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// NIP-29 event kinds
+const (
+	KindPutUser       = 9000
+	KindRemoveUser    = 9001
+	KindEditMetadata  = 9002
+	KindDeleteEvent   = 9005
+	KindCreateGroup   = 9007
+	KindDeleteGroup   = 9008
+	KindCreateInvite  = 9009
+	KindJoinRequest   = 9021
+	KindLeaveRequest  = 9022
+	KindGroupMetadata = 39000
+	KindGroupAdmins   = 39001
+	KindGroupMembers  = 39002
+	KindGroupRoles    = 39003
+)
+
+// Group is the state of a group hosted on this relay, it's rebuilt on startup from the relay signed group events
+type Group struct {
+	ID      string
+	Name    string
+	Picture string
+	About   string
+	Private bool // Only members can read
+	Closed  bool // Join requests need an invite code
+	Admins  map[string][]string
+	Members map[string]bool
+	Invites map[string]bool
+}
+
+var (
+	groups      = map[string]*Group{}
+	groupsMutex sync.RWMutex
+
+	// Changes to groups are made one at a time under changeMutex, groupsMutex is only held to read or swap
+	// the live groups so slow store writes never hold up readers
+	changeMutex sync.Mutex
+)
+
+var groupIDPattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// Initialize loads the existing groups and registers the membership checks that apply to every event with an h tag
+func Initialize(store stores.Store) error {
+	if err := loadGroups(store); err != nil {
+		return err
+	}
+
+	lib_nostr.RegisterEventCheck(CheckWrite)
+	lib_nostr.RegisterReadCheck(CanRead)
+
+	return nil
+}
+
+// CheckWrite rejects group events from non members, late publications and group state events not signed by the relay
+// Join and leave requests come from users who aren't members yet (or no longer want to be) and a group being
+// created has no members, these are checked by their own handlers
+func CheckWrite(event *nostr.Event) error {
+	if event.Kind >= KindGroupMetadata && event.Kind <= KindGroupRoles {
+		if event.PubKey != lib_nostr.RelayPubkey() {
+			return fmt.Errorf("blocked: group state events can only be published by the relay")
+		}
+		return nil
+	}
+
+	groupID := GetGroupID(event)
+	if groupID == "" || event.Kind == KindCreateGroup || event.Kind == KindJoinRequest || event.Kind == KindLeaveRequest {
+		return nil
+	}
+
+	if err := checkLatePublication(event); err != nil {
+		return err
+	}
+
+	group := GetGroup(groupID)
+	if group == nil {
+		return fmt.Errorf("invalid: group %s does not exist", groupID)
+	}
+
+	if !group.IsMember(event.PubKey) {
+		return fmt.Errorf("restricted: you are not a member of group %s", groupID)
+	}
+
+	return nil
+}
+
+// CanRead only lets members of a private group receive its events and only lets group admins receive invites,
+// the group state events stay public
+func CanRead(event *nostr.Event, pubkey string) bool {
+	groupID := GetGroupID(event)
+	if groupID == "" {
+		return true
+	}
+
+	group := GetGroup(groupID)
+
+	// Invite codes let anyone into a closed group so they're only served to the admins who hand them out
+	if event.Kind == KindCreateInvite {
+		return group != nil && group.IsAdmin(pubkey)
+	}
+
+	if group == nil || !group.Private {
+		return true
+	}
+
+	return group.IsMember(pubkey)
+}
+
+// GetGroupID returns the group an event belongs to from its h tag
+func GetGroupID(event *nostr.Event) string {
+	tag := event.Tags.GetFirst([]string{"h", ""})
+	if tag == nil {
+		return ""
+	}
+
+	return tag.Value()
+}
+
+// GetGroup returns a copy of the group so it can be read without holding the lock
+func GetGroup(id string) *Group {
+	groupsMutex.RLock()
+	defer groupsMutex.RUnlock()
+
+	group, ok := groups[id]
+	if !ok {
+		return nil
+	}
+
+	return group.clone()
+}
+
+func (group *Group) IsMember(pubkey string) bool {
+	if pubkey == "" {
+		return false
+	}
+
+	_, admin := group.Admins[pubkey]
+
+	return admin || group.Members[pubkey]
+}
+
+func (group *Group) IsAdmin(pubkey string) bool {
+	_, admin := group.Admins[pubkey]
+
+	return admin
+}
+
+func (group *Group) clone() *Group {
+	copied := *group
+	copied.Admins = map[string][]string{}
+	copied.Members = map[string]bool{}
+	copied.Invites = map[string]bool{}
+
+	for pubkey, roles := range group.Admins {
+		copied.Admins[pubkey] = slices.Clone(roles)
+	}
+	for pubkey := range group.Members {
+		copied.Members[pubkey] = true
+	}
+	for code := range group.Invites {
+		copied.Invites[code] = true
+	}
+
+	return &copied
+}
+
+func newGroup(id string) *Group {
+	return &Group{
+		ID:      id,
+		Name:    id,
+		Admins:  map[string][]string{},
+		Members: map[string]bool{},
+		Invites: map[string]bool{},
+	}
+}
+
+// Events dated too far in the past are refused so old messages can't be slipped into a group's history
+func checkLatePublication(event *nostr.Event) error {
+	window := viper.GetInt64("group_late_publication_window")
+	if window <= 0 {
+		return nil
+	}
+
+	if event.CreatedAt.Time().Unix() < time.Now().Unix()-window {
+		return fmt.Errorf("invalid: group events can't be published more than %d seconds late", window)
+	}
+
+	return nil
+}
+
+// Rebuild the group state from the relay signed metadata, admin and member events and the stored invites
+func loadGroups(store stores.Store) error {
+	events, err := queryEvents(store, nostr.Filter{
+		Kinds:   []int{KindGroupMetadata, KindGroupAdmins, KindGroupMembers},
+		Authors: []string{lib_nostr.RelayPubkey()},
+	})
+	if err != nil {
+		return err
+	}
+
+	groupsMutex.Lock()
+	defer groupsMutex.Unlock()
+
+	for _, event := range events {
+		id := getDTag(event)
+		if id == "" {
+			continue
+		}
+
+		group, ok := groups[id]
+		if !ok {
+			group = newGroup(id)
+			groups[id] = group
+		}
+
+		switch event.Kind {
+		case KindGroupMetadata:
+			applyMetadata(group, event.Tags)
+		case KindGroupAdmins:
+			for _, tag := range event.Tags {
+				if len(tag) >= 2 && tag[0] == "p" {
+					group.Admins[tag[1]] = tag[2:]
+				}
+			}
+		case KindGroupMembers:
+			for _, tag := range event.Tags {
+				if len(tag) >= 2 && tag[0] == "p" {
+					group.Members[tag[1]] = true
+				}
+			}
+		}
+	}
+
+	invites, err := queryEvents(store, nostr.Filter{Kinds: []int{KindCreateInvite}})
+	if err != nil {
+		return err
+	}
+
+	for _, invite := range invites {
+		group, ok := groups[GetGroupID(invite)]
+		if !ok {
+			continue
+		}
+
+		if code := invite.Tags.GetFirst([]string{"code", ""}); code != nil {
+			group.Invites[code.Value()] = true
+		}
+	}
+
+	log.Printf("Loaded %d groups", len(groups))
+
+	return nil
+}
+
+func applyMetadata(group *Group, tags nostr.Tags) {
+	for _, tag := range tags {
+		if len(tag) == 0 {
+			continue
+		}
+
+		switch tag[0] {
+		case "name":
+			if len(tag) >= 2 {
+				group.Name = tag[1]
+			}
+		case "picture":
+			if len(tag) >= 2 {
+				group.Picture = tag[1]
+			}
+		case "about":
+			if len(tag) >= 2 {
+				group.About = tag[1]
+			}
+		case "public":
+			group.Private = false
+		case "private":
+			group.Private = true
+		case "open":
+			group.Closed = false
+		case "closed":
+			group.Closed = true
+		}
+	}
+}
+
+// Replaces the live group with a changed copy once the change has been stored, nil removes the group
+func setGroup(groupID string, group *Group) {
+	groupsMutex.Lock()
+	defer groupsMutex.Unlock()
+
+	if group == nil {
+		delete(groups, groupID)
+		return
+	}
+
+	groups[groupID] = group
+}
+
+// Publish the relay signed metadata, admin, member and role events for a state of the group, replacing the
+// previous ones, a nil group is one that has been deleted and has its state events removed instead
+// The stored state events are returned so they can be broadcast once the whole change has been stored
+func publishGroup(store stores.Store, groupID string, group *Group) ([]*nostr.Event, error) {
+	if group == nil {
+		for _, kind := range []int{KindGroupMetadata, KindGroupAdmins, KindGroupMembers, KindGroupRoles} {
+			if err := deleteGroupEvents(store, groupID, kind); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, nil
+	}
+
+	published := []*nostr.Event{}
+	for kind, tags := range buildGroupTags(group) {
+		event, err := replaceGroupEvent(store, group.ID, kind, tags)
+		if err != nil {
+			return nil, err
+		}
+
+		published = append(published, event)
+	}
+
+	return published, nil
+}
+
+// Puts back the state events of the live group after a change failed part way through being stored
+func restoreGroup(store stores.Store, groupID string) {
+	if _, err := publishGroup(store, groupID, GetGroup(groupID)); err != nil {
+		log.Printf("Error restoring the state of group %s: %v", groupID, err)
+	}
+}
+
+// The tags of each relay signed group state event
+func buildGroupTags(group *Group) map[int]nostr.Tags {
+	metadata := nostr.Tags{
+		{"d", group.ID},
+		{"name", group.Name},
+	}
+	if group.Picture != "" {
+		metadata = append(metadata, nostr.Tag{"picture", group.Picture})
+	}
+	if group.About != "" {
+		metadata = append(metadata, nostr.Tag{"about", group.About})
+	}
+	if group.Private {
+		metadata = append(metadata, nostr.Tag{"private"})
+	} else {
+		metadata = append(metadata, nostr.Tag{"public"})
+	}
+	if group.Closed {
+		metadata = append(metadata, nostr.Tag{"closed"})
+	} else {
+		metadata = append(metadata, nostr.Tag{"open"})
+	}
+
+	admins := nostr.Tags{{"d", group.ID}}
+	roles := []string{}
+	for _, pubkey := range sortedKeys(group.Admins) {
+		admins = append(admins, append(nostr.Tag{"p", pubkey}, group.Admins[pubkey]...))
+
+		for _, role := range group.Admins[pubkey] {
+			if !slices.Contains(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	members := nostr.Tags{{"d", group.ID}}
+	for _, pubkey := range sortedKeys(group.Members) {
+		members = append(members, nostr.Tag{"p", pubkey})
+	}
+
+	// Roles are whatever has been given to the admins, every role can moderate the group
+	slices.Sort(roles)
+	rolesTags := nostr.Tags{{"d", group.ID}}
+	for _, role := range roles {
+		rolesTags = append(rolesTags, nostr.Tag{"role", role})
+	}
+
+	return map[int]nostr.Tags{
+		KindGroupMetadata: metadata,
+		KindGroupAdmins:   admins,
+		KindGroupMembers:  members,
+		KindGroupRoles:    rolesTags,
+	}
+}
+
+func replaceGroupEvent(store stores.Store, groupID string, kind int, tags nostr.Tags) (*nostr.Event, error) {
+	if err := deleteGroupEvents(store, groupID, kind); err != nil {
+		return nil, err
+	}
+
+	event := &nostr.Event{
+		CreatedAt: nostr.Timestamp(time.Now().Unix()),
+		Kind:      kind,
+		Tags:      tags,
+		Content:   "",
+	}
+
+	if err := lib_nostr.SignEventWithRelayKey(event); err != nil {
+		return nil, fmt.Errorf("failed to sign group event: %v", err)
+	}
+
+	if err := store.StoreEvent(event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// Only the state events signed by the relay are removed, anyone else's events with the same d tag are left alone
+func deleteGroupEvents(store stores.Store, groupID string, kind int) error {
+	existing, err := queryEvents(store, nostr.Filter{
+		Kinds:   []int{kind},
+		Authors: []string{lib_nostr.RelayPubkey()},
+		Tags:    nostr.TagMap{"d": []string{groupID}},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range existing {
+		if err := store.DeleteEvent(event.ID); err != nil {
+			log.Printf("Error deleting old group event %s: %v", event.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Moderators can only delete events that were published to their own group
+func deleteModeratedEvents(store stores.Store, groupID string, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+
+	events, err := queryEvents(store, nostr.Filter{IDs: ids})
+	if err != nil {
+		log.Printf("Error querying group events to delete: %v", err)
+		return
+	}
+
+	for _, event := range events {
+		if GetGroupID(event) != groupID {
+			continue
+		}
+
+		if err := aggregates.DeleteEvent(store, event); err != nil {
+			log.Printf("Error deleting group event %s: %v", event.ID, err)
+		}
+	}
+}
+
+// Only events that really match the filter are returned as tag queries can also match events of other kinds
+func queryEvents(store stores.Store, filter nostr.Filter) ([]*nostr.Event, error) {
+	events, err := store.QueryEvents(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	matching := []*nostr.Event{}
+	for _, event := range events {
+		if seen[event.ID] || !filter.Matches(event) {
+			continue
+		}
+
+		seen[event.ID] = true
+		matching = append(matching, event)
+	}
+
+	return matching, nil
+}
+
+func getDTag(event *nostr.Event) string {
+	tag := event.Tags.GetFirst([]string{"d", ""})
+	if tag == nil {
+		return ""
+	}
+
+	return tag.Value()
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,363 @@
+package groups
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Only the event methods of the store are implemented, storing events of the failing kinds returns an error
+type eventStore struct {
+	stores.Store
+
+	events    map[string]*nostr.Event
+	failKinds map[int]bool
+}
+
+func newEventStore(events ...*nostr.Event) *eventStore {
+	store := &eventStore{events: map[string]*nostr.Event{}}
+	for _, event := range events {
+		store.events[event.ID] = event
+	}
+
+	return store
+}
+
+func (store *eventStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	events := []*nostr.Event{}
+	for _, event := range store.events {
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (store *eventStore) StoreEvent(event *nostr.Event) error {
+	if store.failKinds[event.Kind] {
+		return fmt.Errorf("failed to store kind %d", event.Kind)
+	}
+
+	store.events[event.ID] = event
+	return nil
+}
+
+func (store *eventStore) DeleteEvent(id string) error {
+	delete(store.events, id)
+	return nil
+}
+
+// Every test starts with group "g" where bob is an admin and alice a member
+func setupGroups(t *testing.T) string {
+	relayKey := nostr.GeneratePrivateKey()
+	viper.Set("private_key", relayKey)
+	viper.Set("group_late_publication_window", 3600)
+	t.Cleanup(func() {
+		viper.Set("private_key", "")
+		viper.Set("group_late_publication_window", nil)
+	})
+
+	group := newGroup("g")
+	group.Admins["bob"] = []string{"admin"}
+	group.Members["alice"] = true
+
+	groupsMutex.Lock()
+	groups = map[string]*Group{"g": group}
+	groupsMutex.Unlock()
+
+	relayPubkey, _ := nostr.GetPublicKey(relayKey)
+
+	return relayPubkey
+}
+
+// Handlers load the relay settings from config.json in the working directory
+func useConfig(t *testing.T, config string) {
+	directory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(directory, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	workingDirectory, _ := os.Getwd()
+	if err := os.Chdir(directory); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	t.Cleanup(func() { os.Chdir(workingDirectory) })
+}
+
+func groupEvent(id string, kind int, pubkey string, groupID string) *nostr.Event {
+	return &nostr.Event{
+		ID:        id,
+		Kind:      kind,
+		PubKey:    pubkey,
+		CreatedAt: nostr.Now(),
+		Tags:      nostr.Tags{{"h", groupID}},
+	}
+}
+
+func TestCheckWrite(t *testing.T) {
+	relayPubkey := setupGroups(t)
+
+	late := groupEvent("late", 9, "alice", "g")
+	late.CreatedAt = nostr.Timestamp(time.Now().Add(-2 * time.Hour).Unix())
+
+	tests := []struct {
+		name    string
+		event   *nostr.Event
+		allowed bool
+	}{
+		{"event outside of groups", &nostr.Event{Kind: 1, PubKey: "carol"}, true},
+		{"member message", groupEvent("1", 9, "alice", "g"), true},
+		{"admin message", groupEvent("2", 9, "bob", "g"), true},
+		{"non member message", groupEvent("3", 9, "carol", "g"), false},
+		{"message to a missing group", groupEvent("4", 9, "alice", "missing"), false},
+		{"late message", late, false},
+		{"join request from a non member", groupEvent("5", KindJoinRequest, "carol", "g"), true},
+		{"leave request", groupEvent("6", KindLeaveRequest, "alice", "g"), true},
+		{"new group", groupEvent("7", KindCreateGroup, "carol", "new"), true},
+		{"moderation from a non member", groupEvent("8", KindPutUser, "carol", "g"), false},
+		{"moderation from an admin", groupEvent("9", KindPutUser, "bob", "g"), true},
+		{"group state from the relay", &nostr.Event{Kind: KindGroupMetadata, PubKey: relayPubkey}, true},
+		{"group state from a user", &nostr.Event{Kind: KindGroupMetadata, PubKey: "bob"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckWrite(test.event)
+			if (err == nil) != test.allowed {
+				t.Errorf("expected allowed %v, got error %v", test.allowed, err)
+			}
+		})
+	}
+}
+
+func TestCanRead(t *testing.T) {
+	setupGroups(t)
+
+	private := newGroup("private")
+	private.Private = true
+	private.Members["alice"] = true
+
+	groupsMutex.Lock()
+	groups["private"] = private
+	groupsMutex.Unlock()
+
+	tests := []struct {
+		name     string
+		event    *nostr.Event
+		pubkey   string
+		readable bool
+	}{
+		{"event outside of groups", &nostr.Event{Kind: 1, PubKey: "carol"}, "", true},
+		{"public group message", groupEvent("1", 9, "alice", "g"), "", true},
+		{"private group message to a member", groupEvent("2", 9, "alice", "private"), "alice", true},
+		{"private group message to a non member", groupEvent("3", 9, "alice", "private"), "carol", false},
+		{"private group message without auth", groupEvent("4", 9, "alice", "private"), "", false},
+		{"invite to an admin", groupEvent("5", KindCreateInvite, "bob", "g"), "bob", true},
+		{"invite to a member", groupEvent("6", KindCreateInvite, "bob", "g"), "alice", false},
+		{"invite without auth", groupEvent("7", KindCreateInvite, "bob", "g"), "", false},
+		{"invite to a missing group", groupEvent("8", KindCreateInvite, "bob", "missing"), "bob", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if readable := CanRead(test.event, test.pubkey); readable != test.readable {
+				t.Errorf("expected readable %v, got %v", test.readable, readable)
+			}
+		})
+	}
+}
+
+func TestDeleteEventOnlyRemovesGroupEvents(t *testing.T) {
+	setupGroups(t)
+
+	store := newEventStore(
+		groupEvent("in-group", 9, "alice", "g"),
+		groupEvent("other-group", 9, "carol", "other"),
+		&nostr.Event{ID: "no-group", Kind: 1, PubKey: "carol"},
+	)
+
+	deletion := groupEvent("delete", KindDeleteEvent, "bob", "g")
+	deletion.Tags = append(deletion.Tags, nostr.Tag{"e", "in-group"}, nostr.Tag{"e", "other-group"}, nostr.Tag{"e", "no-group"})
+
+	change, err := applyModeration(deletion)
+	if err != nil {
+		t.Fatalf("failed to apply moderation: %v", err)
+	}
+
+	deleteModeratedEvents(store, "g", change.deleteEvents)
+
+	tests := []struct {
+		id   string
+		kept bool
+	}{
+		{"in-group", false},
+		{"other-group", true},
+		{"no-group", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.id, func(t *testing.T) {
+			if _, kept := store.events[test.id]; kept != test.kept {
+				t.Errorf("expected kept %v, got %v", test.kept, kept)
+			}
+		})
+	}
+}
+
+func TestPublishGroup(t *testing.T) {
+	relayPubkey := setupGroups(t)
+
+	// Someone else's addressable event that happens to use the group id as its d tag
+	unrelated := &nostr.Event{ID: "unrelated", Kind: KindGroupMetadata, PubKey: "carol", Tags: nostr.Tags{{"d", "g"}}}
+	store := newEventStore(unrelated)
+
+	promote := groupEvent("promote", KindPutUser, "bob", "g")
+	promote.Tags = append(promote.Tags, nostr.Tag{"p", "alice", "moderator"})
+
+	change, err := applyModeration(promote)
+	if err != nil || !change.publish {
+		t.Fatalf("expected the group to be published, got %v %v", change.publish, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := publishGroup(store, "g", change.group); err != nil {
+			t.Fatalf("failed to publish group: %v", err)
+		}
+	}
+
+	stateEvents := func(kind int) []*nostr.Event {
+		events, _ := store.QueryEvents(nostr.Filter{Kinds: []int{kind}, Authors: []string{relayPubkey}})
+		return events
+	}
+
+	tests := []struct {
+		name string
+		kind int
+		tag  nostr.Tag
+	}{
+		{"metadata", KindGroupMetadata, nostr.Tag{"name", "g"}},
+		{"admins", KindGroupAdmins, nostr.Tag{"p", "alice", "moderator"}},
+		{"members", KindGroupMembers, nostr.Tag{"p", "alice"}},
+		{"admin role", KindGroupRoles, nostr.Tag{"role", "admin"}},
+		{"moderator role", KindGroupRoles, nostr.Tag{"role", "moderator"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events := stateEvents(test.kind)
+			if len(events) != 1 {
+				t.Fatalf("expected a single state event, got %d", len(events))
+			}
+
+			if !slices.ContainsFunc(events[0].Tags, func(tag nostr.Tag) bool { return slices.Equal(tag, test.tag) }) {
+				t.Errorf("expected tag %v in %v", test.tag, events[0].Tags)
+			}
+		})
+	}
+
+	deleteGroup := groupEvent("delete-group", KindDeleteGroup, "bob", "g")
+	change, err = applyModeration(deleteGroup)
+	if err != nil || change.group != nil {
+		t.Fatalf("expected the group to be deleted, got %v %v", change.group, err)
+	}
+
+	if _, err := publishGroup(store, "g", change.group); err != nil {
+		t.Fatalf("failed to publish deleted group: %v", err)
+	}
+
+	for _, kind := range []int{KindGroupMetadata, KindGroupAdmins, KindGroupMembers, KindGroupRoles} {
+		if events := stateEvents(kind); len(events) != 0 {
+			t.Errorf("expected kind %d state events to be deleted with the group, got %d", kind, len(events))
+		}
+	}
+
+	if _, kept := store.events[unrelated.ID]; !kept {
+		t.Errorf("expected events not signed by the relay to be kept")
+	}
+}
+
+func TestGroupHandlerLeavesFailedChangesOut(t *testing.T) {
+	useConfig(t, `{"relay_settings": {"mode": "unlimited"}}`)
+
+	tests := []struct {
+		name     string
+		failKind int
+		accepted bool
+	}{
+		{"stored", 0, true},
+		{"event fails to store", KindJoinRequest, false},
+		{"group state fails to publish", KindGroupMembers, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			relayPubkey := setupGroups(t)
+
+			var broadcast []int
+			lib_nostr.RegisterBroadcaster(func(event *nostr.Event) {
+				broadcast = append(broadcast, event.Kind)
+			})
+			t.Cleanup(lib_nostr.ResetRegistrations)
+
+			store := newEventStore()
+			store.failKinds = map[int]bool{test.failKind: true}
+
+			secretKey := nostr.GeneratePrivateKey()
+			carol, _ := nostr.GetPublicKey(secretKey)
+
+			join := &nostr.Event{Kind: KindJoinRequest, CreatedAt: nostr.Now(), Tags: nostr.Tags{{"h", "g"}}}
+			join.Sign(secretKey)
+			data, _ := nostr.EventEnvelope{Event: *join}.MarshalJSON()
+
+			var accepted bool
+			BuildJoinRequestHandler(store)(func() ([]byte, error) { return data, nil }, func(messageType string, params ...interface{}) {
+				if messageType == "OK" {
+					accepted = params[1].(bool)
+				}
+			})
+
+			if accepted != test.accepted {
+				t.Fatalf("expected accepted %v, got %v", test.accepted, accepted)
+			}
+
+			if member := GetGroup("g").IsMember(carol); member != test.accepted {
+				t.Errorf("expected member %v, got %v", test.accepted, member)
+			}
+
+			if _, stored := store.events[join.ID]; stored != test.accepted {
+				t.Errorf("expected stored %v, got %v", test.accepted, stored)
+			}
+
+			// The stored group state never lists a member the live group doesn't have
+			members, _ := store.QueryEvents(nostr.Filter{Kinds: []int{KindGroupMembers}, Authors: []string{relayPubkey}})
+			listed := slices.ContainsFunc(members, func(event *nostr.Event) bool {
+				return slices.ContainsFunc(event.Tags, func(tag nostr.Tag) bool { return slices.Equal(tag, nostr.Tag{"p", carol}) })
+			})
+			if listed != test.accepted {
+				t.Errorf("expected listed %v, got %v", test.accepted, listed)
+			}
+
+			// Only the state of a change that went through is delivered to subscribers
+			expected := []int{}
+			if test.accepted {
+				expected = []int{KindGroupMetadata, KindGroupAdmins, KindGroupMembers, KindGroupRoles}
+			}
+
+			slices.Sort(broadcast)
+			if !slices.Equal(broadcast, expected) {
+				t.Errorf("expected %v to be broadcast, got %v", expected, broadcast)
+			}
+		})
+	}
+}
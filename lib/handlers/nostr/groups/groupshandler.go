@@ -0,0 +1,242 @@
+package groups
+
+import (
+	"fmt"
+	"log"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// BuildModerationHandler constructs and returns a handler function for the group moderation kinds (9000-9020).
+func BuildModerationHandler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	return buildGroupHandler(store, applyModeration)
+}
+
+// BuildJoinRequestHandler constructs and returns a handler function for kind 9021 (Group Join Request) events.
+func BuildJoinRequestHandler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	return buildGroupHandler(store, applyJoinRequest)
+}
+
+// BuildLeaveRequestHandler constructs and returns a handler function for kind 9022 (Group Leave Request) events.
+func BuildLeaveRequestHandler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	return buildGroupHandler(store, applyLeaveRequest)
+}
+
+// A change to a group is made to a copy which only replaces the live group once the event and the group
+// state events have been stored
+type groupChange struct {
+	group        *Group   // The changed copy of the group, nil when the group has been deleted
+	publish      bool     // The relay signed group state has to be published again
+	deleteEvents []string // Events a moderator removed from the group
+}
+
+// Every group kind is read and validated the same way, only the change made to the group differs
+func buildGroupHandler(store stores.Store, apply func(event *nostr.Event) (groupChange, error)) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+		// Read data from the stream.
+		data, err := read()
+		if err != nil {
+			write("NOTICE", "Error reading from stream.")
+			return
+		}
+
+		// Unmarshal the received data into a Nostr event
+		var env nostr.EventEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			write("NOTICE", "Error unmarshaling event.")
+			return
+		}
+
+		// Check relay settings for allowed events whilst also verifying signatures
+		success := lib_nostr.ValidateEvent(write, env, -1)
+		if !success {
+			return
+		}
+
+		if GetGroupID(&env.Event) == "" {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, "group events must have an 'h' tag")
+			return
+		}
+
+		if err := checkLatePublication(&env.Event); err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
+			return
+		}
+
+		groupID := GetGroupID(&env.Event)
+
+		changeMutex.Lock()
+		defer changeMutex.Unlock()
+
+		change, err := apply(&env.Event)
+		if err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
+			return
+		}
+
+		var published []*nostr.Event
+		if change.publish {
+			published, err = publishGroup(store, groupID, change.group)
+			if err != nil {
+				log.Printf("Error publishing group %s: %v", groupID, err)
+				restoreGroup(store, groupID)
+				lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to publish the group state")
+				return
+			}
+		}
+
+		// Store the new event
+		if err := store.StoreEvent(&env.Event); err != nil {
+			if change.publish {
+				restoreGroup(store, groupID)
+			}
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to store the event")
+			return
+		}
+
+		setGroup(groupID, change.group)
+		deleteModeratedEvents(store, groupID, change.deleteEvents)
+
+		// Subscribers see the new group state once the change is live
+		for _, event := range published {
+			lib_nostr.Broadcast(event)
+		}
+
+		// Successfully processed event
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
+	}
+
+	return handler
+}
+
+func applyModeration(event *nostr.Event) (groupChange, error) {
+	groupID := GetGroupID(event)
+	group := GetGroup(groupID)
+
+	if event.Kind == KindCreateGroup {
+		if group != nil {
+			return groupChange{}, fmt.Errorf("duplicate: group %s already exists", groupID)
+		}
+
+		if !groupIDPattern.MatchString(groupID) {
+			return groupChange{}, fmt.Errorf("invalid: group ids can only contain a-z, 0-9, - and _")
+		}
+
+		if !viper.GetBool("allow_group_creation") {
+			return groupChange{}, fmt.Errorf("restricted: this relay does not allow new groups")
+		}
+
+		group = newGroup(groupID)
+		group.Admins[event.PubKey] = []string{"admin"}
+		applyMetadata(group, event.Tags)
+
+		log.Printf("Created group %s for %s", groupID, event.PubKey)
+
+		return groupChange{group: group, publish: true}, nil
+	}
+
+	if group == nil {
+		return groupChange{}, fmt.Errorf("invalid: group %s does not exist", groupID)
+	}
+
+	if !group.IsAdmin(event.PubKey) {
+		return groupChange{}, fmt.Errorf("restricted: only group admins can moderate group %s", groupID)
+	}
+
+	switch event.Kind {
+	case KindPutUser:
+		for _, tag := range event.Tags {
+			if len(tag) < 2 || tag[0] != "p" {
+				continue
+			}
+
+			// Any role given to the user makes them an admin
+			if len(tag) > 2 {
+				group.Admins[tag[1]] = tag[2:]
+			}
+			group.Members[tag[1]] = true
+		}
+	case KindRemoveUser:
+		for _, tag := range event.Tags {
+			if len(tag) >= 2 && tag[0] == "p" {
+				delete(group.Admins, tag[1])
+				delete(group.Members, tag[1])
+			}
+		}
+	case KindEditMetadata:
+		applyMetadata(group, event.Tags)
+	case KindDeleteEvent:
+		ids := []string{}
+		for _, tag := range event.Tags {
+			if len(tag) >= 2 && tag[0] == "e" {
+				ids = append(ids, tag[1])
+			}
+		}
+		return groupChange{group: group, deleteEvents: ids}, nil
+	case KindDeleteGroup:
+		// Publishing a group that no longer exists removes its state events
+		return groupChange{publish: true}, nil
+	case KindCreateInvite:
+		code := event.Tags.GetFirst([]string{"code", ""})
+		if code == nil {
+			return groupChange{}, fmt.Errorf("invalid: invites must have a 'code' tag")
+		}
+		group.Invites[code.Value()] = true
+		return groupChange{group: group}, nil
+	default:
+		// Other moderation kinds are stored without changing the group
+		return groupChange{group: group}, nil
+	}
+
+	return groupChange{group: group, publish: true}, nil
+}
+
+// Users can join open groups directly, closed groups need a valid invite code
+func applyJoinRequest(event *nostr.Event) (groupChange, error) {
+	groupID := GetGroupID(event)
+
+	group := GetGroup(groupID)
+	if group == nil {
+		return groupChange{}, fmt.Errorf("invalid: group %s does not exist", groupID)
+	}
+
+	if group.IsMember(event.PubKey) {
+		return groupChange{}, fmt.Errorf("duplicate: you are already a member of group %s", groupID)
+	}
+
+	if group.Closed {
+		code := event.Tags.GetFirst([]string{"code", ""})
+		if code == nil || !group.Invites[code.Value()] {
+			return groupChange{}, fmt.Errorf("restricted: group %s is closed and needs an invite code", groupID)
+		}
+	}
+
+	group.Members[event.PubKey] = true
+
+	return groupChange{group: group, publish: true}, nil
+}
+
+func applyLeaveRequest(event *nostr.Event) (groupChange, error) {
+	groupID := GetGroupID(event)
+
+	group := GetGroup(groupID)
+	if group == nil {
+		return groupChange{}, fmt.Errorf("invalid: group %s does not exist", groupID)
+	}
+
+	if !group.IsMember(event.PubKey) {
+		return groupChange{}, fmt.Errorf("invalid: you are not a member of group %s", groupID)
+	}
+
+	delete(group.Admins, event.PubKey)
+	delete(group.Members, event.PubKey)
+
+	return groupChange{group: group, publish: true}, nil
+}
@@ -1,7 +1,31 @@
 package nostr
 
+import (
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
 var KindHandlers map[string]KindHandler
 
+// Checks that apply to events of every kind, such as group membership, are registered by the features that need them
+type EventCheck func(event *nostr.Event) error
+type ReadCheck func(event *nostr.Event, pubkey string) bool
+type QueryFilter func(events []*nostr.Event) []*nostr.Event
+
+// Transports register a broadcaster to deliver events to their subscribers once a handler has accepted them
+type Broadcaster func(event *nostr.Event)
+
+var eventChecks []EventCheck
+var readChecks []ReadCheck
+var queryFilters []QueryFilter
+var broadcasters []Broadcaster
+
+// The store is needed by the generic event validation for subscriber and moderation checks, it's set once on startup
+var validationStore stores.Store
+
 type KindWriter func(messageType string, params ...interface{})
 type KindReader func() ([]byte, error)
 
@@ -30,3 +54,71 @@ func GetHandler(kind string) func(read KindReader, write KindWriter) {
 func GetHandlers() map[string]KindHandler {
 	return KindHandlers
 }
+
+func SetValidationStore(store stores.Store) {
+	validationStore = store
+}
+
+// RegisterEventCheck adds a check that every event has to pass in ValidateEvent
+func RegisterEventCheck(check EventCheck) {
+	eventChecks = append(eventChecks, check)
+}
+
+// RegisterReadCheck adds a check that every event has to pass before it's sent to a connection
+func RegisterReadCheck(check ReadCheck) {
+	readChecks = append(readChecks, check)
+}
+
+// RegisterQueryFilter adds a filter that's applied to the results of every query, it sees all the results
+// at once so features can look up what they need once per query rather than once per event
+func RegisterQueryFilter(filter QueryFilter) {
+	queryFilters = append(queryFilters, filter)
+}
+
+// FilterQueryResults applies every registered query filter to the results of a query
+func FilterQueryResults(events []*nostr.Event) []*nostr.Event {
+	for _, filter := range queryFilters {
+		events = filter(events)
+	}
+
+	return events
+}
+
+// RegisterBroadcaster adds a transport that events passed to Broadcast are delivered to
+func RegisterBroadcaster(broadcaster Broadcaster) {
+	broadcasters = append(broadcasters, broadcaster)
+}
+
+// Broadcast delivers an event to the subscribers of every registered transport
+func Broadcast(event *nostr.Event) {
+	for _, broadcaster := range broadcasters {
+		broadcaster(event)
+	}
+}
+
+// ResetRegistrations removes every registered event check, read check, query filter and broadcaster,
+// tests that register their own call it on cleanup so nothing they register applies to later tests
+func ResetRegistrations() {
+	eventChecks = nil
+	readChecks = nil
+	queryFilters = nil
+	broadcasters = nil
+}
+
+// BroadcastOnAccept wraps a writer so the event is broadcast once the handler accepts it,
+// events that are refused or that the relay already had are never delivered to subscribers
+func BroadcastOnAccept(write KindWriter, event *nostr.Event) KindWriter {
+	return func(messageType string, params ...interface{}) {
+		write(messageType, params...)
+
+		if messageType != "OK" || len(params) < 3 || params[0] != event.ID || params[1] != true {
+			return
+		}
+
+		if reason, ok := params[2].(string); ok && strings.HasPrefix(reason, PrefixDuplicate+":") {
+			return
+		}
+
+		Broadcast(event)
+	}
+}
@@ -0,0 +1,78 @@
+package nostr
+
+import (
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+)
+
+func TestBroadcastOnAccept(t *testing.T) {
+	var broadcast []*nostr.Event
+	t.Cleanup(ResetRegistrations)
+	RegisterBroadcaster(func(event *nostr.Event) {
+		broadcast = append(broadcast, event)
+	})
+	defer func() { broadcasters = nil }()
+
+	event := &nostr.Event{ID: "event"}
+
+	tests := []struct {
+		name      string
+		respond   func(write KindWriter)
+		broadcast bool
+	}{
+		{"accepted", func(write KindWriter) { Accept(write, event.ID, "stored") }, true},
+		{"rejected", func(write KindWriter) { Reject(write, event.ID, PrefixInvalid, "bad") }, false},
+		{"duplicate", func(write KindWriter) { Duplicate(write, event.ID) }, false},
+		{"another event accepted", func(write KindWriter) { Accept(write, "other", "stored") }, false},
+		{"notice", func(write KindWriter) { write("NOTICE", "Error reading from stream.") }, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			broadcast = nil
+			written := 0
+
+			test.respond(BroadcastOnAccept(func(messageType string, params ...interface{}) {
+				written++
+			}, event))
+
+			if written != 1 {
+				t.Errorf("expected the response to be written once, got %d", written)
+			}
+
+			if (len(broadcast) > 0) != test.broadcast {
+				t.Errorf("expected broadcast %v, got %v", test.broadcast, len(broadcast) > 0)
+			}
+		})
+	}
+}
+
+func TestKindAllowed(t *testing.T) {
+	smart := &types.RelaySettings{Mode: "smart", Kinds: []string{"kind1"}, DynamicKinds: []string{"30023"}}
+	unlimited := &types.RelaySettings{Mode: "unlimited", Kinds: []string{"kind4"}}
+
+	tests := []struct {
+		name     string
+		settings *types.RelaySettings
+		kind     int
+		allowed  bool
+	}{
+		{"smart listed kind", smart, 1, true},
+		{"smart dynamic kind", smart, 30023, true},
+		{"smart unlisted kind", smart, 7, false},
+		{"unlimited kind", unlimited, 7, true},
+		{"unlimited disallowed kind", unlimited, 4, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			allowed := IsTheKindAllowed(test.kind, test.settings) && !IsKindBlocked(test.kind, test.settings)
+			if allowed != test.allowed {
+				t.Errorf("expected allowed %v, got %v", test.allowed, allowed)
+			}
+		})
+	}
+}
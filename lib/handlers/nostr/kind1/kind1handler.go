@@ -38,7 +38,7 @@ func BuildKind1Handler(store stores.Store) func(read lib_nostr.KindReader, write
 
 		// Store the new event
 		if err := store.StoreEvent(&env.Event); err != nil {
-			write("NOTICE", "Failed to store the event")
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to store the event")
 			return
 		}
 
@@ -87,7 +87,7 @@ func BuildKind1Handler(store stores.Store) func(read lib_nostr.KindReader, write
 		}
 
 		// Successfully processed event
-		write("OK", env.Event.ID, true, "Event stored successfully")
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
 	}
 
 	return handler
@@ -10,6 +10,7 @@ import (
 	"github.com/nbd-wtf/go-nostr"
 
 	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/lists"
 )
 
 // BuildKind10000Handler constructs and returns a handler function for kind 10000 (Mute List) events.
@@ -37,6 +38,12 @@ func BuildKind10000Handler(store stores.Store) func(read lib_nostr.KindReader, w
 			return
 		}
 
+		// Private items are encrypted in the content so a list may have no public items at all
+		if err := lists.ValidatePrivateItems(env.Event.Content); err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
+			return
+		}
+
 		// Retrieve existing kind 10000 events for the pubkey to determine if this is an update
 		filter := nostr.Filter{
 			Authors: []string{env.Event.PubKey},
@@ -45,33 +52,26 @@ func BuildKind10000Handler(store stores.Store) func(read lib_nostr.KindReader, w
 		existingEvents, err := store.QueryEvents(filter)
 		if err != nil {
 			log.Printf("Error querying existing kind 10000 events: %v", err)
-			write("NOTICE", fmt.Sprintf("Error querying existing events: %v", err))
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to query existing events")
 			return
 		}
 
-		// Perform tag validation only if there are no existing events (i.e., it's a new event)
-		if len(existingEvents) == 0 {
+		// Perform tag validation only if it's a new list without private items
+		if len(existingEvents) == 0 && env.Event.Content == "" {
 			if err := validateMuteListTags(env.Event.Tags); err != nil {
-				write("NOTICE", err.Error())
+				lib_nostr.RejectError(write, env.Event.ID, err)
 				return
 			}
 		}
 
-		// Delete existing kind 10000 events if any
-		for _, oldEvent := range existingEvents {
-			if err := store.DeleteEvent(oldEvent.ID); err != nil {
-				log.Printf("Error deleting old kind 10000 event %s: %v", oldEvent.ID, err)
-			}
-		}
-
-		// Store the new event
-		if err := store.StoreEvent(&env.Event); err != nil {
-			write("NOTICE", "Failed to store the event")
+		// Store the new event replacing the previous mute list
+		if err := lib_nostr.StoreReplaceableEvent(store, &env.Event); err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
 			return
 		}
 
 		// Successfully processed event
-		write("OK", env.Event.ID, true, "Event stored successfully")
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
 	}
 
 	return handler
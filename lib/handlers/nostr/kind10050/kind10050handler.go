@@ -0,0 +1,78 @@
+package kind10050
+
+import (
+	"fmt"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// BuildKind10050Handler constructs and returns a handler function for kind 10050 (DM Relay List) events.
+func BuildKind10050Handler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+		// Read data from the stream.
+		data, err := read()
+		if err != nil {
+			write("NOTICE", "Error reading from stream.")
+			return
+		}
+
+		// Unmarshal the received data into a Nostr event
+		var env nostr.EventEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			write("NOTICE", "Error unmarshaling event.")
+			return
+		}
+
+		// Check relay settings for allowed events whilst also verifying signatures and kind number
+		success := lib_nostr.ValidateEvent(write, env, 10050)
+		if !success {
+			return
+		}
+
+		// Validate the event's tags
+		if err := validateDMRelayTags(env.Event.Tags); err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
+			return
+		}
+
+		// Store the new event replacing the previous DM relay list
+		if err := lib_nostr.StoreReplaceableEvent(store, &env.Event); err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
+			return
+		}
+
+		// Successfully processed event
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
+	}
+
+	return handler
+}
+
+// validateDMRelayTags checks that the list only contains 'relay' tags with websocket urls.
+func validateDMRelayTags(tags nostr.Tags) error {
+	relays := 0
+	for _, tag := range tags {
+		if len(tag) < 2 || tag[0] != "relay" {
+			continue
+		}
+
+		if !strings.HasPrefix(tag[1], "wss://") && !strings.HasPrefix(tag[1], "ws://") {
+			return fmt.Errorf("invalid: relay url must be a websocket url: %s", tag[1])
+		}
+
+		relays++
+	}
+
+	if relays == 0 {
+		return fmt.Errorf("invalid: DM relay list must contain at least one 'relay' tag")
+	}
+
+	return nil
+}
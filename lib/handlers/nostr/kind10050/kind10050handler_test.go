@@ -0,0 +1,31 @@
+package kind10050
+
+import (
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestValidateDMRelayTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		tags  nostr.Tags
+		valid bool
+	}{
+		{"secure relay", nostr.Tags{{"relay", "wss://relay.example.com"}}, true},
+		{"plain relay", nostr.Tags{{"relay", "ws://localhost:9000"}}, true},
+		{"other tags are ignored", nostr.Tags{{"relay", "wss://relay.example.com"}, {"alt", "dm relays"}}, true},
+		{"http url", nostr.Tags{{"relay", "https://relay.example.com"}}, false},
+		{"no relays", nostr.Tags{{"alt", "dm relays"}}, false},
+		{"relay tag without a url", nostr.Tags{{"relay"}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateDMRelayTags(test.tags)
+			if (err == nil) != test.valid {
+				t.Errorf("expected valid %v, got error %v", test.valid, err)
+			}
+		})
+	}
+}
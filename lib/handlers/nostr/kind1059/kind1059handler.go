@@ -0,0 +1,156 @@
+package kind1059
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// BuildKind1059Handler constructs and returns a handler function for kind 1059 (Gift Wrap) events.
+// Gift wraps are signed by a random key and only ever served to the authenticated recipient
+func BuildKind1059Handler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+		// Read data from the stream.
+		data, err := read()
+		if err != nil {
+			write("NOTICE", "Error reading from stream.")
+			return
+		}
+
+		// Unmarshal the received data into a Nostr event
+		var env nostr.EventEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			write("NOTICE", "Error unmarshaling event.")
+			return
+		}
+
+		// Check relay settings for allowed events whilst also verifying signatures and kind number
+		success := lib_nostr.ValidateEvent(write, env, 1059)
+		if !success {
+			return
+		}
+
+		// Validate the gift wrap's tags and content
+		if errMsg := validateGiftWrap(&env.Event); errMsg != "" {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, errMsg)
+			return
+		}
+
+		// Store the new event
+		if err := store.StoreEvent(&env.Event); err != nil {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to store the event")
+			return
+		}
+
+		// Successfully processed event
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
+	}
+
+	return handler
+}
+
+// validateGiftWrap checks the gift wrap has a single recipient, encrypted content and has not already expired.
+func validateGiftWrap(event *nostr.Event) string {
+	recipients := 0
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "p" {
+			if !nostr.IsValid32ByteHex(tag[1]) {
+				return "gift wrap recipient is not a valid pubkey"
+			}
+			recipients++
+		}
+	}
+
+	if recipients != 1 {
+		return "gift wrap must have exactly one 'p' tag for the recipient"
+	}
+
+	if event.Content == "" {
+		return "gift wrap content must contain the encrypted seal"
+	}
+
+	if expiration, ok := getExpiration(event); ok && expiration <= time.Now().Unix() {
+		return "gift wrap has already expired"
+	}
+
+	return ""
+}
+
+// ExpireGiftWraps periodically deletes gift wraps that have passed their expiration tag or are older than
+// gift_wrap_retention_days, created_at is randomized by clients so the retention period should be a few days longer than wanted
+func ExpireGiftWraps(store stores.Store) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := DeleteExpiredGiftWraps(store)
+		if err != nil {
+			log.Printf("Error expiring gift wraps: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Deleted %d expired gift wraps", deleted)
+		}
+
+		<-ticker.C
+	}
+}
+
+// DeleteExpiredGiftWraps removes every stored gift wrap that has expired and returns how many were deleted
+func DeleteExpiredGiftWraps(store stores.Store) (int, error) {
+	events, err := store.QueryEvents(nostr.Filter{Kinds: []int{1059}})
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	retentionDays := viper.GetInt("gift_wrap_retention_days")
+
+	deleted := 0
+	for _, event := range events {
+		expired := false
+
+		if expiration, ok := getExpiration(event); ok && expiration <= now.Unix() {
+			expired = true
+		}
+
+		if retentionDays > 0 && event.CreatedAt.Time().Before(now.AddDate(0, 0, -retentionDays)) {
+			expired = true
+		}
+
+		if !expired {
+			continue
+		}
+
+		if err := store.DeleteEvent(event.ID); err != nil {
+			log.Printf("Error deleting expired gift wrap %s: %v", event.ID, err)
+			continue
+		}
+
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// getExpiration returns the NIP-40 expiration timestamp of the event if it has one
+func getExpiration(event *nostr.Event) (int64, bool) {
+	tag := event.Tags.GetFirst([]string{"expiration"})
+	if tag == nil || len(*tag) < 2 {
+		return 0, false
+	}
+
+	expiration, err := strconv.ParseInt((*tag)[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return expiration, true
+}
@@ -0,0 +1,41 @@
+package kind1059
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestValidateGiftWrap(t *testing.T) {
+	recipient, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	other, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+
+	past := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	future := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name    string
+		tags    nostr.Tags
+		content string
+		valid   bool
+	}{
+		{"single recipient", nostr.Tags{{"p", recipient}}, "sealed", true},
+		{"not yet expired", nostr.Tags{{"p", recipient}, {"expiration", future}}, "sealed", true},
+		{"no recipient", nostr.Tags{}, "sealed", false},
+		{"two recipients", nostr.Tags{{"p", recipient}, {"p", other}}, "sealed", false},
+		{"invalid recipient", nostr.Tags{{"p", "npub"}}, "sealed", false},
+		{"empty content", nostr.Tags{{"p", recipient}}, "", false},
+		{"already expired", nostr.Tags{{"p", recipient}, {"expiration", past}}, "sealed", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errMsg := validateGiftWrap(&nostr.Event{Kind: 1059, Tags: test.tags, Content: test.content})
+			if (errMsg == "") != test.valid {
+				t.Errorf("expected valid %v, got %q", test.valid, errMsg)
+			}
+		})
+	}
+}
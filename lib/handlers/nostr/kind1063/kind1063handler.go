@@ -0,0 +1,191 @@
+package kind1063
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/uploads"
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+)
+
+// BuildKind1063Handler constructs and returns a handler function for kind 1063 (File Metadata) events.
+func BuildKind1063Handler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+		// Read data from the stream.
+		data, err := read()
+		if err != nil {
+			write("NOTICE", "Error reading from stream.")
+			return
+		}
+
+		// Unmarshal the received data into a Nostr event
+		var env nostr.EventEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			write("NOTICE", "Error unmarshaling event.")
+			return
+		}
+
+		// Check relay settings for allowed events whilst also verifying signatures and kind number
+		success := lib_nostr.ValidateEvent(write, env, 1063)
+		if !success {
+			return
+		}
+
+		// Make sure the metadata actually describes something this relay is storing
+		if err := ValidateFileMetadata(store, &env.Event); err != nil {
+			log.Printf("Rejected file metadata event %s: %v", env.Event.ID, err)
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, err.Error())
+			return
+		}
+
+		// Store the new event
+		if err := store.StoreEvent(&env.Event); err != nil {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to store the event")
+			return
+		}
+
+		// Successfully processed event
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
+	}
+
+	return handler
+}
+
+// ValidateFileMetadata checks the x, ox, m, size and url tags of a file metadata event against
+// the blossom blob or scionic merkle tree that the relay has stored for it
+func ValidateFileMetadata(store stores.Store, event *nostr.Event) error {
+	metadata := ParseFileMetadata(event)
+
+	if !isSha256Hex(metadata.X) {
+		return fmt.Errorf("missing or malformed 'x' tag")
+	}
+
+	if metadata.OX != "" && !isSha256Hex(metadata.OX) {
+		return fmt.Errorf("malformed 'ox' tag")
+	}
+
+	if !isMimeType(metadata.M) {
+		return fmt.Errorf("missing or malformed 'm' tag")
+	}
+
+	if metadata.URL == "" {
+		return fmt.Errorf("missing 'url' tag")
+	}
+
+	var size int64 = -1
+	if metadata.Size != "" {
+		parsed, err := strconv.ParseInt(metadata.Size, 10, 64)
+		if err != nil || parsed < 0 {
+			return fmt.Errorf("malformed 'size' tag")
+		}
+
+		size = parsed
+	}
+
+	// Scionic merkle trees are referenced by their root hash
+	if metadata.ScionicRoot != "" {
+		if err := checkOwner(store.GetDagsByPubkey, metadata.Uploader, metadata.ScionicRoot); err != nil {
+			return err
+		}
+
+		return validateDagMetadata(store, metadata)
+	}
+
+	if err := checkOwner(store.GetBlobsByPubkey, metadata.Uploader, metadata.X); err != nil {
+		return err
+	}
+
+	data, err := uploads.GetBlob(store, metadata.X)
+	if err != nil || data == nil {
+		return fmt.Errorf("no blob stored for hash %s", metadata.X)
+	}
+
+	if size > -1 && size != int64(len(data)) {
+		return fmt.Errorf("size %d does not match stored blob size %d", size, len(data))
+	}
+
+	if !mimeMatchesContent(metadata.M, data) {
+		return fmt.Errorf("mime type %s does not match the stored blob", metadata.M)
+	}
+
+	if !strings.Contains(metadata.URL, metadata.X) {
+		return fmt.Errorf("url does not reference the blob hash")
+	}
+
+	return nil
+}
+
+// The file hash of a scionic merkle tree is not stored by the relay so we can only confirm the tree exists
+// and that the metadata is consistent with the root leaf
+func validateDagMetadata(store stores.Store, metadata *FileMetadata) error {
+	rootData, err := store.RetrieveLeaf(metadata.ScionicRoot, metadata.ScionicRoot, false)
+	if err != nil {
+		return fmt.Errorf("no scionic merkle tree stored for root %s", metadata.ScionicRoot)
+	}
+
+	expected := mime.TypeByExtension(filepath.Ext(rootData.Leaf.ItemName))
+	if expected != "" && !sameMediaType(expected, metadata.M) {
+		return fmt.Errorf("mime type %s does not match %s", metadata.M, rootData.Leaf.ItemName)
+	}
+
+	if !strings.Contains(metadata.URL, metadata.ScionicRoot) {
+		return fmt.Errorf("url does not reference the scionic root")
+	}
+
+	return nil
+}
+
+// Users can only describe files they uploaded themselves
+func checkOwner(getOwned func(publicKey string) ([]string, error), uploader string, hash string) error {
+	owned, err := getOwned(uploader)
+	if err != nil || !slices.Contains(owned, hash) {
+		return fmt.Errorf("%s was not uploaded by %s", hash, uploader)
+	}
+
+	return nil
+}
+
+// Content sniffing only recognises a limited set of formats so we only reject when
+// the sniffed type is specific and disagrees with the declared type
+func mimeMatchesContent(declared string, data []byte) bool {
+	detected := http.DetectContentType(data)
+
+	if strings.HasPrefix(detected, "application/octet-stream") || strings.HasPrefix(detected, "text/") {
+		return true
+	}
+
+	return sameMediaType(detected, declared)
+}
+
+// Compare only the top level media type (image, video, audio etc.) as the subtypes often differ between detectors
+func sameMediaType(a string, b string) bool {
+	return strings.SplitN(a, "/", 2)[0] == strings.SplitN(b, "/", 2)[0]
+}
+
+func isMimeType(value string) bool {
+	_, _, err := mime.ParseMediaType(value)
+	return err == nil && strings.Contains(value, "/")
+}
+
+func isSha256Hex(value string) bool {
+	if len(value) != 64 {
+		return false
+	}
+
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
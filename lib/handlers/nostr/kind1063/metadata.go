@@ -0,0 +1,192 @@
+package kind1063
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/uploads"
+	merkle_dag "github.com/HORNET-Storage/scionic-merkletree/dag"
+)
+
+// FileMetadata holds the NIP-94 tags that the relay validates and indexes
+type FileMetadata struct {
+	URL         string
+	M           string
+	X           string
+	OX          string
+	Size        string
+	ScionicRoot string
+	Uploader    string
+}
+
+func ParseFileMetadata(event *nostr.Event) *FileMetadata {
+	metadata := &FileMetadata{}
+
+	for _, tag := range event.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+
+		switch tag[0] {
+		case "url":
+			metadata.URL = tag[1]
+		case "m":
+			metadata.M = tag[1]
+		case "x":
+			metadata.X = tag[1]
+		case "ox":
+			metadata.OX = tag[1]
+		case "size":
+			metadata.Size = tag[1]
+		case "scionic_root":
+			metadata.ScionicRoot = tag[1]
+		case "p":
+			metadata.Uploader = tag[1]
+		}
+	}
+
+	// Only the relay publishes metadata on behalf of someone else, events published by users
+	// describe their own uploads whatever their p tag says
+	if metadata.Uploader == "" || event.PubKey != lib_nostr.RelayPubkey() {
+		metadata.Uploader = event.PubKey
+	}
+
+	return metadata
+}
+
+// Create, sign and store a file metadata event for a blossom blob on behalf of the uploader
+func CreateBlobMetadataEvent(store stores.Store, data []byte, mimeType string, url string, uploader string) (*nostr.Event, error) {
+	hash := sha256.Sum256(data)
+
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	tags := nostr.Tags{
+		{"url", url},
+		{"m", mimeType},
+		{"x", hex.EncodeToString(hash[:])},
+		{"ox", hex.EncodeToString(hash[:])},
+		{"size", strconv.Itoa(len(data))},
+		{"p", uploader},
+	}
+
+	return createMetadataEvent(store, tags)
+}
+
+// Create, sign and store a file metadata event for an uploaded scionic merkle tree
+// Only trees that represent a single file can be described by NIP-94
+func CreateDagMetadataEvent(store stores.Store, dag *merkle_dag.Dag, uploader string) (*nostr.Event, error) {
+	rootLeaf, ok := dag.Leafs[dag.Root]
+	if !ok {
+		return nil, fmt.Errorf("dag is missing its root leaf")
+	}
+
+	if rootLeaf.Type != merkle_dag.FileLeafType {
+		return nil, fmt.Errorf("only single file dags can be described by file metadata")
+	}
+
+	content, err := uploads.GetDagFileContent(dag)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(content)
+
+	mimeType := mime.TypeByExtension(filepath.Ext(rootLeaf.ItemName))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(content)
+	}
+
+	tags := nostr.Tags{
+		{"url", fmt.Sprintf("scionic://%s", dag.Root)},
+		{"m", mimeType},
+		{"x", hex.EncodeToString(hash[:])},
+		{"ox", hex.EncodeToString(hash[:])},
+		{"size", strconv.Itoa(len(content))},
+		{"scionic_root", dag.Root},
+		{"p", uploader},
+	}
+
+	return createMetadataEvent(store, tags)
+}
+
+func createMetadataEvent(store stores.Store, tags nostr.Tags) (*nostr.Event, error) {
+	event := &nostr.Event{
+		CreatedAt: nostr.Timestamp(time.Now().Unix()),
+		Kind:      1063,
+		Tags:      tags,
+		Content:   "",
+	}
+
+	if err := lib_nostr.SignEventWithRelayKey(event); err != nil {
+		return nil, fmt.Errorf("failed to sign file metadata event: %v", err)
+	}
+
+	if err := store.StoreEvent(event); err != nil {
+		return nil, fmt.Errorf("failed to store file metadata event: %v", err)
+	}
+
+	log.Printf("Created file metadata event %s", event.ID)
+
+	return event, nil
+}
+
+// Query file metadata events by file hash, mime type and uploader, any empty value is ignored
+func QueryFileMetadata(store stores.Store, hash string, mimeType string, uploader string) ([]*nostr.Event, error) {
+	filter := nostr.Filter{
+		Kinds: []int{1063},
+		Tags:  nostr.TagMap{},
+	}
+
+	if hash != "" {
+		filter.Tags["x"] = []string{hash}
+	}
+
+	if mimeType != "" {
+		filter.Tags["m"] = []string{mimeType}
+	}
+
+	events, err := store.QueryEvents(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	results := []*nostr.Event{}
+	for _, event := range events {
+		if event.Kind != 1063 || seen[event.ID] {
+			continue
+		}
+
+		metadata := ParseFileMetadata(event)
+
+		if hash != "" && metadata.X != hash && metadata.OX != hash {
+			continue
+		}
+
+		if mimeType != "" && metadata.M != mimeType {
+			continue
+		}
+
+		if uploader != "" && metadata.Uploader != uploader {
+			continue
+		}
+
+		seen[event.ID] = true
+		results = append(results, event)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,71 @@
+package kind1063
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+)
+
+func TestParseFileMetadataUploader(t *testing.T) {
+	relayKey := nostr.GeneratePrivateKey()
+	relayPubkey, _ := nostr.GetPublicKey(relayKey)
+	viper.Set("private_key", relayKey)
+	defer viper.Set("private_key", "")
+
+	userPubkey, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	otherPubkey, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+
+	tests := []struct {
+		name     string
+		pubkey   string
+		tags     nostr.Tags
+		uploader string
+	}{
+		{"relay signed with p tag", relayPubkey, nostr.Tags{{"p", userPubkey}}, userPubkey},
+		{"relay signed without p tag", relayPubkey, nostr.Tags{}, relayPubkey},
+		{"user signed without p tag", userPubkey, nostr.Tags{}, userPubkey},
+		{"user signed naming someone else", userPubkey, nostr.Tags{{"p", otherPubkey}}, userPubkey},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metadata := ParseFileMetadata(&nostr.Event{PubKey: test.pubkey, Kind: 1063, Tags: test.tags})
+			if metadata.Uploader != test.uploader {
+				t.Errorf("expected uploader %s, got %s", test.uploader, metadata.Uploader)
+			}
+		})
+	}
+}
+
+func TestCheckOwner(t *testing.T) {
+	owned := map[string][]string{"alice": {"aaaa", "bbbb"}}
+	getOwned := func(publicKey string) ([]string, error) {
+		if hashes, ok := owned[publicKey]; ok {
+			return hashes, nil
+		}
+
+		return nil, fmt.Errorf("nothing uploaded by %s", publicKey)
+	}
+
+	tests := []struct {
+		name     string
+		uploader string
+		hash     string
+		allowed  bool
+	}{
+		{"own upload", "alice", "bbbb", true},
+		{"someone else's upload", "alice", "cccc", false},
+		{"no uploads", "bob", "aaaa", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkOwner(getOwned, test.uploader, test.hash)
+			if (err == nil) != test.allowed {
+				t.Errorf("expected allowed %v, got error %v", test.allowed, err)
+			}
+		})
+	}
+}
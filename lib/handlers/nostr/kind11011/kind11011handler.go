@@ -54,12 +54,12 @@ func BuildKind11011Handler(store stores.Store) func(read lib_nostr.KindReader, w
 		// Store the event
 		if err := store.StoreEvent(&env.Event); err != nil {
 			log.Printf("failed to store event: %v", err)
-			write("NOTICE", "Failed to store the event")
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to store the event")
 			return
 		}
 
 		// Successfully processed event
-		write("OK", env.Event.ID, true, "Event stored successfully")
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
 	}
 
 	return handler
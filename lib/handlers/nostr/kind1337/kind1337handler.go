@@ -1,47 +0,0 @@
-package kind1337handler
-
-import (
-	jsoniter "github.com/json-iterator/go"
-
-	"github.com/HORNET-Storage/hornet-storage/lib/stores"
-	"github.com/nbd-wtf/go-nostr"
-
-	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
-)
-
-func BuildKind1337Handler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
-	handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
-		var json = jsoniter.ConfigCompatibleWithStandardLibrary
-
-		// Read data from the stream
-		data, err := read()
-		if err != nil {
-			write("NOTICE", "Error reading data from stream")
-			return
-		}
-
-		// Unmarshal the nostr envelope
-		var env nostr.EventEnvelope
-		if err := json.Unmarshal(data, &env); err != nil {
-			write("NOTICE", "Failed to deserialize the event envelope")
-			return
-		}
-
-		// Check relay settings for allowed events whilst also verifying signatures and kind number
-		success := lib_nostr.ValidateEvent(write, env, 1337)
-		if !success {
-			return
-		}
-
-		// Store the new event
-		if err := store.StoreEvent(&env.Event); err != nil {
-			write("NOTICE", "Failed to store the event")
-			return
-		}
-
-		// Successfully processed event
-		write("OK", env.Event.ID, true, "Event stored successfully")
-	}
-
-	return handler
-}
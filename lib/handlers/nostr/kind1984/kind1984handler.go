@@ -1,8 +1,11 @@
 package kind1984
 
 import (
+	"log"
+
 	jsoniter "github.com/json-iterator/go"
 
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
 	"github.com/nbd-wtf/go-nostr"
 
@@ -36,18 +39,23 @@ func BuildKind1984Handler(store stores.Store) func(read lib_nostr.KindReader, wr
 
 		// Validate the report event's tags.
 		if errMsg := validateReportEventTags(env.Event.Tags); errMsg != "" {
-			write("OK", env.Event.ID, false, errMsg)
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, errMsg)
 			return
 		}
 
 		// Store the new event
 		if err := store.StoreEvent(&env.Event); err != nil {
-			write("NOTICE", "Failed to store the event")
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to store the event")
 			return
 		}
 
+		// Count the report towards the moderation queue for the reported event or pubkey
+		if err := moderation.RecordReport(store, &env.Event); err != nil {
+			log.Printf("Error recording report %s: %v", env.Event.ID, err)
+		}
+
 		// Successfully processed event
-		write("OK", env.Event.ID, true, "Event stored successfully")
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
 	}
 
 	return handler
@@ -62,7 +70,7 @@ func validateReportEventTags(tags nostr.Tags) string {
 		}
 	}
 	if !hasValidReportTag {
-		return "Report event missing valid 'p' or 'e' report tag."
+		return "report event missing valid 'p' or 'e' report tag"
 	}
 	return ""
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/nbd-wtf/go-nostr"
 
 	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/badges"
 )
 
 // BuildKind30008Handler constructs and returns a handler function for kind 30008 (Profile Badges) events.
@@ -40,18 +41,24 @@ func BuildKind30008Handler(store stores.Store) func(read lib_nostr.KindReader, w
 		isValid, errMsg := validateProfileBadgesEvent(env.Event)
 		if !isValid {
 			log.Println(errMsg)
-			write("OK", env.Event.ID, false, errMsg)
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, errMsg)
 			return
 		}
 
-		// Store the new event
-		if err := store.StoreEvent(&env.Event); err != nil {
-			write("NOTICE", "Failed to store the event")
+		// Every displayed badge must have been awarded to the profile owner
+		if err := badges.ValidateProfileBadges(store, &env.Event); err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
+			return
+		}
+
+		// Store the new event replacing the previous version of the profile badges
+		if err := lib_nostr.StoreReplaceableEvent(store, &env.Event); err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
 			return
 		}
 
 		// Successfully processed event
-		write("OK", env.Event.ID, true, "Event stored successfully")
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
 	}
 
 	return handler
@@ -74,10 +81,10 @@ func validateProfileBadgesEvent(event nostr.Event) (bool, string) {
 	}
 
 	if !hasDTag {
-		return false, "Profile Badges event missing 'd' tag with value 'profile_badges'"
+		return false, "profile badges event missing 'd' tag with value 'profile_badges'"
 	}
 	if !aePairsValid {
-		return false, "Profile Badges event contains invalid 'a' and 'e' tag pairs"
+		return false, "profile badges event contains invalid 'a' and 'e' tag pairs"
 	}
 
 	log.Println("Profile Badges event is valid.")
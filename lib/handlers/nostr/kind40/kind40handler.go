@@ -0,0 +1,121 @@
+package kind40
+
+import (
+	"fmt"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// ChannelMetadata is the content of kind 40 and kind 41 events
+type ChannelMetadata struct {
+	Name    string   `json:"name"`
+	About   string   `json:"about,omitempty"`
+	Picture string   `json:"picture,omitempty"`
+	Relays  []string `json:"relays,omitempty"`
+}
+
+// BuildKind40Handler constructs and returns a handler function for kind 40 (Channel Creation) events.
+func BuildKind40Handler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+		// Read data from the stream.
+		data, err := read()
+		if err != nil {
+			write("NOTICE", "Error reading from stream.")
+			return
+		}
+
+		// Unmarshal the received data into a Nostr event
+		var env nostr.EventEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			write("NOTICE", "Error unmarshaling event.")
+			return
+		}
+
+		// Check relay settings for allowed events whilst also verifying signatures and kind number
+		success := lib_nostr.ValidateEvent(write, env, 40)
+		if !success {
+			return
+		}
+
+		// Validate the channel metadata in the content
+		if err := ValidateChannelMetadata(env.Event.Content); err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
+			return
+		}
+
+		// Store the new event
+		if err := store.StoreEvent(&env.Event); err != nil {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to store the event")
+			return
+		}
+
+		// Successfully processed event
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
+	}
+
+	return handler
+}
+
+// ValidateChannelMetadata checks the content is channel metadata json with a name
+func ValidateChannelMetadata(content string) error {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+	var metadata ChannelMetadata
+	if err := json.Unmarshal([]byte(content), &metadata); err != nil {
+		return fmt.Errorf("invalid: channel metadata must be json")
+	}
+
+	if metadata.Name == "" {
+		return fmt.Errorf("invalid: channel metadata must have a name")
+	}
+
+	return nil
+}
+
+// GetChannel retrieves the kind 40 event that created a channel
+func GetChannel(store stores.Store, channelID string) (*nostr.Event, error) {
+	filter := nostr.Filter{
+		IDs:   []string{channelID},
+		Kinds: []int{40},
+	}
+
+	events, err := store.QueryEvents(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		if filter.Matches(event) {
+			return event, nil
+		}
+	}
+
+	return nil, fmt.Errorf("channel not found: %s", channelID)
+}
+
+// GetChannelID returns the channel an event refers to, the e tag marked as root is preferred
+// and the first e tag is used for clients that don't add markers
+func GetChannelID(event *nostr.Event) string {
+	channelID := ""
+	for _, tag := range event.Tags {
+		if len(tag) < 2 || tag[0] != "e" {
+			continue
+		}
+
+		if len(tag) >= 4 && tag[3] == "root" {
+			return tag[1]
+		}
+
+		if channelID == "" {
+			channelID = tag[1]
+		}
+	}
+
+	return channelID
+}
@@ -0,0 +1,74 @@
+package kind41
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind40"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// BuildKind41Handler constructs and returns a handler function for kind 41 (Channel Metadata) events.
+// Only the channel creator can update the metadata and only their latest update is kept
+func BuildKind41Handler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+		// Read data from the stream.
+		data, err := read()
+		if err != nil {
+			write("NOTICE", "Error reading from stream.")
+			return
+		}
+
+		// Unmarshal the received data into a Nostr event
+		var env nostr.EventEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			write("NOTICE", "Error unmarshaling event.")
+			return
+		}
+
+		// Check relay settings for allowed events whilst also verifying signatures and kind number
+		success := lib_nostr.ValidateEvent(write, env, 41)
+		if !success {
+			return
+		}
+
+		channelID := kind40.GetChannelID(&env.Event)
+		if channelID == "" {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, "channel metadata must reference the channel with an 'e' tag")
+			return
+		}
+
+		channel, err := kind40.GetChannel(store, channelID)
+		if err != nil {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, err.Error())
+			return
+		}
+
+		if channel.PubKey != env.Event.PubKey {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixRestricted, "only the channel creator can update its metadata")
+			return
+		}
+
+		if err := kind40.ValidateChannelMetadata(env.Event.Content); err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
+			return
+		}
+
+		// Only the latest metadata of the channel is kept, updates of the creator's other channels are left alone
+		err = lib_nostr.StoreReplacingEvent(store, &env.Event, func(oldEvent *nostr.Event) bool {
+			return kind40.GetChannelID(oldEvent) == channelID
+		})
+		if err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
+			return
+		}
+
+		// Successfully processed event
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
+	}
+
+	return handler
+}
@@ -0,0 +1,60 @@
+package kind42
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind40"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// BuildKind42Handler constructs and returns a handler function for kind 42 (Channel Message) events.
+func BuildKind42Handler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+		// Read data from the stream.
+		data, err := read()
+		if err != nil {
+			write("NOTICE", "Error reading from stream.")
+			return
+		}
+
+		// Unmarshal the received data into a Nostr event
+		var env nostr.EventEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			write("NOTICE", "Error unmarshaling event.")
+			return
+		}
+
+		// Check relay settings for allowed events whilst also verifying signatures and kind number
+		success := lib_nostr.ValidateEvent(write, env, 42)
+		if !success {
+			return
+		}
+
+		// Messages must belong to a channel this relay knows about
+		channelID := kind40.GetChannelID(&env.Event)
+		if channelID == "" {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, "channel messages must reference the channel with a root 'e' tag")
+			return
+		}
+
+		if _, err := kind40.GetChannel(store, channelID); err != nil {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, err.Error())
+			return
+		}
+
+		// Store the new event
+		if err := store.StoreEvent(&env.Event); err != nil {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to store the event")
+			return
+		}
+
+		// Successfully processed event
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
+	}
+
+	return handler
+}
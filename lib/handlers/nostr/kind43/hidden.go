@@ -0,0 +1,128 @@
+package kind43
+
+import (
+	"log"
+	"slices"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind40"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// BuildHiddenMessageFilter returns a query filter that drops channel messages the channel creator
+// has hidden with kind 43 or that were written by users the creator has muted with kind 44
+// The channels and the moderation events of their creators are each loaded with a single query per result set
+func BuildHiddenMessageFilter(store stores.Store) lib_nostr.QueryFilter {
+	return func(events []*nostr.Event) []*nostr.Event {
+		channelIDs := []string{}
+		for _, event := range events {
+			if event.Kind != 42 {
+				continue
+			}
+
+			if channelID := kind40.GetChannelID(event); channelID != "" && !slices.Contains(channelIDs, channelID) {
+				channelIDs = append(channelIDs, channelID)
+			}
+		}
+
+		if len(channelIDs) == 0 {
+			return events
+		}
+
+		creators := loadCreators(store, channelIDs)
+		if len(creators) == 0 {
+			return events
+		}
+
+		hidden, muted := loadModeration(store, creators)
+
+		var filtered []*nostr.Event
+		for _, event := range events {
+			if event.Kind == 42 {
+				creator := creators[kind40.GetChannelID(event)]
+				if creator != "" && (hidden[creator][event.ID] || muted[creator][event.PubKey]) {
+					continue
+				}
+			}
+
+			filtered = append(filtered, event)
+		}
+
+		return filtered
+	}
+}
+
+// Find the creator of every channel from its kind 40 creation event
+func loadCreators(store stores.Store, channelIDs []string) map[string]string {
+	creators := make(map[string]string)
+
+	filter := nostr.Filter{
+		IDs:   channelIDs,
+		Kinds: []int{40},
+	}
+
+	channels, err := store.QueryEvents(filter)
+	if err != nil {
+		log.Printf("Error querying channels: %v", err)
+		return creators
+	}
+
+	for _, channel := range channels {
+		if filter.Matches(channel) {
+			creators[channel.ID] = channel.PubKey
+		}
+	}
+
+	return creators
+}
+
+// Collect the messages each creator has hidden and the users they have muted
+func loadModeration(store stores.Store, creators map[string]string) (map[string]map[string]bool, map[string]map[string]bool) {
+	hidden := make(map[string]map[string]bool)
+	muted := make(map[string]map[string]bool)
+
+	authors := []string{}
+	for _, creator := range creators {
+		if hidden[creator] != nil {
+			continue
+		}
+
+		hidden[creator] = make(map[string]bool)
+		muted[creator] = make(map[string]bool)
+		authors = append(authors, creator)
+	}
+
+	filter := nostr.Filter{
+		Authors: authors,
+		Kinds:   []int{43, 44},
+	}
+
+	moderationEvents, err := store.QueryEvents(filter)
+	if err != nil {
+		log.Printf("Error querying channel moderation events: %v", err)
+		return hidden, muted
+	}
+
+	for _, moderationEvent := range moderationEvents {
+		if !filter.Matches(moderationEvent) {
+			continue
+		}
+
+		creator := moderationEvent.PubKey
+		for _, tag := range moderationEvent.Tags {
+			if len(tag) < 2 {
+				continue
+			}
+
+			if moderationEvent.Kind == 43 && tag[0] == "e" {
+				hidden[creator][tag[1]] = true
+			} else if moderationEvent.Kind == 44 && tag[0] == "p" {
+				muted[creator][tag[1]] = true
+			}
+		}
+	}
+
+	return hidden, muted
+}
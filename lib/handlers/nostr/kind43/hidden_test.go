@@ -0,0 +1,89 @@
+package kind43
+
+import (
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Only event queries are implemented, every query is counted
+type eventStore struct {
+	stores.Store
+
+	events  []*nostr.Event
+	queries int
+}
+
+func (store *eventStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	store.queries++
+
+	events := []*nostr.Event{}
+	for _, event := range store.events {
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func message(id string, pubkey string, channelID string) *nostr.Event {
+	return &nostr.Event{ID: id, Kind: 42, PubKey: pubkey, Tags: nostr.Tags{{"e", channelID, "", "root"}}}
+}
+
+func TestHiddenMessageFilter(t *testing.T) {
+	store := &eventStore{events: []*nostr.Event{
+		{ID: "channel-a", Kind: 40, PubKey: "alice"},
+		{ID: "channel-b", Kind: 40, PubKey: "bob"},
+		{ID: "hide", Kind: 43, PubKey: "alice", Tags: nostr.Tags{{"e", "a-hidden"}}},
+		{ID: "mute", Kind: 44, PubKey: "bob", Tags: nostr.Tags{{"p", "mallory"}}},
+		// Moderation by someone who didn't create the channel has no effect
+		{ID: "other-hide", Kind: 43, PubKey: "mallory", Tags: nostr.Tags{{"e", "a-visible"}}},
+	}}
+
+	filter := BuildHiddenMessageFilter(store)
+
+	tests := []struct {
+		name    string
+		event   *nostr.Event
+		visible bool
+	}{
+		{"visible message", message("a-visible", "carol", "channel-a"), true},
+		{"hidden message", message("a-hidden", "carol", "channel-a"), false},
+		{"muted author", message("b-muted", "mallory", "channel-b"), false},
+		{"muted author in another channel", message("a-mallory", "mallory", "channel-a"), true},
+		{"unknown channel", message("unknown", "carol", "channel-c"), true},
+		{"not a channel message", &nostr.Event{ID: "note", Kind: 1, PubKey: "mallory"}, true},
+	}
+
+	events := []*nostr.Event{}
+	for _, test := range tests {
+		events = append(events, test.event)
+	}
+
+	filtered := map[string]bool{}
+	for _, event := range filter(events) {
+		filtered[event.ID] = true
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if filtered[test.event.ID] != test.visible {
+				t.Errorf("expected visible %v, got %v", test.visible, filtered[test.event.ID])
+			}
+		})
+	}
+
+	// The channels and the moderation of their creators are each loaded once for the whole result set
+	if store.queries != 2 {
+		t.Errorf("expected 2 queries, got %d", store.queries)
+	}
+
+	store.queries = 0
+	filter([]*nostr.Event{{ID: "note", Kind: 1}})
+	if store.queries != 0 {
+		t.Errorf("expected results without channel messages to skip the queries, got %d", store.queries)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	jsoniter "github.com/json-iterator/go"
 
+	"github.com/HORNET-Storage/hornet-storage/lib/aggregates"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
 	"github.com/nbd-wtf/go-nostr"
 
@@ -35,54 +36,57 @@ func BuildKind5Handler(store stores.Store) func(read lib_nostr.KindReader, write
 			return
 		}
 
-		// Inside handleKindFiveEvents, within the for loop that processes each deletion request
+		// Only events published by the author of the deletion request can be deleted, the request is refused
+		// as a whole if it references someone else's events
+		events := []*nostr.Event{}
 		for _, tag := range env.Event.Tags {
 			if tag[0] == "e" && len(tag) > 1 {
 				eventID := tag[1]
-				// Retrieve the public key of the event to be deleted
-				pubKey, err := extractPubKeyFromEventID(store, eventID)
+				// Retrieve the event to be deleted to check its author
+				event, err := getEvent(store, eventID)
 				if err != nil {
-					log.Printf("Failed to extract public key for event %s: %v", eventID, err)
-					// Decide how to handle this error; continue to next tag, respond with an error, etc.
-					write("NOTICE", fmt.Sprintf("Failed to extract public key for event %s: %v, the event doesn't exist", eventID, err))
+					// Events we don't have are simply skipped
+					log.Printf("Failed to find event %s: %v", eventID, err)
 					continue
 				}
 
-				log.Println("Found Public key:", pubKey)
-
-				// Validate that the deletion request and the event have the same public key
-				if pubKey == env.Event.PubKey {
-					if err := store.DeleteEvent(eventID); err != nil {
-						log.Printf("Error deleting event %s: %v", eventID, err)
-						// Optionally, handle individual delete failures
-					} else {
-						write("OK", env.Event.ID, true, "Deletion processed")
-					}
-				} else {
+				if event.PubKey != env.Event.PubKey {
 					log.Printf("Public key mismatch for event %s, deletion request ignored", eventID)
-					write("NOTICE", fmt.Sprintf("Public key mismatch for event %s, deletion request ignored", eventID))
+					lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixRestricted, fmt.Sprintf("event %s was not published by the author of the deletion request", eventID))
+					return
 				}
+
+				events = append(events, event)
+			}
+		}
+
+		// Deleted reactions, reposts and zaps are taken off the totals of the events they referenced
+		for _, event := range events {
+			if err := aggregates.DeleteEvent(store, event); err != nil {
+				log.Printf("Error deleting event %s: %v", event.ID, err)
+				lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, fmt.Sprintf("failed to delete event %s", event.ID))
+				return
 			}
 		}
 
+		lib_nostr.Accept(write, env.Event.ID, "Deletion processed")
 	}
 
 	return handler
 }
 
-func extractPubKeyFromEventID(store stores.Store, eventID string) (string, error) {
+func getEvent(store stores.Store, eventID string) (*nostr.Event, error) {
 	events, err := store.QueryEvents(nostr.Filter{
 		IDs: []string{eventID},
 	})
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if len(events) == 0 {
-		return "", fmt.Errorf("no events found for ID: %s", eventID)
+		return nil, fmt.Errorf("no events found for ID: %s", eventID)
 	}
 
-	event := events[0]
-	return event.PubKey, nil
+	return events[0], nil
 }
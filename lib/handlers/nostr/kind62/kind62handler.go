@@ -0,0 +1,57 @@
+package kind62
+
+import (
+	"log"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/vanish"
+)
+
+// BuildKind62Handler constructs and returns a handler function for kind 62 (Request to Vanish) events.
+func BuildKind62Handler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+		// Read data from the stream.
+		data, err := read()
+		if err != nil {
+			write("NOTICE", "Error reading from stream.")
+			return
+		}
+
+		// Unmarshal the received data into a Nostr event
+		var env nostr.EventEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			write("NOTICE", "Error unmarshaling event.")
+			return
+		}
+
+		// Check relay settings for allowed events whilst also verifying signatures and kind number
+		success := lib_nostr.ValidateEvent(write, env, vanish.KindVanishRequest)
+		if !success {
+			return
+		}
+
+		// Requests for other relays are ignored rather than stored so they can't delete anything later
+		if !vanish.IsAddressedToRelay(&env.Event) {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, "request to vanish is not addressed to this relay")
+			return
+		}
+
+		// The request is kept for auditing by the vanish package instead of being stored as an event,
+		// everything stored by the pubkey is deleted in the background
+		if _, err := vanish.Request(store, &env.Event); err != nil {
+			log.Printf("Error recording request to vanish %s: %v", env.Event.ID, err)
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to record the request to vanish")
+			return
+		}
+
+		lib_nostr.Accept(write, env.Event.ID, "Everything stored by this pubkey will be deleted")
+	}
+
+	return handler
+}
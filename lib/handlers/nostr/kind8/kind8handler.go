@@ -9,6 +9,7 @@ import (
 	"github.com/nbd-wtf/go-nostr"
 
 	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/badges"
 )
 
 // BuildKind8Handler constructs and returns a handler function for kind 8 (Badge Award) events.
@@ -38,18 +39,24 @@ func BuildKind8Handler(store stores.Store) func(read lib_nostr.KindReader, write
 
 		// Validate the badge award event's tags.
 		if !isValidBadgeAwardEvent(env.Event) {
-			write("NOTICE", "Invalid badge award event.")
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, "badge award must have 'a' and 'p' tags")
+			return
+		}
+
+		// The award must be for a badge the awarder has defined
+		if err := badges.ValidateAward(store, &env.Event); err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
 			return
 		}
 
 		// Store the new event
 		if err := store.StoreEvent(&env.Event); err != nil {
-			write("NOTICE", "Failed to store the event")
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to store the event")
 			return
 		}
 
 		// Successfully processed event
-		write("OK", env.Event.ID, true, "Event stored successfully")
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
 	}
 
 	return handler
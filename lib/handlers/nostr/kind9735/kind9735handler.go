@@ -5,8 +5,10 @@ import (
 
 	jsoniter "github.com/json-iterator/go"
 
+	"github.com/HORNET-Storage/hornet-storage/lib/aggregates"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
 	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
 
 	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
 )
@@ -36,14 +38,28 @@ func BuildKind9735Handler(store stores.Store) func(read lib_nostr.KindReader, wr
 			return
 		}
 
-		// Store the new event
-		if err := store.StoreEvent(&env.Event); err != nil {
-			write("NOTICE", "Failed to store the event")
+		// Validate the receipt against the zap request and invoice it carries
+		if err := ValidateZapReceipt(&env.Event); err != nil {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, err.Error())
+			return
+		}
+
+		// Optionally check the receipt came from the recipient's lnurl server, this makes a network request per recipient
+		if viper.GetBool("zap_verify_lnurl_pubkey") {
+			if err := ValidateZapperPubkey(store, &env.Event); err != nil {
+				lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, err.Error())
+				return
+			}
+		}
+
+		// Store the new event and add it to the zap totals of the zapped event
+		if err := aggregates.StoreEvent(store, &env.Event); err != nil {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to store the event")
 			return
 		}
 
 		// Successfully processed event
-		write("OK", env.Event.ID, true, "Event stored successfully")
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
 	}
 
 	return handler
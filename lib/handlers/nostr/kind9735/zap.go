@@ -0,0 +1,301 @@
+package kind9735
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/bolt11"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// LnurlPubkeyResolver returns the nostrPubkey advertised by the lnurl pay endpoint of a lud16 address or lud06 lnurl
+type LnurlPubkeyResolver func(address string) (string, error)
+
+var resolver LnurlPubkeyResolver = fetchLnurlPubkey
+
+// Resolved lnurls are cached so a burst of receipts for the same recipient doesn't make a request each, failures
+// are cached too so an unreachable server can't be used to hold up every receipt
+const (
+	lnurlCacheTTL     = 10 * time.Minute
+	maxCachedLnurls   = 10000
+	lnurlFetchTimeout = 3 * time.Second
+)
+
+type resolvedLnurl struct {
+	pubkey  string
+	err     error
+	expires time.Time
+}
+
+var (
+	lnurlCache      = map[string]resolvedLnurl{}
+	lnurlCacheMutex sync.Mutex
+)
+
+// Addresses come from profiles anyone can publish so the client only connects to public addresses
+var lnurlClient = &http.Client{
+	Timeout: lnurlFetchTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: lnurlFetchTimeout,
+			Control: publicAddressesOnly,
+		}).DialContext,
+		TLSHandshakeTimeout:   lnurlFetchTimeout,
+		ResponseHeaderTimeout: lnurlFetchTimeout,
+	},
+}
+
+// SetLnurlPubkeyResolver replaces the resolver used to check who signed a zap receipt, mainly so it can be stubbed locally
+func SetLnurlPubkeyResolver(newResolver LnurlPubkeyResolver) {
+	lnurlCacheMutex.Lock()
+	defer lnurlCacheMutex.Unlock()
+
+	resolver = newResolver
+	lnurlCache = map[string]resolvedLnurl{}
+}
+
+// ValidateZapReceipt checks a zap receipt against its embedded zap request and bolt11 invoice as described in NIP-57
+func ValidateZapReceipt(receipt *nostr.Event) error {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+	bolt11Tag := receipt.Tags.GetFirst([]string{"bolt11", ""})
+	if bolt11Tag == nil {
+		return fmt.Errorf("zap receipt must include a bolt11 tag")
+	}
+
+	descriptionTag := receipt.Tags.GetFirst([]string{"description", ""})
+	if descriptionTag == nil {
+		return fmt.Errorf("zap receipt must include a description tag")
+	}
+
+	receiptRecipient := receipt.Tags.GetFirst([]string{"p", ""})
+	if receiptRecipient == nil {
+		return fmt.Errorf("zap receipt must include a p tag")
+	}
+
+	var request nostr.Event
+	if err := json.Unmarshal([]byte(descriptionTag.Value()), &request); err != nil {
+		return fmt.Errorf("description is not a valid zap request")
+	}
+
+	if request.Kind != 9734 {
+		return fmt.Errorf("description must be a kind 9734 zap request")
+	}
+
+	if request.GetID() != request.ID {
+		return fmt.Errorf("zap request id does not match its content")
+	}
+
+	if ok, err := request.CheckSignature(); err != nil || !ok {
+		return fmt.Errorf("zap request signature is invalid")
+	}
+
+	// Zap requests must have exactly one p tag and it must be who the receipt was sent to
+	var requestRecipients []string
+	for _, tag := range request.Tags {
+		if len(tag) >= 2 && tag[0] == "p" {
+			requestRecipients = append(requestRecipients, tag[1])
+		}
+	}
+
+	if len(requestRecipients) != 1 || requestRecipients[0] != receiptRecipient.Value() {
+		return fmt.Errorf("zap request recipient does not match the receipt")
+	}
+
+	if requestEvent := request.Tags.GetFirst([]string{"e", ""}); requestEvent != nil {
+		receiptEvent := receipt.Tags.GetFirst([]string{"e", ""})
+		if receiptEvent == nil || receiptEvent.Value() != requestEvent.Value() {
+			return fmt.Errorf("zapped event does not match the zap request")
+		}
+	}
+
+	invoice, err := bolt11.Decode(bolt11Tag.Value())
+	if err != nil {
+		return err
+	}
+
+	descriptionHash := sha256.Sum256([]byte(descriptionTag.Value()))
+	if !bytes.Equal(invoice.DescriptionHash, descriptionHash[:]) {
+		return fmt.Errorf("invoice description hash does not match the zap request")
+	}
+
+	if amountTag := request.Tags.GetFirst([]string{"amount", ""}); amountTag != nil {
+		amount, err := strconv.ParseInt(amountTag.Value(), 10, 64)
+		if err != nil {
+			return fmt.Errorf("zap request amount is not a number")
+		}
+
+		if amount != invoice.AmountMsat {
+			return fmt.Errorf("invoice amount %d does not match the zap request amount %d", invoice.AmountMsat, amount)
+		}
+	}
+
+	return nil
+}
+
+// ValidateZapperPubkey checks the receipt was signed by the nostrPubkey of the recipient's lnurl server
+func ValidateZapperPubkey(store stores.Store, receipt *nostr.Event) error {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+	recipient := receipt.Tags.GetFirst([]string{"p", ""})
+	if recipient == nil {
+		return fmt.Errorf("zap receipt must include a p tag")
+	}
+
+	filter := nostr.Filter{
+		Authors: []string{recipient.Value()},
+		Kinds:   []int{0},
+	}
+
+	profiles, err := store.QueryEvents(filter)
+	if err != nil {
+		return fmt.Errorf("failed to look up the recipient profile")
+	}
+
+	var profile *nostr.Event
+	for _, event := range profiles {
+		if filter.Matches(event) && (profile == nil || event.CreatedAt > profile.CreatedAt) {
+			profile = event
+		}
+	}
+
+	if profile == nil {
+		return fmt.Errorf("recipient profile not found")
+	}
+
+	var metadata struct {
+		Lud06 string `json:"lud06"`
+		Lud16 string `json:"lud16"`
+	}
+	if err := json.Unmarshal([]byte(profile.Content), &metadata); err != nil {
+		return fmt.Errorf("recipient profile is not valid json")
+	}
+
+	address := metadata.Lud16
+	if address == "" {
+		address = metadata.Lud06
+	}
+
+	if address == "" {
+		return fmt.Errorf("recipient profile has no lightning address")
+	}
+
+	pubkey, err := resolveLnurlPubkey(address)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the recipient lnurl: %v", err)
+	}
+
+	if pubkey != receipt.PubKey {
+		return fmt.Errorf("zap receipt was not signed by the recipient's lnurl server")
+	}
+
+	return nil
+}
+
+func resolveLnurlPubkey(address string) (string, error) {
+	lnurlCacheMutex.Lock()
+	cached, ok := lnurlCache[address]
+	currentResolver := resolver
+	lnurlCacheMutex.Unlock()
+
+	if ok && time.Now().Before(cached.expires) {
+		return cached.pubkey, cached.err
+	}
+
+	pubkey, err := currentResolver(address)
+
+	lnurlCacheMutex.Lock()
+	defer lnurlCacheMutex.Unlock()
+
+	if len(lnurlCache) >= maxCachedLnurls {
+		lnurlCache = map[string]resolvedLnurl{}
+	}
+	lnurlCache[address] = resolvedLnurl{pubkey: pubkey, err: err, expires: time.Now().Add(lnurlCacheTTL)}
+
+	return pubkey, err
+}
+
+func fetchLnurlPubkey(address string) (string, error) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+	url, err := lnurlPayUrl(address)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := lnurlClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("lnurl server returned status %d", response.StatusCode)
+	}
+
+	var payParams struct {
+		AllowsNostr bool   `json:"allowsNostr"`
+		NostrPubkey string `json:"nostrPubkey"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&payParams); err != nil {
+		return "", fmt.Errorf("invalid lnurl pay response")
+	}
+
+	if !payParams.AllowsNostr || payParams.NostrPubkey == "" {
+		return "", fmt.Errorf("lnurl server does not support nostr zaps")
+	}
+
+	return payParams.NostrPubkey, nil
+}
+
+// Lightning addresses map to a well known url, lnurls are bech32 encoded urls
+func lnurlPayUrl(address string) (string, error) {
+	if name, domain, found := strings.Cut(address, "@"); found {
+		return fmt.Sprintf("https://%s/.well-known/lnurlp/%s", domain, name), nil
+	}
+
+	hrp, data, err := bech32.DecodeNoLimit(address)
+	if err != nil || hrp != "lnurl" {
+		return "", fmt.Errorf("invalid lnurl: %s", address)
+	}
+
+	url, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return "", fmt.Errorf("invalid lnurl: %v", err)
+	}
+
+	return string(url), nil
+}
+
+// Refuses connections to loopback, private and link local addresses, checked on the resolved address so neither
+// dns nor redirects can point the request back at the relay's own network
+func publicAddressesOnly(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("lnurl server address %s is not public", host)
+	}
+
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
@@ -0,0 +1,178 @@
+package kind9735
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Builds a bolt11 invoice carrying only a description hash, the signature is zeroed as it isn't checked
+func invoice(t *testing.T, hrp string, description string) string {
+	hash := sha256.Sum256([]byte(description))
+
+	groups, err := bech32.ConvertBits(hash[:], 8, 5, true)
+	if err != nil {
+		t.Fatalf("failed to convert description hash: %v", err)
+	}
+
+	data := []byte{0, 0, 0, 0, 0, 0, 1, 23, byte(len(groups) >> 5), byte(len(groups) & 31)}
+	data = append(data, groups...)
+	data = append(data, make([]byte, 104)...)
+
+	encoded, err := bech32.Encode(hrp, data)
+	if err != nil {
+		t.Fatalf("failed to encode invoice: %v", err)
+	}
+
+	return encoded
+}
+
+func zapRequest(t *testing.T, tags nostr.Tags) string {
+	request := nostr.Event{Kind: 9734, CreatedAt: nostr.Now(), Tags: tags}
+	if err := request.Sign(nostr.GeneratePrivateKey()); err != nil {
+		t.Fatalf("failed to sign zap request: %v", err)
+	}
+
+	return request.String()
+}
+
+func TestValidateZapReceipt(t *testing.T) {
+	request := zapRequest(t, nostr.Tags{{"p", "recipient"}, {"e", "note"}, {"amount", "21000"}})
+	withoutAmount := zapRequest(t, nostr.Tags{{"p", "recipient"}})
+	twoRecipients := zapRequest(t, nostr.Tags{{"p", "recipient"}, {"p", "other"}})
+
+	tampered := nostr.Event{}
+	tampered.UnmarshalJSON([]byte(request))
+	tampered.Content = "changed"
+
+	tests := []struct {
+		name  string
+		tags  nostr.Tags
+		valid bool
+	}{
+		{"valid receipt", nostr.Tags{{"p", "recipient"}, {"e", "note"}, {"bolt11", invoice(t, "lnbc210n", request)}, {"description", request}}, true},
+		{"request without an amount", nostr.Tags{{"p", "recipient"}, {"bolt11", invoice(t, "lnbc1u", withoutAmount)}, {"description", withoutAmount}}, true},
+		{"amount mismatch", nostr.Tags{{"p", "recipient"}, {"e", "note"}, {"bolt11", invoice(t, "lnbc1u", request)}, {"description", request}}, false},
+		{"description hash mismatch", nostr.Tags{{"p", "recipient"}, {"e", "note"}, {"bolt11", invoice(t, "lnbc210n", withoutAmount)}, {"description", request}}, false},
+		{"recipient mismatch", nostr.Tags{{"p", "other"}, {"e", "note"}, {"bolt11", invoice(t, "lnbc210n", request)}, {"description", request}}, false},
+		{"zapped event mismatch", nostr.Tags{{"p", "recipient"}, {"e", "other"}, {"bolt11", invoice(t, "lnbc210n", request)}, {"description", request}}, false},
+		{"request with two recipients", nostr.Tags{{"p", "recipient"}, {"bolt11", invoice(t, "lnbc1u", twoRecipients)}, {"description", twoRecipients}}, false},
+		{"tampered request", nostr.Tags{{"p", "recipient"}, {"e", "note"}, {"bolt11", invoice(t, "lnbc210n", tampered.String())}, {"description", tampered.String()}}, false},
+		{"missing bolt11", nostr.Tags{{"p", "recipient"}, {"e", "note"}, {"description", request}}, false},
+		{"missing description", nostr.Tags{{"p", "recipient"}, {"e", "note"}, {"bolt11", invoice(t, "lnbc210n", request)}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			receipt := &nostr.Event{Kind: 9735, Tags: test.tags}
+
+			if err := ValidateZapReceipt(receipt); (err == nil) != test.valid {
+				t.Errorf("expected valid %v, got error %v", test.valid, err)
+			}
+		})
+	}
+}
+
+// Only profile queries are implemented
+type profileStore struct {
+	stores.Store
+
+	profiles []*nostr.Event
+}
+
+func (store *profileStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	return store.profiles, nil
+}
+
+func TestValidateZapperPubkey(t *testing.T) {
+	store := &profileStore{profiles: []*nostr.Event{
+		{Kind: 0, PubKey: "recipient", CreatedAt: 1, Content: `{"lud16":"old@wallet.example"}`},
+		{Kind: 0, PubKey: "recipient", CreatedAt: 2, Content: `{"lud16":"alice@wallet.example"}`},
+		{Kind: 0, PubKey: "unreachable", CreatedAt: 1, Content: `{"lud16":"bob@down.example"}`},
+		{Kind: 0, PubKey: "no-address", CreatedAt: 1, Content: `{"name":"carol"}`},
+	}}
+
+	resolved := 0
+	SetLnurlPubkeyResolver(func(address string) (string, error) {
+		resolved++
+
+		if address == "alice@wallet.example" {
+			return "zapper", nil
+		}
+
+		return "", fmt.Errorf("unreachable")
+	})
+	defer SetLnurlPubkeyResolver(fetchLnurlPubkey)
+
+	tests := []struct {
+		name      string
+		recipient string
+		signer    string
+		valid     bool
+	}{
+		{"signed by the lnurl server", "recipient", "zapper", true},
+		{"signed by someone else", "recipient", "mallory", false},
+		{"unreachable lnurl server", "unreachable", "zapper", false},
+		{"profile without a lightning address", "no-address", "zapper", false},
+		{"unknown recipient", "unknown", "zapper", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Profiles of other pubkeys are filtered out by the author
+			profiles := []*nostr.Event{}
+			for _, profile := range store.profiles {
+				if profile.PubKey == test.recipient {
+					profiles = append(profiles, profile)
+				}
+			}
+
+			receipt := &nostr.Event{Kind: 9735, PubKey: test.signer, Tags: nostr.Tags{{"p", test.recipient}}}
+			if err := ValidateZapperPubkey(&profileStore{profiles: profiles}, receipt); (err == nil) != test.valid {
+				t.Errorf("expected valid %v, got error %v", test.valid, err)
+			}
+		})
+	}
+
+	// Each address is only resolved once, including the one that failed
+	if resolved != 2 {
+		t.Errorf("expected 2 lookups, got %d", resolved)
+	}
+}
+
+func TestPublicAddressesOnly(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:443", false},
+		{"[::1]:443", false},
+		{"10.0.0.1:443", false},
+		{"192.168.1.10:80", false},
+		{"172.16.0.1:443", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:443", false},
+		{"[fd00::1]:443", false},
+		{"0.0.0.0:443", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			if err := publicAddressesOnly("tcp", test.address, nil); (err == nil) != test.allowed {
+				t.Errorf("expected allowed %v, got error %v", test.allowed, err)
+			}
+		})
+	}
+
+	if isPublicIP(net.ParseIP("::ffff:127.0.0.1")) {
+		t.Errorf("expected a mapped loopback address to be refused")
+	}
+}
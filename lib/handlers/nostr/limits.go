@@ -0,0 +1,112 @@
+package nostr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+)
+
+// CheckEventID recomputes the id from the event content, the signature check only covers the id
+// the client sent so an event with a forged id would otherwise be stored under it
+func CheckEventID(event *nostr.Event) error {
+	if !nostr.IsValid32ByteHex(event.ID) {
+		return fmt.Errorf("invalid: event id must be 32 bytes of lowercase hex")
+	}
+
+	if !nostr.IsValid32ByteHex(event.PubKey) {
+		return fmt.Errorf("invalid: pubkey must be 32 bytes of lowercase hex")
+	}
+
+	if event.GetID() != event.ID {
+		return fmt.Errorf("invalid: event id does not match its content")
+	}
+
+	return nil
+}
+
+// CheckEventLimits enforces the configured limits on content length, tag count, tag value length and the
+// total size of the serialized event, a limit of 0 disables it
+func CheckEventLimits(event *nostr.Event) error {
+	if limit := viper.GetInt("max_content_length"); limit > 0 && len(event.Content) > limit {
+		return fmt.Errorf("invalid: content is longer than %d bytes", limit)
+	}
+
+	if limit := viper.GetInt("max_event_tags"); limit > 0 && len(event.Tags) > limit {
+		return fmt.Errorf("invalid: event has more than %d tags", limit)
+	}
+
+	if limit := viper.GetInt("max_tag_value_length"); limit > 0 {
+		for _, tag := range event.Tags {
+			// The description of a zap receipt carries the whole zap request so only max_event_size bounds it
+			if event.Kind == 9735 && tag.Key() == "description" {
+				continue
+			}
+
+			for _, value := range tag {
+				if len(value) > limit {
+					return fmt.Errorf("invalid: tag values can't be longer than %d bytes", limit)
+				}
+			}
+		}
+	}
+
+	if limit := viper.GetInt("max_event_size"); limit > 0 {
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+		serialized, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("invalid: event could not be serialized")
+		}
+
+		if len(serialized) > limit {
+			return fmt.Errorf("invalid: event is larger than %d bytes", limit)
+		}
+	}
+
+	return nil
+}
+
+// CheckTagValues makes sure tags are well formed, handlers index into tags without checking their length
+// so empty tags are refused and e, p and a tags must hold an event id, a pubkey and a coordinate
+func CheckTagValues(event *nostr.Event) error {
+	for _, tag := range event.Tags {
+		if len(tag) == 0 {
+			return fmt.Errorf("invalid: empty tag")
+		}
+
+		switch tag[0] {
+		case "e":
+			if len(tag) < 2 || !nostr.IsValid32ByteHex(tag[1]) {
+				return fmt.Errorf("invalid: 'e' tags must reference a 32 byte lowercase hex event id")
+			}
+		case "p":
+			if len(tag) < 2 || !nostr.IsValid32ByteHex(tag[1]) {
+				return fmt.Errorf("invalid: 'p' tags must reference a 32 byte lowercase hex pubkey")
+			}
+		case "a":
+			if len(tag) < 2 || !isValidCoordinate(tag[1]) {
+				return fmt.Errorf("invalid: 'a' tags must reference a <kind>:<pubkey>:<d tag> coordinate")
+			}
+		}
+	}
+
+	return nil
+}
+
+// Replaceable events are referenced as <kind>:<pubkey>: but the trailing colon is often left out
+func isValidCoordinate(coordinate string) bool {
+	parts := strings.SplitN(coordinate, ":", 3)
+	if len(parts) < 2 {
+		return false
+	}
+
+	if kind, err := strconv.Atoi(parts[0]); err != nil || kind < 0 {
+		return false
+	}
+
+	return nostr.IsValid32ByteHex(parts[1])
+}
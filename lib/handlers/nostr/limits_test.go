@@ -0,0 +1,46 @@
+package nostr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+)
+
+func TestCheckEventLimits(t *testing.T) {
+	viper.Set("max_content_length", 100)
+	viper.Set("max_event_tags", 3)
+	viper.Set("max_tag_value_length", 50)
+	viper.Set("max_event_size", 2000)
+	defer viper.Set("max_content_length", nil)
+	defer viper.Set("max_event_tags", nil)
+	defer viper.Set("max_tag_value_length", nil)
+	defer viper.Set("max_event_size", nil)
+
+	long := strings.Repeat("x", 60)
+
+	tests := []struct {
+		name  string
+		event nostr.Event
+		valid bool
+	}{
+		{"within every limit", nostr.Event{Kind: 1, Content: "hello", Tags: nostr.Tags{{"t", "nostr"}}}, true},
+		{"content too long", nostr.Event{Kind: 1, Content: strings.Repeat("x", 101)}, false},
+		{"too many tags", nostr.Event{Kind: 1, Tags: nostr.Tags{{"t", "a"}, {"t", "b"}, {"t", "c"}, {"t", "d"}}}, false},
+		{"tag value too long", nostr.Event{Kind: 1, Tags: nostr.Tags{{"t", long}}}, false},
+		{"zap receipt description", nostr.Event{Kind: 9735, Tags: nostr.Tags{{"description", long}}}, true},
+		{"zap receipt other tag", nostr.Event{Kind: 9735, Tags: nostr.Tags{{"bolt11", long}}}, false},
+		{"description on another kind", nostr.Event{Kind: 1, Tags: nostr.Tags{{"description", long}}}, false},
+		{"zap receipt description over the event size", nostr.Event{Kind: 9735, Tags: nostr.Tags{{"description", strings.Repeat("x", 2000)}}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckEventLimits(&test.event)
+			if (err == nil) != test.valid {
+				t.Errorf("expected valid %v, got error %v", test.valid, err)
+			}
+		})
+	}
+}
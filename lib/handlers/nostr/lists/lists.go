@@ -0,0 +1,137 @@
+package lists
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// The item tags each NIP-51 list or set may contain, other tags such as title or alt are left alone
+var listItems = map[int][]string{
+	10003: {"e", "a", "t", "r"},              // Bookmarks
+	10004: {"a"},                             // Communities
+	10005: {"e"},                             // Public chats
+	10006: {"relay"},                         // Blocked relays
+	10007: {"relay"},                         // Search relays
+	10009: {"group", "r"},                    // Simple groups
+	10012: {"relay", "a"},                    // Favorite relays
+	10015: {"t", "a"},                        // Interests
+	10020: {"p"},                             // Media follows
+	10030: {"emoji", "a"},                    // Emojis
+	10101: {"p"},                             // Good wiki authors
+	10102: {"relay"},                         // Good wiki relays
+	30001: {"e", "a", "t", "r", "p", "word"}, // Categorized lists, deprecated in favour of the sets below
+	30002: {"relay"},                         // Relay sets
+	30003: {"e", "a", "t", "r"},              // Bookmark sets
+	30004: {"a", "e"},                        // Curation sets
+	30005: {"e"},                             // Video curation sets
+	30007: {"p"},                             // Kind mute sets
+	30015: {"t"},                             // Interest sets
+	30030: {"emoji"},                         // Emoji sets
+}
+
+// Every tag that is a list item in one of the lists, an item tag that isn't expected by a list is rejected
+var itemTags = []string{"e", "a", "t", "r", "p", "word", "relay", "group", "emoji"}
+
+// Kinds returns every list and set kind handled by this package
+func Kinds() []int {
+	kinds := make([]int, 0, len(listItems))
+	for kind := range listItems {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+
+	return kinds
+}
+
+// ValidateList checks the public items of a list and the format of any private items in its content
+func ValidateList(event *nostr.Event) error {
+	expected, ok := listItems[event.Kind]
+	if !ok {
+		return fmt.Errorf("invalid: kind %d is not a list", event.Kind)
+	}
+
+	if err := ValidateItems(event.Tags, expected); err != nil {
+		return err
+	}
+
+	if err := ValidatePrivateItems(event.Content); err != nil {
+		return err
+	}
+
+	if event.Kind >= 30000 && event.Tags.GetFirst([]string{"d", ""}) == nil {
+		return fmt.Errorf("invalid: sets must have a 'd' identifier tag")
+	}
+
+	return nil
+}
+
+// ValidateItems checks each item tag is expected by the list and has a value in the right format
+func ValidateItems(tags nostr.Tags, expected []string) error {
+	for _, tag := range tags {
+		if len(tag) == 0 || !slices.Contains(itemTags, tag[0]) {
+			continue
+		}
+
+		if !slices.Contains(expected, tag[0]) {
+			return fmt.Errorf("invalid: '%s' tags are not expected in this list", tag[0])
+		}
+
+		if len(tag) < 2 || tag[1] == "" {
+			return fmt.Errorf("invalid: '%s' tag is missing a value", tag[0])
+		}
+
+		switch tag[0] {
+		case "e", "p":
+			if !nostr.IsValid32ByteHex(tag[1]) {
+				return fmt.Errorf("invalid: '%s' tag must contain a hex id: %s", tag[0], tag[1])
+			}
+		case "relay":
+			if !strings.HasPrefix(tag[1], "wss://") && !strings.HasPrefix(tag[1], "ws://") {
+				return fmt.Errorf("invalid: relay url must be a websocket url: %s", tag[1])
+			}
+		case "emoji":
+			if len(tag) < 3 {
+				return fmt.Errorf("invalid: 'emoji' tag must contain a shortcode and an image url")
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidatePrivateItems checks the content is empty or private items encrypted to the author with NIP-44 or NIP-04,
+// the relay can't decrypt them so only the format is checked
+func ValidatePrivateItems(content string) error {
+	if content == "" {
+		return nil
+	}
+
+	// NIP-04 payloads are the base64 ciphertext followed by the base64 iv
+	if ciphertext, iv, found := strings.Cut(content, "?iv="); found {
+		if _, err := base64.StdEncoding.DecodeString(ciphertext); err != nil {
+			return fmt.Errorf("invalid: private items are not valid nip-04 ciphertext")
+		}
+
+		if decoded, err := base64.StdEncoding.DecodeString(iv); err != nil || len(decoded) != 16 {
+			return fmt.Errorf("invalid: private items have an invalid nip-04 iv")
+		}
+
+		return nil
+	}
+
+	// NIP-44 v2 payloads are a version byte, a 32 byte nonce, the padded ciphertext and a 32 byte mac
+	decoded, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return fmt.Errorf("invalid: private items must be encrypted with nip-44 or nip-04")
+	}
+
+	if len(decoded) < 99 || len(decoded) > 65603 || decoded[0] != 2 {
+		return fmt.Errorf("invalid: private items are not a nip-44 v2 payload")
+	}
+
+	return nil
+}
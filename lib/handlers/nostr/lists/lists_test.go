@@ -0,0 +1,132 @@
+package lists
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"github.com/nbd-wtf/go-nostr/nip44"
+)
+
+func hexKey(character string) string {
+	return strings.Repeat(character, 64)
+}
+
+// Encrypts the private items to the author the way clients do
+func encrypt(t *testing.T, version int, items string) string {
+	secretKey := nostr.GeneratePrivateKey()
+	pubkey, _ := nostr.GetPublicKey(secretKey)
+
+	var content string
+	var err error
+	if version == 44 {
+		key, keyErr := nip44.GenerateConversationKey(pubkey, secretKey)
+		if keyErr != nil {
+			t.Fatalf("failed to generate conversation key: %v", keyErr)
+		}
+
+		// The library drops the random salt it generates so one is passed in
+		salt := make([]byte, 32)
+		rand.Read(salt)
+		content, err = nip44.Encrypt(items, key, nip44.WithCustomSalt(salt))
+	} else {
+		key, keyErr := nip04.ComputeSharedSecret(pubkey, secretKey)
+		if keyErr != nil {
+			t.Fatalf("failed to compute shared secret: %v", keyErr)
+		}
+		content, err = nip04.Encrypt(items, key)
+	}
+
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	return content
+}
+
+func TestValidatePrivateItems(t *testing.T) {
+	items := `[["e","` + hexKey("1") + `"],["t","nostr"]]`
+	nip44Payload, _ := base64.StdEncoding.DecodeString(encrypt(t, 44, items))
+	nip44Payload[0] = 1
+
+	tests := []struct {
+		name    string
+		content string
+		valid   bool
+	}{
+		{"no private items", "", true},
+		{"nip-44", encrypt(t, 44, items), true},
+		{"nip-04", encrypt(t, 4, items), true},
+		{"plain text", items, false},
+		{"unknown nip-44 version", base64.StdEncoding.EncodeToString(nip44Payload), false},
+		{"nip-44 payload too short", base64.StdEncoding.EncodeToString([]byte{2, 0, 0}), false},
+		{"nip-04 without base64 ciphertext", "items?iv=" + base64.StdEncoding.EncodeToString(make([]byte, 16)), false},
+		{"nip-04 with a short iv", base64.StdEncoding.EncodeToString([]byte("items")) + "?iv=" + base64.StdEncoding.EncodeToString(make([]byte, 8)), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidatePrivateItems(test.content)
+			if test.valid && err != nil {
+				t.Errorf("expected the content to be accepted, got %v", err)
+			}
+
+			if !test.valid && err == nil {
+				t.Errorf("expected the content to be rejected")
+			}
+		})
+	}
+}
+
+func TestValidateList(t *testing.T) {
+	tests := []struct {
+		name  string
+		event *nostr.Event
+		valid bool
+	}{
+		{
+			"bookmarks",
+			&nostr.Event{Kind: 10003, Tags: nostr.Tags{{"e", hexKey("1")}, {"t", "nostr"}, {"r", "https://example.com"}}},
+			true,
+		},
+		{
+			"bookmarks with private items",
+			&nostr.Event{Kind: 10003, Tags: nostr.Tags{{"e", hexKey("1")}}, Content: encrypt(t, 44, `[["t","private"]]`)},
+			true,
+		},
+		{
+			"other tags are left alone",
+			&nostr.Event{Kind: 10015, Tags: nostr.Tags{{"t", "music"}, {"alt", "interests"}}},
+			true,
+		},
+		{"item not expected by the list", &nostr.Event{Kind: 10003, Tags: nostr.Tags{{"p", hexKey("1")}}}, false},
+		{"item without a value", &nostr.Event{Kind: 10015, Tags: nostr.Tags{{"t"}}}, false},
+		{"invalid event id", &nostr.Event{Kind: 10005, Tags: nostr.Tags{{"e", "note"}}}, false},
+		{"relay that isn't a websocket", &nostr.Event{Kind: 10006, Tags: nostr.Tags{{"relay", "https://relay.example.com"}}}, false},
+		{"emoji without an image", &nostr.Event{Kind: 10030, Tags: nostr.Tags{{"emoji", "soapbox"}}}, false},
+		{"unencrypted private items", &nostr.Event{Kind: 10003, Content: "secret bookmarks"}, false},
+		{
+			"bookmark set",
+			&nostr.Event{Kind: 30003, Tags: nostr.Tags{{"d", "reading"}, {"a", "30023:" + hexKey("2") + ":post"}}},
+			true,
+		},
+		{"set without an identifier", &nostr.Event{Kind: 30003, Tags: nostr.Tags{{"e", hexKey("1")}}}, false},
+		{"not a list", &nostr.Event{Kind: 1}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateList(test.event)
+			if test.valid && err != nil {
+				t.Errorf("expected the list to be accepted, got %v", err)
+			}
+
+			if !test.valid && err == nil {
+				t.Errorf("expected the list to be rejected")
+			}
+		})
+	}
+}
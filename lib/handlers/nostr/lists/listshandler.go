@@ -0,0 +1,53 @@
+package lists
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// BuildListHandler constructs and returns a handler function for the NIP-51 standard lists and sets,
+// lists keep the latest event per pubkey and kind and sets keep the latest event per d tag
+func BuildListHandler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+		// Read data from the stream.
+		data, err := read()
+		if err != nil {
+			write("NOTICE", "Error reading from stream.")
+			return
+		}
+
+		// Unmarshal the received data into a Nostr event
+		var env nostr.EventEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			write("NOTICE", "Error unmarshaling event.")
+			return
+		}
+
+		// The same handler is registered for every list kind so the event's own kind is the expected kind
+		success := lib_nostr.ValidateEvent(write, env, env.Event.Kind)
+		if !success {
+			return
+		}
+
+		if err := ValidateList(&env.Event); err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
+			return
+		}
+
+		// Store the new event replacing the previous version of the list
+		if err := lib_nostr.StoreReplaceableEvent(store, &env.Event); err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
+			return
+		}
+
+		// Successfully processed event
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
+	}
+
+	return handler
+}
@@ -0,0 +1,127 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/lists"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Kinds that only differ in how they are stored and which tags they need, kinds with more involved
+// rules such as channels, badges, zaps or groups keep their own handler packages
+func defaultPolicies() []Policy {
+	return []Policy{
+		{Kind: 0, Storage: StorageReplaceable},                                               // User metadata
+		{Kind: 3, Storage: StorageReplaceable},                                               // Follow list
+		{Kind: 6, Storage: StorageRegular, OneOfTags: []string{"e", "p"}, Hook: checkRepost}, // Repost of a kind 1 note
+		{Kind: 7, Storage: StorageRegular, Hook: checkReaction},                              // Reaction
+		// Generic repost of anything but a kind 1 note
+		{Kind: 16, Storage: StorageRegular, RequiredTags: []string{"k"}, ReferencedEvents: []string{"e"}, Hook: checkGenericRepost},
+		{Kind: 117, Storage: StorageRegular},
+		{Kind: 1337, Storage: StorageRegular},
+		{Kind: 9372, Storage: StorageRegular, ReferencedEvents: []string{"e"}},
+		{Kind: 9373, Storage: StorageRegular, ReferencedEvents: []string{"e"}},
+		{Kind: 9802, Storage: StorageRegular, OneOfTags: []string{"a", "e", "r", "p", "context"}},     // Highlight
+		{Kind: 10001, Storage: StorageReplaceable, Hook: checkPinnedNotes},                            // Pinned notes
+		{Kind: 10002, Storage: StorageReplaceable, Hook: checkRelayList},                              // Relay list metadata
+		{Kind: 30000, Storage: StorageAddressable, RequiredTags: []string{"d"}, Hook: checkFollowSet}, // Follow sets
+		{Kind: 30009, Storage: StorageAddressable, RequiredTags: []string{"d"}},                       // Badge definition
+		{Kind: 30023, Storage: StorageAddressable, RequiredTags: []string{"d"}, Hook: checkMarkdown},  // Long form article
+		{Kind: 30024, Storage: StorageAddressable, RequiredTags: []string{"d"}, Hook: checkMarkdown},  // Long form draft
+		{Kind: 30079, Storage: StorageAddressable, RequiredTags: []string{"d", "f"}},                  // Event path
+	}
+}
+
+var (
+	unicodeEmojiRegex    = regexp.MustCompile(`[\x{1F600}-\x{1F64F}\x{1F300}-\x{1F5FF}\x{1F680}-\x{1F6FF}\x{1F700}-\x{1F77F}\x{1F780}-\x{1F7FF}\x{1F800}-\x{1F8FF}\x{1F900}-\x{1F9FF}\x{1FA00}-\x{1FA6F}\x{1FA70}-\x{1FAFF}\x{2600}-\x{26FF}\x{2700}-\x{27BF}]+`)
+	customShortcodeRegex = regexp.MustCompile(`^:[a-zA-Z0-9_+-]+:$`)
+	htmlTagRegex         = regexp.MustCompile(`<("[^"]*"|'[^']*'|[^'">])*>`)
+	hardLineBreakRegex   = regexp.MustCompile(`[ ]{2,}\n`)
+)
+
+// Reactions are "+" for a like, "-" for a dislike or an emoji, either unicode or a ":shortcode:"
+func checkReaction(store stores.Store, event *nostr.Event) error {
+	switch {
+	case event.Content == "+", event.Content == "-":
+		return nil
+	case unicodeEmojiRegex.MatchString(event.Content), customShortcodeRegex.MatchString(event.Content):
+		return nil
+	default:
+		return fmt.Errorf("invalid: reaction content must be '+', '-' or an emoji")
+	}
+}
+
+// Reposts reference the note with an e tag or only name its author with a p tag, a referenced note must be stored
+func checkRepost(store stores.Store, event *nostr.Event) error {
+	if tag := event.Tags.GetFirst([]string{"e", ""}); tag != nil && !isStored(store, (*tag)[1]) {
+		return fmt.Errorf("invalid: reposted event %s not found", (*tag)[1])
+	}
+
+	return nil
+}
+
+// Kind 1 notes are reposted with kind 6 so generic reposts of them are refused
+func checkGenericRepost(store stores.Store, event *nostr.Event) error {
+	if kind := event.Tags.GetFirst([]string{"k", ""}); kind != nil && (*kind)[1] == "1" {
+		return fmt.Errorf("invalid: kind 16 reposts cannot contain kind 1 events, use kind 6")
+	}
+
+	return nil
+}
+
+// Private pinned notes are encrypted in the content so the list may have no public items at all
+func checkPinnedNotes(store stores.Store, event *nostr.Event) error {
+	if err := lists.ValidateItems(event.Tags, []string{"e"}); err != nil {
+		return err
+	}
+
+	return lists.ValidatePrivateItems(event.Content)
+}
+
+func checkFollowSet(store stores.Store, event *nostr.Event) error {
+	if err := lists.ValidateItems(event.Tags, []string{"p"}); err != nil {
+		return err
+	}
+
+	return lists.ValidatePrivateItems(event.Content)
+}
+
+// Relay lists are made of 'r' tags holding a websocket url and an optional read or write marker
+func checkRelayList(store stores.Store, event *nostr.Event) error {
+	if len(event.Tags) == 0 {
+		return fmt.Errorf("invalid: relay list event must contain at least one 'r' tag")
+	}
+
+	for _, tag := range event.Tags {
+		if len(tag) < 2 || tag[0] != "r" {
+			return fmt.Errorf("invalid: relay lists can only contain 'r' tags")
+		}
+
+		if !strings.HasPrefix(tag[1], "wss://") || len(tag[1]) <= len("wss://") {
+			return fmt.Errorf("invalid: relay url %s must start with wss://", tag[1])
+		}
+
+		if len(tag) > 2 && tag[2] != "read" && tag[2] != "write" {
+			return fmt.Errorf("invalid: relay marker %s must be read or write", tag[2])
+		}
+	}
+
+	return nil
+}
+
+// Articles are markdown so html tags and hard line breaks are refused
+func checkMarkdown(store stores.Store, event *nostr.Event) error {
+	if htmlTagRegex.MatchString(event.Content) {
+		return fmt.Errorf("invalid: content must be markdown without html")
+	}
+
+	if hardLineBreakRegex.MatchString(event.Content) {
+		return fmt.Errorf("invalid: content must be markdown without hard line breaks")
+	}
+
+	return nil
+}
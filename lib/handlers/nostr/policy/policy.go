@@ -0,0 +1,283 @@
+package policy
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/aggregates"
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// StorageClass decides what happens to an event once it has passed the checks of its kind
+type StorageClass string
+
+const (
+	StorageRegular     StorageClass = "regular"     // Every event is kept
+	StorageReplaceable StorageClass = "replaceable" // Only the latest event per pubkey and kind is kept
+	StorageAddressable StorageClass = "addressable" // Only the latest event per pubkey, kind and d tag is kept
+	StorageEphemeral   StorageClass = "ephemeral"   // Events are broadcast to subscribers and never stored
+)
+
+// Hook runs checks written in Go for kinds the declarative fields can't describe, it runs after them
+// and the error it returns is sent to the client so it must start with a NIP-01 prefix such as invalid:
+type Hook func(store stores.Store, event *nostr.Event) error
+
+// Policy describes how events of a kind are checked and stored
+type Policy struct {
+	Kind             int          `mapstructure:"kind" json:"kind"`
+	Storage          StorageClass `mapstructure:"storage" json:"storage"`
+	RequiredTags     []string     `mapstructure:"required_tags" json:"required_tags,omitempty"`         // Every tag must be present with a value
+	OneOfTags        []string     `mapstructure:"one_of_tags" json:"one_of_tags,omitempty"`             // At least one of the tags must be present with a value
+	ReferencedEvents []string     `mapstructure:"referenced_events" json:"referenced_events,omitempty"` // Tags whose event id must already be stored on the relay
+	Authors          []string     `mapstructure:"authors" json:"authors,omitempty"`                     // Only these pubkeys may publish the kind, empty allows anyone
+	Hook             Hook         `mapstructure:"-" json:"-"`
+}
+
+var policies = map[int]Policy{}
+
+// Register adds or replaces the policy of a kind
+func Register(policy Policy) {
+	if policy.Storage == "" {
+		policy.Storage = StorageFor(policy.Kind)
+	}
+
+	policies[policy.Kind] = policy
+}
+
+// Get returns the policy of a kind, kinds the operator enabled through DynamicKinds without configuring
+// a policy get the default policy for their kind range so they can be accepted without code changes
+func Get(kind int) (Policy, bool) {
+	if policy, ok := policies[kind]; ok {
+		return policy, true
+	}
+
+	if isDynamicKind(kind) {
+		return ForKind(kind), true
+	}
+
+	return Policy{}, false
+}
+
+// Policies returns every registered policy ordered by kind
+func Policies() []Policy {
+	kinds := make([]int, 0, len(policies))
+	for kind := range policies {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+
+	registered := make([]Policy, 0, len(kinds))
+	for _, kind := range kinds {
+		registered = append(registered, policies[kind])
+	}
+
+	return registered
+}
+
+// ForKind returns the default policy of a kind, the kind range decides how it's stored and nothing else is checked
+func ForKind(kind int) Policy {
+	return Policy{Kind: kind, Storage: StorageFor(kind)}
+}
+
+// StorageFor returns the storage class NIP-01 gives to the range a kind falls in
+func StorageFor(kind int) StorageClass {
+	switch {
+	case lib_nostr.IsReplaceableKind(kind):
+		return StorageReplaceable
+	case lib_nostr.IsEphemeralKind(kind):
+		return StorageEphemeral
+	case lib_nostr.IsAddressableKind(kind):
+		return StorageAddressable
+	default:
+		return StorageRegular
+	}
+}
+
+// LoadPolicies registers the built in policies and then the ones configured in kind_policies, a configured
+// policy replaces the declarative fields of a built in one but keeps its Go hook
+func LoadPolicies() error {
+	for _, policy := range defaultPolicies() {
+		Register(policy)
+	}
+
+	configured := map[string]Policy{}
+	if err := viper.UnmarshalKey("kind_policies", &configured); err != nil {
+		return fmt.Errorf("failed to read kind_policies: %v", err)
+	}
+
+	for key, policy := range configured {
+		kind, err := strconv.Atoi(key)
+		if err != nil {
+			return fmt.Errorf("invalid kind %q in kind_policies", key)
+		}
+
+		policy.Kind = kind
+		if policy.Storage != "" && !isStorageClass(policy.Storage) {
+			return fmt.Errorf("invalid storage class %q for kind %d", policy.Storage, kind)
+		}
+
+		// Replacement is scoped by the kind range so any other class would replace the wrong events
+		if policy.Storage != "" && policy.Storage != StorageFor(kind) {
+			return fmt.Errorf("storage class %q doesn't match kind %d, kinds in its range are %s", policy.Storage, kind, StorageFor(kind))
+		}
+
+		if existing, ok := policies[kind]; ok {
+			policy.Hook = existing.Hook
+		}
+
+		Register(policy)
+	}
+
+	return nil
+}
+
+// Check runs the checks of a policy against an event that has already passed ValidateEvent
+func Check(store stores.Store, policy Policy, event *nostr.Event) error {
+	if len(policy.Authors) > 0 && !slices.Contains(policy.Authors, event.PubKey) {
+		return fmt.Errorf("restricted: kind %d can only be published by approved authors", event.Kind)
+	}
+
+	for _, name := range policy.RequiredTags {
+		if !hasTag(event, name) {
+			return fmt.Errorf("invalid: missing '%s' tag", name)
+		}
+	}
+
+	if len(policy.OneOfTags) > 0 && !slices.ContainsFunc(policy.OneOfTags, func(name string) bool { return hasTag(event, name) }) {
+		return fmt.Errorf("invalid: kind %d events must have one of the %v tags", event.Kind, policy.OneOfTags)
+	}
+
+	for _, name := range policy.ReferencedEvents {
+		tag := event.Tags.GetFirst([]string{name, ""})
+		if tag == nil || len(*tag) < 2 {
+			return fmt.Errorf("invalid: missing '%s' tag referencing an event", name)
+		}
+
+		if !isStored(store, (*tag)[1]) {
+			return fmt.Errorf("invalid: referenced event %s not found", (*tag)[1])
+		}
+	}
+
+	if policy.Hook != nil {
+		return policy.Hook(store, event)
+	}
+
+	return nil
+}
+
+// Store keeps an event according to the storage class of its policy
+func Store(store stores.Store, policy Policy, event *nostr.Event) error {
+	switch policy.Storage {
+	case StorageEphemeral:
+		lib_nostr.Broadcast(event)
+		return nil
+	case StorageReplaceable, StorageAddressable:
+		return lib_nostr.StoreReplaceableEvent(store, event)
+	default:
+		if err := aggregates.StoreEvent(store, event); err != nil {
+			return fmt.Errorf("error: failed to store the event")
+		}
+
+		return nil
+	}
+}
+
+// BuildHandler constructs the handler shared by every kind with a policy, the policy is looked up for each
+// event so kinds added to DynamicKinds from the panel are accepted without restarting the relay
+func BuildHandler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+		// Read data from the stream
+		data, err := read()
+		if err != nil {
+			write("NOTICE", "Error reading from stream.")
+			return
+		}
+
+		// Unmarshal the received data into a Nostr event
+		var env nostr.EventEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			write("NOTICE", "Error unmarshaling event.")
+			return
+		}
+
+		policy, ok := Get(env.Event.Kind)
+		if !ok {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixBlocked, fmt.Sprintf("kind %d is not supported by this relay", env.Event.Kind))
+			return
+		}
+
+		// Check relay settings for allowed events whilst also verifying signatures and kind number
+		success := lib_nostr.ValidateEvent(write, env, policy.Kind)
+		if !success {
+			return
+		}
+
+		if err := Check(store, policy, &env.Event); err != nil {
+			lib_nostr.RejectError(write, env.Event.ID, err)
+			return
+		}
+
+		if err := Store(store, policy, &env.Event); err != nil {
+			log.Printf("Error storing kind %d event %s: %v", env.Event.Kind, env.Event.ID, err)
+			lib_nostr.RejectError(write, env.Event.ID, err)
+			return
+		}
+
+		if policy.Storage == StorageEphemeral {
+			lib_nostr.Accept(write, env.Event.ID, "Event broadcast to subscribers")
+			return
+		}
+
+		// Successfully processed event
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
+	}
+
+	return handler
+}
+
+func isDynamicKind(kind int) bool {
+	settings, err := lib_nostr.LoadRelaySettings()
+	if err != nil {
+		return false
+	}
+
+	return slices.Contains(settings.DynamicKinds, strconv.Itoa(kind))
+}
+
+func isStorageClass(storage StorageClass) bool {
+	return slices.Contains([]StorageClass{StorageRegular, StorageReplaceable, StorageAddressable, StorageEphemeral}, storage)
+}
+
+func hasTag(event *nostr.Event, name string) bool {
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == name && tag[1] != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isStored(store stores.Store, id string) bool {
+	events, err := store.QueryEvents(nostr.Filter{IDs: []string{id}})
+	if err != nil {
+		return false
+	}
+
+	for _, event := range events {
+		if event.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
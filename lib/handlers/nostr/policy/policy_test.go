@@ -0,0 +1,274 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Keeps events in memory, the statistics store isn't used by the kinds under test
+type eventStore struct {
+	stores.Store
+
+	events map[string]*nostr.Event
+}
+
+func newStore(events ...*nostr.Event) *eventStore {
+	store := &eventStore{events: map[string]*nostr.Event{}}
+	for _, event := range events {
+		store.events[event.ID] = event
+	}
+
+	return store
+}
+
+func (store *eventStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	events := []*nostr.Event{}
+	for _, event := range store.events {
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (store *eventStore) StoreEvent(event *nostr.Event) error {
+	store.events[event.ID] = event
+	return nil
+}
+
+func (store *eventStore) DeleteEvent(id string) error {
+	delete(store.events, id)
+	return nil
+}
+
+func (store *eventStore) GetStatsStore() stores.StatisticsStore {
+	return nil
+}
+
+func hexKey(character string) string {
+	return strings.Repeat(character, 64)
+}
+
+// Get reads DynamicKinds from a config file in the working directory
+func useConfig(t *testing.T, config string) {
+	directory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(directory, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	workingDirectory, _ := os.Getwd()
+	if err := os.Chdir(directory); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	t.Cleanup(func() { os.Chdir(workingDirectory) })
+}
+
+func resetPolicies() {
+	policies = map[int]Policy{}
+	viper.Set("kind_policies", nil)
+}
+
+func TestLoadPolicies(t *testing.T) {
+	defer resetPolicies()
+
+	tests := []struct {
+		name       string
+		configured map[string]interface{}
+		valid      bool
+		kind       int
+		storage    StorageClass
+	}{
+		{"built in policies", nil, true, 30023, StorageAddressable},
+		{"new kind", map[string]interface{}{"1111": map[string]interface{}{"required_tags": []string{"e"}}}, true, 1111, StorageRegular},
+		{"matching storage class", map[string]interface{}{"10123": map[string]interface{}{"storage": "replaceable"}}, true, 10123, StorageReplaceable},
+		{"unknown storage class", map[string]interface{}{"1111": map[string]interface{}{"storage": "forever"}}, false, 0, ""},
+		{"addressable below 30000", map[string]interface{}{"1111": map[string]interface{}{"storage": "addressable"}}, false, 0, ""},
+		{"replaceable addressable kind", map[string]interface{}{"30123": map[string]interface{}{"storage": "replaceable"}}, false, 0, ""},
+		{"invalid kind", map[string]interface{}{"note": map[string]interface{}{}}, false, 0, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetPolicies()
+			viper.Set("kind_policies", test.configured)
+
+			err := LoadPolicies()
+			if (err == nil) != test.valid {
+				t.Fatalf("expected valid %v, got error %v", test.valid, err)
+			}
+
+			if !test.valid {
+				return
+			}
+
+			if policy, ok := policies[test.kind]; !ok || policy.Storage != test.storage {
+				t.Errorf("expected kind %d stored as %s, got %+v", test.kind, test.storage, policy)
+			}
+		})
+	}
+
+	// A configured policy replaces the rules of a built in one but keeps its hook
+	resetPolicies()
+	viper.Set("kind_policies", map[string]interface{}{"7": map[string]interface{}{"authors": []string{hexKey("a")}}})
+	if err := LoadPolicies(); err != nil {
+		t.Fatalf("failed to load policies: %v", err)
+	}
+
+	if policy := policies[7]; policy.Hook == nil || !slices.Equal(policy.Authors, []string{hexKey("a")}) {
+		t.Errorf("expected the configured authors and the built in hook, got %+v", policy)
+	}
+}
+
+func TestGet(t *testing.T) {
+	defer resetPolicies()
+	useConfig(t, `{"relay_settings": {"mode": "smart", "dynamicKinds": ["1111", "30123"]}}`)
+
+	resetPolicies()
+	Register(Policy{Kind: 30023})
+
+	tests := []struct {
+		name    string
+		kind    int
+		found   bool
+		storage StorageClass
+	}{
+		{"registered", 30023, true, StorageAddressable},
+		{"dynamic regular kind", 1111, true, StorageRegular},
+		{"dynamic addressable kind", 30123, true, StorageAddressable},
+		{"unknown kind", 2222, false, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy, ok := Get(test.kind)
+			if ok != test.found || policy.Storage != test.storage {
+				t.Errorf("expected %v %s, got %v %+v", test.found, test.storage, ok, policy)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	note := &nostr.Event{ID: hexKey("1"), Kind: 1}
+	store := newStore(note)
+
+	defaults := map[int]Policy{}
+	for _, policy := range defaultPolicies() {
+		defaults[policy.Kind] = policy
+	}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		event  nostr.Event
+		valid  bool
+	}{
+		{"repost of a stored note", defaults[6], nostr.Event{Kind: 6, Tags: nostr.Tags{{"e", note.ID}}}, true},
+		{"repost naming the author", defaults[6], nostr.Event{Kind: 6, Tags: nostr.Tags{{"p", hexKey("a")}}}, true},
+		{"repost of a missing note", defaults[6], nostr.Event{Kind: 6, Tags: nostr.Tags{{"e", hexKey("2")}}}, false},
+		{"repost without a reference", defaults[6], nostr.Event{Kind: 6}, false},
+		{"like", defaults[7], nostr.Event{Kind: 7, Content: "+"}, true},
+		{"reaction text", defaults[7], nostr.Event{Kind: 7, Content: "nice"}, false},
+		{"generic repost of a note", defaults[16], nostr.Event{Kind: 16, Tags: nostr.Tags{{"k", "1"}, {"e", note.ID}}}, false},
+		{"missing required tag", defaults[30023], nostr.Event{Kind: 30023}, false},
+		{"approved author", Policy{Kind: 1111, Authors: []string{hexKey("a")}}, nostr.Event{Kind: 1111, PubKey: hexKey("a")}, true},
+		{"other author", Policy{Kind: 1111, Authors: []string{hexKey("a")}}, nostr.Event{Kind: 1111, PubKey: hexKey("b")}, false},
+		{"one of the tags", Policy{Kind: 9802, OneOfTags: []string{"a", "r"}}, nostr.Event{Kind: 9802, Tags: nostr.Tags{{"r", "https://example.com"}}}, true},
+		{"none of the tags", Policy{Kind: 9802, OneOfTags: []string{"a", "r"}}, nostr.Event{Kind: 9802, Tags: nostr.Tags{{"t", "nostr"}}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Check(store, test.policy, &test.event)
+			if (err == nil) != test.valid {
+				t.Errorf("expected valid %v, got error %v", test.valid, err)
+			}
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	author := hexKey("a")
+
+	var broadcast []string
+	t.Cleanup(lib_nostr.ResetRegistrations)
+	lib_nostr.RegisterBroadcaster(func(event *nostr.Event) {
+		broadcast = append(broadcast, event.ID)
+	})
+
+	tests := []struct {
+		name      string
+		stored    []*nostr.Event
+		event     *nostr.Event
+		remaining []string
+		broadcast bool
+	}{
+		{
+			"regular events are all kept",
+			[]*nostr.Event{{ID: hexKey("1"), PubKey: author, Kind: 1111, CreatedAt: 10}},
+			&nostr.Event{ID: hexKey("2"), PubKey: author, Kind: 1111, CreatedAt: 20},
+			[]string{hexKey("1"), hexKey("2")},
+			false,
+		},
+		{
+			"replaceable events replace the previous one",
+			[]*nostr.Event{{ID: hexKey("1"), PubKey: author, Kind: 0, CreatedAt: 10}},
+			&nostr.Event{ID: hexKey("2"), PubKey: author, Kind: 0, CreatedAt: 20},
+			[]string{hexKey("2")},
+			false,
+		},
+		{
+			"addressable events only replace the same d tag",
+			[]*nostr.Event{
+				{ID: hexKey("1"), PubKey: author, Kind: 30023, CreatedAt: 10, Tags: nostr.Tags{{"d", "first"}}},
+				{ID: hexKey("3"), PubKey: author, Kind: 30023, CreatedAt: 10, Tags: nostr.Tags{{"d", "second"}}},
+			},
+			&nostr.Event{ID: hexKey("2"), PubKey: author, Kind: 30023, CreatedAt: 20, Tags: nostr.Tags{{"d", "first"}}},
+			[]string{hexKey("2"), hexKey("3")},
+			false,
+		},
+		{
+			"ephemeral events are broadcast and not stored",
+			nil,
+			&nostr.Event{ID: hexKey("2"), PubKey: author, Kind: 20001, CreatedAt: 20},
+			[]string{},
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			broadcast = nil
+			store := newStore(test.stored...)
+
+			if err := Store(store, ForKind(test.event.Kind), test.event); err != nil {
+				t.Fatalf("failed to store event: %v", err)
+			}
+
+			remaining := []string{}
+			for id := range store.events {
+				remaining = append(remaining, id)
+			}
+			slices.Sort(remaining)
+
+			if !slices.Equal(remaining, test.remaining) {
+				t.Errorf("expected %v stored, got %v", test.remaining, remaining)
+			}
+
+			if test.broadcast != (len(broadcast) == 1) {
+				t.Errorf("expected broadcast %v, got %v", test.broadcast, broadcast)
+			}
+		})
+	}
+}
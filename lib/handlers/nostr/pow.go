@@ -0,0 +1,83 @@
+package nostr
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip13"
+	"github.com/spf13/viper"
+)
+
+// MinPowDifficulty returns the lowest difficulty that applies to every event regardless of kind or author
+func MinPowDifficulty() int {
+	return viper.GetInt("min_pow_difficulty")
+}
+
+// RequiredPowDifficulty returns the difficulty an event must meet, the highest of the global minimum,
+// the minimum for the kind and the minimum for authors without an active subscription
+func RequiredPowDifficulty(event *nostr.Event) int {
+	required := MinPowDifficulty()
+
+	kindDifficulties := viper.GetStringMap("min_pow_difficulty_kinds")
+	if value, ok := kindDifficulties[strconv.Itoa(event.Kind)]; ok {
+		if difficulty, err := strconv.Atoi(fmt.Sprint(value)); err == nil && difficulty > required {
+			required = difficulty
+		}
+	}
+
+	nonSubscriberDifficulty := viper.GetInt("min_pow_difficulty_non_subscribers")
+	if nonSubscriberDifficulty > required && !isActiveSubscriber(event.PubKey) {
+		required = nonSubscriberDifficulty
+	}
+
+	return required
+}
+
+// CheckPow verifies the event id has enough leading zero bits and that the nonce tag commits to at least
+// the required difficulty, so events that got lucky with a lower target are still rejected
+func CheckPow(event *nostr.Event) error {
+	required := RequiredPowDifficulty(event)
+	if required <= 0 {
+		return nil
+	}
+
+	// The id has to match the content or the zero bits could simply be made up
+	if err := CheckEventID(event); err != nil {
+		return err
+	}
+
+	if difficulty := nip13.Difficulty(event.ID); difficulty < required {
+		return fmt.Errorf("pow: difficulty %d is less than %d", difficulty, required)
+	}
+
+	nonce := event.Tags.GetFirst([]string{"nonce"})
+	if nonce == nil || len(*nonce) < 3 {
+		return fmt.Errorf("pow: missing nonce tag with target difficulty")
+	}
+
+	target, err := strconv.Atoi((*nonce)[2])
+	if err != nil {
+		return fmt.Errorf("pow: invalid nonce target difficulty")
+	}
+
+	if target < required {
+		return fmt.Errorf("pow: committed target %d is less than %d", target, required)
+	}
+
+	return nil
+}
+
+func isActiveSubscriber(pubkey string) bool {
+	if validationStore == nil {
+		return false
+	}
+
+	subscriber, err := validationStore.GetSubscriber(pubkey)
+	if err != nil {
+		return false
+	}
+
+	return subscriber.Tier != "" && time.Now().Before(subscriber.EndDate)
+}
@@ -0,0 +1,108 @@
+package nostr
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip13"
+	"github.com/spf13/viper"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Only subscribers are implemented
+type subscriberStore struct {
+	stores.Store
+
+	subscribers map[string]*types.Subscriber
+}
+
+func (store *subscriberStore) GetSubscriber(npub string) (*types.Subscriber, error) {
+	if subscriber, ok := store.subscribers[npub]; ok {
+		return subscriber, nil
+	}
+
+	return nil, fmt.Errorf("subscriber not found")
+}
+
+// Mines an event until its id has exactly the difficulty, the nonce tag commits to the given target
+// and is left out when the target is negative
+func mine(t *testing.T, kind int, pubkey string, difficulty int, target int) *nostr.Event {
+	t.Helper()
+
+	event := &nostr.Event{Kind: kind, PubKey: pubkey, CreatedAt: nostr.Now(), Tags: nostr.Tags{}}
+	if target >= 0 {
+		event.Tags = nostr.Tags{{"nonce", "0", strconv.Itoa(target)}}
+	}
+
+	for nonce := 0; nonce < 1<<20; nonce++ {
+		if target >= 0 {
+			event.Tags[0][1] = strconv.Itoa(nonce)
+		} else {
+			event.Content = strconv.Itoa(nonce)
+		}
+
+		if nip13.Difficulty(event.GetID()) == difficulty {
+			event.ID = event.GetID()
+			return event
+		}
+	}
+
+	t.Fatalf("failed to mine an event with difficulty %d", difficulty)
+	return nil
+}
+
+func TestCheckPow(t *testing.T) {
+	subscriber := nostr.GeneratePrivateKey()
+	subscriberPubkey, _ := nostr.GetPublicKey(subscriber)
+	other, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+
+	SetValidationStore(&subscriberStore{subscribers: map[string]*types.Subscriber{
+		subscriberPubkey: {Npub: subscriberPubkey, Tier: "1 GB", EndDate: time.Now().Add(time.Hour)},
+	}})
+	defer SetValidationStore(nil)
+
+	defer viper.Set("min_pow_difficulty", nil)
+	defer viper.Set("min_pow_difficulty_kinds", nil)
+	defer viper.Set("min_pow_difficulty_non_subscribers", nil)
+
+	forged := mine(t, 1, other, 8, 8)
+	forged.Content = "changed after mining"
+
+	tests := []struct {
+		name           string
+		minimum        int
+		kinds          map[string]interface{}
+		nonSubscribers int
+		event          *nostr.Event
+		valid          bool
+	}{
+		{"disabled", 0, nil, 0, &nostr.Event{Kind: 1, PubKey: other}, true},
+		{"enough work", 8, nil, 0, mine(t, 1, other, 8, 8), true},
+		{"not enough work", 12, nil, 0, mine(t, 1, other, 8, 8), false},
+		{"committed to a lower target", 8, nil, 0, mine(t, 1, other, 8, 4), false},
+		{"missing nonce tag", 8, nil, 0, mine(t, 1, other, 8, -1), false},
+		{"id doesn't match the content", 8, nil, 0, forged, false},
+		{"kind minimum", 0, map[string]interface{}{"1": 12}, 0, mine(t, 1, other, 8, 8), false},
+		{"other kind", 0, map[string]interface{}{"7": 12}, 0, &nostr.Event{Kind: 1, PubKey: other}, true},
+		{"non subscriber", 0, nil, 8, &nostr.Event{Kind: 1, PubKey: other}, false},
+		{"active subscriber", 0, nil, 8, &nostr.Event{Kind: 1, PubKey: subscriberPubkey}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			viper.Set("min_pow_difficulty", test.minimum)
+			viper.Set("min_pow_difficulty_kinds", test.kinds)
+			viper.Set("min_pow_difficulty_non_subscribers", test.nonSubscribers)
+
+			err := CheckPow(test.event)
+			if (err == nil) != test.valid {
+				t.Errorf("expected valid %v, got error %v", test.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package nostr
+
+import (
+	"fmt"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// IsProtected reports whether an event has the NIP-70 ["-"] tag, protected events may only be
+// published by their author and are never synced to other relays
+func IsProtected(event *nostr.Event) bool {
+	for _, tag := range event.Tags {
+		if len(tag) >= 1 && tag[0] == "-" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckProtected checks a protected event is being published by a connection authenticated as its author,
+// pubkey is the pubkey the connection proved with AUTH and is empty when it hasn't authenticated
+func CheckProtected(event *nostr.Event, pubkey string) error {
+	if !IsProtected(event) {
+		return nil
+	}
+
+	if pubkey == "" {
+		return fmt.Errorf("auth-required: this event may only be published by its author")
+	}
+
+	if pubkey != event.PubKey {
+		return fmt.Errorf("restricted: this event may only be published by its author")
+	}
+
+	return nil
+}
+
+// WithoutProtected removes protected events from a set of events that is about to leave the relay
+func WithoutProtected(events []*nostr.Event) []*nostr.Event {
+	filtered := make([]*nostr.Event, 0, len(events))
+	for _, event := range events {
+		if !IsProtected(event) {
+			filtered = append(filtered, event)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,57 @@
+package nostr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestCheckProtected(t *testing.T) {
+	tests := []struct {
+		name   string
+		event  *nostr.Event
+		pubkey string
+		prefix string
+	}{
+		{"unprotected without auth", &nostr.Event{PubKey: "alice"}, "", ""},
+		{"unprotected from someone else", &nostr.Event{PubKey: "alice", Tags: nostr.Tags{{"t", "nostr"}}}, "bob", ""},
+		{"protected from its author", &nostr.Event{PubKey: "alice", Tags: nostr.Tags{{"-"}}}, "alice", ""},
+		{"protected without auth", &nostr.Event{PubKey: "alice", Tags: nostr.Tags{{"-"}}}, "", PrefixAuthRequired},
+		{"protected from someone else", &nostr.Event{PubKey: "alice", Tags: nostr.Tags{{"t", "nostr"}, {"-"}}}, "bob", PrefixRestricted},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckProtected(test.event, test.pubkey)
+			if test.prefix == "" {
+				if err != nil {
+					t.Errorf("expected the event to be accepted, got %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.HasPrefix(err.Error(), test.prefix+":") {
+				t.Errorf("expected a %s error, got %v", test.prefix, err)
+			}
+		})
+	}
+}
+
+func TestWithoutProtected(t *testing.T) {
+	events := []*nostr.Event{
+		{ID: "1"},
+		{ID: "2", Tags: nostr.Tags{{"-"}}},
+		{ID: "3", Tags: nostr.Tags{{"e", "1"}}},
+		{ID: "4", Tags: nostr.Tags{{"p", "alice"}, {"-"}}},
+	}
+
+	filtered := WithoutProtected(events)
+	if len(filtered) != 2 || filtered[0].ID != "1" || filtered[1].ID != "3" {
+		t.Errorf("expected only the unprotected events to be left, got %v", filtered)
+	}
+
+	if len(events) != 4 {
+		t.Errorf("expected the events passed in to be left alone")
+	}
+}
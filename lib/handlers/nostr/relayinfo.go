@@ -0,0 +1,262 @@
+package nostr
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+)
+
+// RelayInformation is the NIP-11 relay information document, it's built from the live configuration and
+// registered handlers so the http document and the kind 411 event always advertise the same thing
+type RelayInformation struct {
+	Name          string           `json:"name,omitempty"`
+	Description   string           `json:"description,omitempty"`
+	Pubkey        string           `json:"pubkey,omitempty"`
+	Contact       string           `json:"contact,omitempty"`
+	SupportedNIPs []int            `json:"supported_nips,omitempty"`
+	Software      string           `json:"software,omitempty"`
+	Version       string           `json:"version,omitempty"`
+	Icon          string           `json:"icon,omitempty"`
+	PaymentsURL   string           `json:"payments_url,omitempty"`
+	Limitation    *RelayLimitation `json:"limitation,omitempty"`
+	Retention     []RelayRetention `json:"retention,omitempty"`
+	Fees          *RelayFees       `json:"fees,omitempty"`
+}
+
+type RelayLimitation struct {
+	MaxMessageLength     int   `json:"max_message_length,omitempty"`
+	MaxSubscriptions     int   `json:"max_subscriptions,omitempty"`
+	MaxFilters           int   `json:"max_filters,omitempty"`
+	MaxEventTags         int   `json:"max_event_tags,omitempty"`
+	MaxContentLength     int   `json:"max_content_length,omitempty"`
+	AuthRequired         bool  `json:"auth_required"`
+	PaymentRequired      bool  `json:"payment_required"`
+	MinPowDifficulty     int   `json:"min_pow_difficulty,omitempty"`
+	CreatedAtLowerLimit  int64 `json:"created_at_lower_limit,omitempty"`
+	CreatedAtUpperLimit  int64 `json:"created_at_upper_limit"`
+	RestrictedReadsKinds []int `json:"restricted_read_kinds,omitempty"` // Not part of NIP-11, kinds only served to their author and recipients
+}
+
+type RelayRetention struct {
+	Kinds []int `json:"kinds,omitempty"`
+	Time  *int  `json:"time,omitempty"`
+	Count *int  `json:"count,omitempty"`
+}
+
+type RelayFees struct {
+	Subscription []RelayFee `json:"subscription,omitempty"`
+}
+
+type RelayFee struct {
+	Amount int64  `json:"amount"`
+	Unit   string `json:"unit"`
+	Period int    `json:"period,omitempty"`
+}
+
+// NIPs implemented by the relay itself rather than by a kind handler, each one is only advertised while
+// the configuration has it enabled
+var coreNips = map[int]func() bool{
+	1:  always,
+	5:  always, // Names of subscribers are served from /.well-known/nostr.json
+	11: always,
+	13: powRequired,
+	42: always,
+	50: always,
+	70: always,
+	86: func() bool { return len(viper.GetStringSlice("admin_pubkeys")) > 0 },
+	94: func() bool { return viper.GetBool("nip94_auto_generate") },
+	96: always,
+	98: always,
+}
+
+func always() bool {
+	return true
+}
+
+// Proof of work is only required when a minimum difficulty is configured for some events
+func powRequired() bool {
+	return MinPowDifficulty() > 0 ||
+		viper.GetInt("min_pow_difficulty_non_subscribers") > 0 ||
+		len(viper.GetStringMap("min_pow_difficulty_kinds")) > 0
+}
+
+// AuthRequired reports if connections have to authenticate with NIP-42 before publishing or requesting events
+func AuthRequired() bool {
+	return viper.GetBool("auth_required")
+}
+
+// NIPs implemented by each kind handler
+var kindNips = map[int][]int{
+	0:     {1, 24},
+	1:     {1},
+	3:     {2},
+	5:     {9},
+	6:     {18},
+	7:     {25},
+	8:     {58},
+	16:    {18},
+	40:    {28},
+	41:    {28},
+	42:    {28},
+	43:    {28},
+	44:    {28},
+	1059:  {59},
+	1063:  {94},
+	62:    {62},
+	1984:  {56},
+	9007:  {29},
+	9021:  {29},
+	9735:  {57},
+	9802:  {84},
+	10000: {51},
+	10001: {51},
+	10002: {65},
+	10003: {51},
+	10004: {51},
+	10005: {51},
+	10006: {51},
+	10007: {51},
+	10009: {51},
+	10012: {51},
+	10015: {51},
+	10020: {51},
+	10030: {51},
+	10050: {17},
+	10101: {51},
+	10102: {51},
+	30000: {51},
+	30001: {51},
+	30002: {51},
+	30003: {51},
+	30004: {51},
+	30005: {51},
+	30007: {51},
+	30008: {58},
+	30009: {58},
+	30015: {51},
+	30023: {23},
+	30024: {23},
+	30030: {51},
+	30079: {116},
+}
+
+// BuildRelayInformation creates the relay information document from the current configuration
+func BuildRelayInformation() RelayInformation {
+	return RelayInformation{
+		Name:          viper.GetString("RelayName"),
+		Description:   viper.GetString("RelayDescription"),
+		Pubkey:        viper.GetString("RelayPubkey"),
+		Contact:       viper.GetString("RelayContact"),
+		SupportedNIPs: SupportedNips(),
+		Software:      viper.GetString("RelaySoftware"),
+		Version:       viper.GetString("RelayVersion"),
+		Icon:          viper.GetString("RelayIcon"),
+		PaymentsURL:   viper.GetString("RelayPaymentsUrl"),
+		Limitation:    buildRelayLimitation(),
+		Retention:     buildRelayRetention(),
+		Fees:          buildRelayFees(),
+	}
+}
+
+// SupportedNips works out the supported NIPs from the handlers that are actually registered
+func SupportedNips() []int {
+	nips := map[int]bool{}
+	for nip, enabled := range coreNips {
+		if enabled() {
+			nips[nip] = true
+		}
+	}
+
+	for name := range GetHandlers() {
+		switch {
+		case name == "universal":
+			// Every kind is accepted so everything with a handler is supported
+			for _, kindNip := range kindNips {
+				for _, nip := range kindNip {
+					nips[nip] = true
+				}
+			}
+		case name == "count":
+			nips[45] = true
+		case strings.HasPrefix(name, "kind/"):
+			kind, err := strconv.Atoi(strings.TrimPrefix(name, "kind/"))
+			if err != nil {
+				continue
+			}
+
+			for _, nip := range kindNips[kind] {
+				nips[nip] = true
+			}
+		}
+	}
+
+	supported := []int{}
+	for nip := range nips {
+		supported = append(supported, nip)
+	}
+	sort.Ints(supported)
+
+	return supported
+}
+
+func buildRelayLimitation() *RelayLimitation {
+	var subscriptionTiers []types.SubscriptionTier
+	viper.UnmarshalKey("subscription_tiers", &subscriptionTiers)
+
+	return &RelayLimitation{
+		MaxMessageLength:     viper.GetInt("max_message_length"),
+		MaxSubscriptions:     viper.GetInt("max_subscriptions"),
+		MaxFilters:           viper.GetInt("max_filters"),
+		MaxEventTags:         viper.GetInt("max_event_tags"),
+		MaxContentLength:     viper.GetInt("max_content_length"),
+		AuthRequired:         AuthRequired(),
+		PaymentRequired:      viper.GetBool("enforce_storage_quotas") && len(subscriptionTiers) > 0,
+		MinPowDifficulty:     MinPowDifficulty(),
+		CreatedAtLowerLimit:  viper.GetInt64("created_at_lower_limit"),
+		CreatedAtUpperLimit:  viper.GetInt64("created_at_upper_limit"),
+		RestrictedReadsKinds: viper.GetIntSlice("restricted_read_kinds"),
+	}
+}
+
+func buildRelayRetention() []RelayRetention {
+	retention := []RelayRetention{}
+
+	if days := viper.GetInt("gift_wrap_retention_days"); days > 0 {
+		seconds := days * 24 * 60 * 60
+		retention = append(retention, RelayRetention{Kinds: []int{1059}, Time: &seconds})
+	}
+
+	return retention
+}
+
+// Subscription tier prices are configured in sats for a month
+func buildRelayFees() *RelayFees {
+	var subscriptionTiers []types.SubscriptionTier
+	if err := viper.UnmarshalKey("subscription_tiers", &subscriptionTiers); err != nil {
+		return nil
+	}
+
+	fees := &RelayFees{}
+	for _, tier := range subscriptionTiers {
+		price, err := strconv.ParseInt(strings.ReplaceAll(tier.Price, ",", ""), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		fees.Subscription = append(fees.Subscription, RelayFee{
+			Amount: price * 1000,
+			Unit:   "msats",
+			Period: 30 * 24 * 60 * 60,
+		})
+	}
+
+	if len(fees.Subscription) == 0 {
+		return nil
+	}
+
+	return fees
+}
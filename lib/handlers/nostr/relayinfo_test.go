@@ -0,0 +1,86 @@
+package nostr
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestSupportedNips(t *testing.T) {
+	defer viper.Reset()
+
+	RegisterHandler("kind/1059", func(read KindReader, write KindWriter) {})
+	defer delete(KindHandlers, "kind/1059")
+
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		nip      int
+		expected bool
+	}{
+		{"always supported", nil, 11, true},
+		{"registered handler", nil, 59, true},
+		{"handler that isn't registered", nil, 57, false},
+		{"proof of work disabled", nil, 13, false},
+		{"proof of work required", map[string]interface{}{"min_pow_difficulty": 16}, 13, true},
+		{"proof of work required for a kind", map[string]interface{}{"min_pow_difficulty_kinds": map[string]interface{}{"1": 20}}, 13, true},
+		{"management api without admins", nil, 86, false},
+		{"management api with admins", map[string]interface{}{"admin_pubkeys": []string{"alice"}}, 86, true},
+		{"file metadata disabled", nil, 94, false},
+		{"file metadata generated", map[string]interface{}{"nip94_auto_generate": true}, 94, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			viper.Reset()
+			for key, value := range test.settings {
+				viper.Set(key, value)
+			}
+
+			if supported := slices.Contains(SupportedNips(), test.nip); supported != test.expected {
+				t.Errorf("expected nip %d supported %v, got %v", test.nip, test.expected, supported)
+			}
+		})
+	}
+}
+
+func TestBuildRelayLimitationAuthRequired(t *testing.T) {
+	defer viper.Reset()
+
+	for _, required := range []bool{false, true} {
+		viper.Set("auth_required", required)
+
+		if limitation := buildRelayLimitation(); limitation.AuthRequired != required {
+			t.Errorf("expected auth_required %v, got %v", required, limitation.AuthRequired)
+		}
+	}
+}
+
+func TestAuthTimeCheck(t *testing.T) {
+	viper.Set("created_at_upper_limit", 60)
+	defer viper.Set("created_at_upper_limit", nil)
+
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name      string
+		createdAt int64
+		valid     bool
+	}{
+		{"now", now, true},
+		{"a few minutes ago", now - 300, true},
+		{"more than ten minutes ago", now - 900, false},
+		{"within clock drift", now + 30, true},
+		{"too far in the future", now + 600, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if valid, errMsg := AuthTimeCheck(test.createdAt); valid != test.valid {
+				t.Errorf("expected valid %v, got %v (%s)", test.valid, valid, errMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package nostr
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// IsReplaceableKind reports whether only the latest event per pubkey and kind is kept
+func IsReplaceableKind(kind int) bool {
+	return kind == 0 || kind == 3 || (kind >= 10000 && kind < 20000)
+}
+
+// IsAddressableKind reports whether only the latest event per pubkey, kind and d tag is kept
+func IsAddressableKind(kind int) bool {
+	return kind >= 30000 && kind < 40000
+}
+
+// IsEphemeralKind reports whether events of the kind are only relayed to subscribers and never stored
+func IsEphemeralKind(kind int) bool {
+	return kind >= 20000 && kind < 30000
+}
+
+// StoreReplaceableEvent stores a replaceable or addressable event and deletes the events it replaces,
+// an error starting with "duplicate:" is returned when a newer version is already stored
+func StoreReplaceableEvent(store stores.Store, event *nostr.Event) error {
+	dTag := event.Tags.GetD()
+
+	return StoreReplacingEvent(store, event, func(oldEvent *nostr.Event) bool {
+		return !IsAddressableKind(event.Kind) || oldEvent.Tags.GetD() == dTag
+	})
+}
+
+// StoreReplacingEvent stores an event that replaces the events of the same author and kind that replaces
+// reports true for, such as kinds where only the latest event per channel is kept
+func StoreReplacingEvent(store stores.Store, event *nostr.Event, replaces func(oldEvent *nostr.Event) bool) error {
+	filter := nostr.Filter{
+		Authors: []string{event.PubKey},
+		Kinds:   []int{event.Kind},
+	}
+
+	existingEvents, err := store.QueryEvents(filter)
+	if err != nil {
+		return fmt.Errorf("error: failed to query existing events: %v", err)
+	}
+
+	var replaced []*nostr.Event
+	for _, oldEvent := range existingEvents {
+		if !filter.Matches(oldEvent) || oldEvent.ID == event.ID {
+			continue
+		}
+
+		if !replaces(oldEvent) {
+			continue
+		}
+
+		// When the timestamps match the event with the lowest id is kept
+		if oldEvent.CreatedAt > event.CreatedAt || (oldEvent.CreatedAt == event.CreatedAt && oldEvent.ID < event.ID) {
+			return fmt.Errorf("duplicate: a newer version of this event already exists")
+		}
+
+		replaced = append(replaced, oldEvent)
+	}
+
+	if err := store.StoreEvent(event); err != nil {
+		return fmt.Errorf("error: failed to store the event")
+	}
+
+	for _, oldEvent := range replaced {
+		if err := store.DeleteEvent(oldEvent.ID); err != nil {
+			log.Printf("Error deleting replaced kind %d event %s: %v", oldEvent.Kind, oldEvent.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package nostr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Only the event methods of the store are implemented
+type eventStore struct {
+	stores.Store
+
+	events map[string]*nostr.Event
+}
+
+func (store *eventStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	events := []*nostr.Event{}
+	for _, event := range store.events {
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (store *eventStore) StoreEvent(event *nostr.Event) error {
+	store.events[event.ID] = event
+	return nil
+}
+
+func (store *eventStore) DeleteEvent(id string) error {
+	delete(store.events, id)
+	return nil
+}
+
+func TestStoreReplaceableEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  *nostr.Event
+		event     *nostr.Event
+		replaced  bool
+		duplicate bool
+	}{
+		{
+			"newer replaceable",
+			&nostr.Event{ID: "old", Kind: 10050, PubKey: "alice", CreatedAt: 1},
+			&nostr.Event{ID: "new", Kind: 10050, PubKey: "alice", CreatedAt: 2},
+			true, false,
+		},
+		{
+			"older replaceable",
+			&nostr.Event{ID: "old", Kind: 10050, PubKey: "alice", CreatedAt: 2},
+			&nostr.Event{ID: "new", Kind: 10050, PubKey: "alice", CreatedAt: 1},
+			false, true,
+		},
+		{
+			"same time keeps the lowest id",
+			&nostr.Event{ID: "a", Kind: 10050, PubKey: "alice", CreatedAt: 1},
+			&nostr.Event{ID: "b", Kind: 10050, PubKey: "alice", CreatedAt: 1},
+			false, true,
+		},
+		{
+			"another author",
+			&nostr.Event{ID: "old", Kind: 10050, PubKey: "bob", CreatedAt: 1},
+			&nostr.Event{ID: "new", Kind: 10050, PubKey: "alice", CreatedAt: 2},
+			false, false,
+		},
+		{
+			"addressable with the same d tag",
+			&nostr.Event{ID: "old", Kind: 30023, PubKey: "alice", CreatedAt: 1, Tags: nostr.Tags{{"d", "post"}}},
+			&nostr.Event{ID: "new", Kind: 30023, PubKey: "alice", CreatedAt: 2, Tags: nostr.Tags{{"d", "post"}}},
+			true, false,
+		},
+		{
+			"addressable with another d tag",
+			&nostr.Event{ID: "old", Kind: 30023, PubKey: "alice", CreatedAt: 1, Tags: nostr.Tags{{"d", "other"}}},
+			&nostr.Event{ID: "new", Kind: 30023, PubKey: "alice", CreatedAt: 2, Tags: nostr.Tags{{"d", "post"}}},
+			false, false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &eventStore{events: map[string]*nostr.Event{test.existing.ID: test.existing}}
+
+			err := StoreReplaceableEvent(store, test.event)
+			if duplicate := err != nil && strings.HasPrefix(err.Error(), PrefixDuplicate+":"); duplicate != test.duplicate {
+				t.Fatalf("expected duplicate %v, got error %v", test.duplicate, err)
+			}
+
+			if _, kept := store.events[test.existing.ID]; kept == test.replaced {
+				t.Errorf("expected replaced %v, got %v", test.replaced, !kept)
+			}
+		})
+	}
+}
+
+func TestStoreReplacingEvent(t *testing.T) {
+	channel := func(event *nostr.Event) string {
+		return event.Tags.GetFirst([]string{"e", ""}).Value()
+	}
+
+	store := &eventStore{events: map[string]*nostr.Event{
+		"a": {ID: "a", Kind: 41, PubKey: "alice", CreatedAt: 1, Tags: nostr.Tags{{"e", "channel-a"}}},
+		"b": {ID: "b", Kind: 41, PubKey: "alice", CreatedAt: 1, Tags: nostr.Tags{{"e", "channel-b"}}},
+	}}
+
+	update := &nostr.Event{ID: "a2", Kind: 41, PubKey: "alice", CreatedAt: 2, Tags: nostr.Tags{{"e", "channel-a"}}}
+	err := StoreReplacingEvent(store, update, func(oldEvent *nostr.Event) bool {
+		return channel(oldEvent) == "channel-a"
+	})
+	if err != nil {
+		t.Fatalf("failed to store event: %v", err)
+	}
+
+	for id, kept := range map[string]bool{"a": false, "b": true, "a2": true} {
+		if _, ok := store.events[id]; ok != kept {
+			t.Errorf("expected %s kept %v, got %v", id, kept, ok)
+		}
+	}
+}
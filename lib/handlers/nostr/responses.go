@@ -0,0 +1,102 @@
+package nostr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Machine readable prefixes NIP-01 requires at the start of the reason of OK false and CLOSED messages
+const (
+	PrefixDuplicate    = "duplicate"
+	PrefixPow          = "pow"
+	PrefixBlocked      = "blocked"
+	PrefixRateLimited  = "rate-limited"
+	PrefixInvalid      = "invalid"
+	PrefixRestricted   = "restricted"
+	PrefixAuthRequired = "auth-required"
+	PrefixError        = "error"
+)
+
+var reasonPrefixes = []string{
+	PrefixDuplicate,
+	PrefixPow,
+	PrefixBlocked,
+	PrefixRateLimited,
+	PrefixInvalid,
+	PrefixRestricted,
+	PrefixAuthRequired,
+	PrefixError,
+}
+
+// Reason joins a machine readable prefix and a human readable message, e.g. "invalid: missing 'd' tag"
+func Reason(prefix string, message string) string {
+	return fmt.Sprintf("%s: %s", prefix, message)
+}
+
+// HasReasonPrefix reports whether a reason already starts with one of the machine readable prefixes
+func HasReasonPrefix(reason string) bool {
+	for _, prefix := range reasonPrefixes {
+		if strings.HasPrefix(reason, prefix+":") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReasonFromError uses the message of an error as the reason, checks return errors that are already
+// prefixed and anything else is an unexpected failure on our side so it's reported as error:
+func ReasonFromError(err error) string {
+	if HasReasonPrefix(err.Error()) {
+		return err.Error()
+	}
+
+	return Reason(PrefixError, err.Error())
+}
+
+// Accept tells the client the event was accepted
+func Accept(write KindWriter, id string, message string) {
+	write("OK", id, true, message)
+}
+
+// Reject tells the client the event was refused and why
+func Reject(write KindWriter, id string, prefix string, message string) {
+	write("OK", id, false, Reason(prefix, message))
+}
+
+// RejectError refuses the event with the reason carried by an error
+func RejectError(write KindWriter, id string, err error) {
+	write("OK", id, false, ReasonFromError(err))
+}
+
+// Duplicate tells the client the relay already has the event, NIP-01 reports this as accepted
+func Duplicate(write KindWriter, id string) {
+	write("OK", id, true, Reason(PrefixDuplicate, "already have this event"))
+}
+
+// Closed ends a subscription the relay refused to serve or stopped serving
+func Closed(write KindWriter, subscriptionID string, prefix string, message string) {
+	write("CLOSED", subscriptionID, Reason(prefix, message))
+}
+
+// IsDuplicate reports whether an event with the id is already stored
+func IsDuplicate(id string) bool {
+	if validationStore == nil || id == "" {
+		return false
+	}
+
+	events, err := validationStore.QueryEvents(nostr.Filter{IDs: []string{id}})
+	if err != nil {
+		return false
+	}
+
+	for _, event := range events {
+		if event.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
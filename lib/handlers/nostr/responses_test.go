@@ -0,0 +1,106 @@
+package nostr
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestReasonFromError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		reason string
+	}{
+		{"prefixed", errors.New("invalid: missing 'd' tag"), "invalid: missing 'd' tag"},
+		{"prefix with a dash", errors.New("rate-limited: slow down"), "rate-limited: slow down"},
+		{"unexpected failure", errors.New("failed to store the event"), "error: failed to store the event"},
+		{"prefix without a colon", errors.New("invalid event"), "error: invalid event"},
+		{"unknown prefix", errors.New("nope: not today"), "error: nope: not today"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if reason := ReasonFromError(test.err); reason != test.reason {
+				t.Errorf("expected %q, got %q", test.reason, reason)
+			}
+		})
+	}
+}
+
+func TestResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(write KindWriter)
+		sent    []interface{}
+	}{
+		{
+			"accept",
+			func(write KindWriter) { Accept(write, "id", "Event stored successfully") },
+			[]interface{}{"OK", "id", true, "Event stored successfully"},
+		},
+		{
+			"reject",
+			func(write KindWriter) { Reject(write, "id", PrefixPow, "difficulty 8 is less than 16") },
+			[]interface{}{"OK", "id", false, "pow: difficulty 8 is less than 16"},
+		},
+		{
+			"reject an error",
+			func(write KindWriter) { RejectError(write, "id", errors.New("blocked: this pubkey has been banned")) },
+			[]interface{}{"OK", "id", false, "blocked: this pubkey has been banned"},
+		},
+		{
+			"duplicate",
+			func(write KindWriter) { Duplicate(write, "id") },
+			[]interface{}{"OK", "id", true, "duplicate: already have this event"},
+		},
+		{
+			"closed",
+			func(write KindWriter) {
+				Closed(write, "sub", PrefixAuthRequired, "authenticate to read direct messages")
+			},
+			[]interface{}{"CLOSED", "sub", "auth-required: authenticate to read direct messages"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sent []interface{}
+			test.respond(func(messageType string, params ...interface{}) {
+				sent = append([]interface{}{messageType}, params...)
+			})
+
+			if !slices.Equal(sent, test.sent) {
+				t.Errorf("expected %v, got %v", test.sent, sent)
+			}
+		})
+	}
+}
+
+func TestIsDuplicate(t *testing.T) {
+	stored := strings.Repeat("1", 64)
+	SetValidationStore(&eventStore{events: map[string]*nostr.Event{stored: {ID: stored}}})
+	defer SetValidationStore(nil)
+
+	tests := []struct {
+		name      string
+		id        string
+		duplicate bool
+	}{
+		{"stored", stored, true},
+		{"not stored", strings.Repeat("2", 64), false},
+		{"prefix of a stored id", stored[:16], false},
+		{"no id", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if duplicate := IsDuplicate(test.id); duplicate != test.duplicate {
+				t.Errorf("expected %v, got %v", test.duplicate, duplicate)
+			}
+		})
+	}
+}
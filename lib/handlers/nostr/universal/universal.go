@@ -1,6 +1,7 @@
 package universal
 
 import (
+	"github.com/HORNET-Storage/hornet-storage/lib/aggregates"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/nbd-wtf/go-nostr"
@@ -32,14 +33,21 @@ func BuildUniversalHandler(store stores.Store) func(read lib_nostr.KindReader, w
 			return
 		}
 
-		// Store the new event
-		if err := store.StoreEvent(&env.Event); err != nil {
-			write("NOTICE", "Failed to store the event")
+		// Ephemeral events sent over libp2p end up here too, they are broadcast rather than stored
+		if lib_nostr.IsEphemeralKind(env.Event.Kind) {
+			lib_nostr.Broadcast(&env.Event)
+			lib_nostr.Accept(write, env.Event.ID, "Event broadcast to subscribers")
+			return
+		}
+
+		// Store the new event, reactions, reposts and zaps are added to the totals of the events they reference
+		if err := aggregates.StoreEvent(store, &env.Event); err != nil {
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to store the event")
 			return
 		}
 
 		// Successfully processed event
-		write("OK", env.Event.ID, true, "Event stored successfully")
+		lib_nostr.Accept(write, env.Event.ID, "Event stored successfully")
 	}
 
 	return handler
@@ -1,9 +1,13 @@
 package nostr
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 
 	"time"
 
@@ -14,6 +18,9 @@ import (
 	"github.com/spf13/viper"
 
 	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation"
+	"github.com/HORNET-Storage/hornet-storage/lib/signing"
+	"github.com/HORNET-Storage/hornet-storage/lib/vanish"
 )
 
 // Gerneric event validation that almost all kinds will use
@@ -21,7 +28,7 @@ func ValidateEvent(write KindWriter, env nostr.EventEnvelope, expectedKind int)
 	// If the expected kind is greater than -1 then we ensure the event kind matches the expected kind
 	if expectedKind > -1 {
 		if env.Event.Kind != expectedKind {
-			write("OK", env.Event.ID, false, "Invalid event kind")
+			Reject(write, env.Event.ID, PrefixInvalid, fmt.Sprintf("kind %d is not handled here", env.Event.Kind))
 			return false
 		}
 	}
@@ -29,42 +36,106 @@ func ValidateEvent(write KindWriter, env nostr.EventEnvelope, expectedKind int)
 	// Load and check relay settings
 	settings, err := LoadRelaySettings()
 	if err != nil {
-		write("NOTICE", "Failed to load relay settings")
+		Reject(write, env.Event.ID, PrefixError, "failed to load relay settings")
 		return false
 	}
 
-	// Check if the event kind is allowed
-	blocked := IsTheKindAllowed(env.Event.Kind, settings)
-	if !blocked {
-		write("OK", env.Event.ID, false, "This kind is not handled by the relay")
+	// Check if the event kind is allowed, smart mode only accepts the listed kinds while unlimited mode
+	// accepts everything but the kinds that were disallowed
+	if !IsTheKindAllowed(env.Event.Kind, settings) || IsKindBlocked(env.Event.Kind, settings) {
+		Reject(write, env.Event.ID, PrefixBlocked, fmt.Sprintf("kind %d is not accepted by this relay", env.Event.Kind))
 		return false
 	}
 
-	timeCheck := TimeCheck(env.Event.CreatedAt.Time().Unix())
-	if !timeCheck {
-		write("OK", env.Event.ID, false, "The event creation date must be after January 1, 2019")
+	// Size limits are checked first as they are the cheapest way to turn away oversized events
+	if err := CheckEventLimits(&env.Event); err != nil {
+		RejectError(write, env.Event.ID, err)
+		return false
+	}
+
+	if err := CheckTagValues(&env.Event); err != nil {
+		RejectError(write, env.Event.ID, err)
+		return false
+	}
+
+	if err := CheckCreatedAt(&env.Event); err != nil {
+		RejectError(write, env.Event.ID, err)
+		return false
+	}
+
+	if err := CheckEventID(&env.Event); err != nil {
+		RejectError(write, env.Event.ID, err)
+		return false
 	}
 
 	// Validate the event signature
 	success, err := env.Event.CheckSignature()
 	if err != nil {
-		write("NOTICE", "Failed to check signature")
+		Reject(write, env.Event.ID, PrefixInvalid, "failed to check signature")
 		return false
 	}
 
 	if !success {
-		write("OK", env.Event.ID, false, "Signature failed to verify")
+		Reject(write, env.Event.ID, PrefixInvalid, "signature failed to verify")
+		return false
+	}
+
+	// Events we already have are acknowledged without being processed again, ephemeral events are never stored
+	if !IsEphemeralKind(env.Event.Kind) && IsDuplicate(env.Event.ID) {
+		Duplicate(write, env.Event.ID)
+		return false
+	}
+
+	// Reject banned events and pubkeys from the relay management lists
+	if validationStore != nil {
+		if err := moderation.CheckEvent(validationStore.GetStatsStore(), &env.Event); err != nil {
+			RejectError(write, env.Event.ID, err)
+			return false
+		}
+
+		// Events deleted by a request to vanish can't be published again
+		if err := vanish.CheckEvent(validationStore.GetStatsStore(), &env.Event); err != nil {
+			RejectError(write, env.Event.ID, err)
+			return false
+		}
+	}
+
+	for _, check := range eventChecks {
+		if err := check(&env.Event); err != nil {
+			RejectError(write, env.Event.ID, err)
+			return false
+		}
+	}
+
+	// Check the event meets the proof of work difficulty required for its kind and author
+	if err := CheckPow(&env.Event); err != nil {
+		RejectError(write, env.Event.ID, err)
 		return false
 	}
 
 	return true
 }
 
-// Check if the event is pretending it can time travel
-func TimeCheck(eventCreatedAt int64) bool {
-	currentTime := time.Now()
+// Check if the event is pretending it can time travel (NIP-22), created_at_upper_limit allows for clock drift
+// and created_at_lower_limit rejects events dated too far in the past when set
+func CheckCreatedAt(event *nostr.Event) error {
+	now := time.Now().Unix()
+	createdAt := int64(event.CreatedAt)
 
-	return eventCreatedAt <= currentTime.Unix()
+	if upperLimit := viper.GetInt64("created_at_upper_limit"); createdAt > now+upperLimit {
+		return fmt.Errorf("invalid: created_at is more than %d seconds in the future", upperLimit)
+	}
+
+	// Gift wraps backdate created_at to hide when a message was sent so only the future bound applies to them
+	if event.Kind == 1059 {
+		return nil
+	}
+
+	if lowerLimit := viper.GetInt64("created_at_lower_limit"); lowerLimit > 0 && createdAt < now-lowerLimit {
+		return fmt.Errorf("invalid: created_at is more than %d seconds in the past", lowerLimit)
+	}
+
+	return nil
 }
 
 func AuthTimeCheck(eventCreatedAt int64) (bool, string) {
@@ -74,7 +145,13 @@ func AuthTimeCheck(eventCreatedAt int64) (bool, string) {
 
 	// Check if the event timestamp is within the last 10 minutes
 	if eventTime.Before(tenMinutesAgo) {
-		errMsg := fmt.Sprintf("invalid: event creation date is more than 10 minutes ago (%s)", eventTime)
+		errMsg := fmt.Sprintf("event creation date is more than 10 minutes ago (%s)", eventTime)
+		return false, errMsg
+	}
+
+	// Auth events from the future are held to the same clock drift allowance as every other event
+	if upperLimit := viper.GetInt64("created_at_upper_limit"); eventCreatedAt > currentTime.Unix()+upperLimit {
+		errMsg := fmt.Sprintf("event creation date is more than %d seconds in the future (%s)", upperLimit, eventTime)
 		return false, errMsg
 	}
 
@@ -160,6 +237,27 @@ func CloseStream(stream network.Stream) {
 	}
 }
 
+// Sign an event with the relay private key so the relay can publish events of its own
+// The pubkey, id and signature of the event are all overwritten
+func SignEventWithRelayKey(event *nostr.Event) error {
+	privateKey, _, err := signing.DeserializePrivateKey(viper.GetString("private_key"))
+	if err != nil {
+		return fmt.Errorf("failed to deserialize relay private key: %v", err)
+	}
+
+	return event.Sign(hex.EncodeToString(privateKey.Serialize()))
+}
+
+// RelayPubkey returns the hex nostr pubkey of the relay private key, events signed by the relay carry it
+func RelayPubkey() string {
+	_, publicKey, err := signing.DeserializePrivateKey(viper.GetString("private_key"))
+	if err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(publicKey.SerializeCompressed()[1:])
+}
+
 func LoadRelaySettings() (*types.RelaySettings, error) {
 	viper.SetConfigName("config") // Name of config file (without extension)
 	viper.SetConfigType("json")   // Type of the config file
@@ -219,5 +317,59 @@ func IsKindBlocked(kind int, settings *types.RelaySettings) bool {
 		return false
 	}
 
-	return true // Default: allow all kinds if mode is not specified
+	// Smart mode lists the kinds it allows instead
+	return false
+}
+
+// Validate a NIP-98 http auth header ("Nostr <base64 encoded event>") for the given url and method
+// The payload tag is only checked when present as it is optional for requests with a body
+func ValidateHttpAuth(header string, url string, method string, body []byte) (*nostr.Event, error) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+	encoded, found := strings.CutPrefix(header, "Nostr ")
+	if !found {
+		return nil, fmt.Errorf("missing nostr authorization header")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("authorization header is not valid base64")
+	}
+
+	var event nostr.Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("authorization header does not contain a valid event")
+	}
+
+	if event.Kind != 27235 {
+		return nil, fmt.Errorf("authorization event must be kind 27235")
+	}
+
+	// Auth events are only valid for a short window either side of the current time
+	difference := time.Now().Unix() - event.CreatedAt.Time().Unix()
+	if difference > 60 || difference < -60 {
+		return nil, fmt.Errorf("authorization event is outside of the allowed time window")
+	}
+
+	if tag := event.Tags.GetFirst([]string{"u", ""}); tag == nil || strings.TrimSuffix(tag.Value(), "/") != strings.TrimSuffix(url, "/") {
+		return nil, fmt.Errorf("authorization event url does not match the request")
+	}
+
+	if tag := event.Tags.GetFirst([]string{"method", ""}); tag == nil || !strings.EqualFold(tag.Value(), method) {
+		return nil, fmt.Errorf("authorization event method does not match the request")
+	}
+
+	if tag := event.Tags.GetFirst([]string{"payload", ""}); tag != nil {
+		hash := sha256.Sum256(body)
+		if tag.Value() != hex.EncodeToString(hash[:]) {
+			return nil, fmt.Errorf("authorization event payload does not match the request body")
+		}
+	}
+
+	success, err := event.CheckSignature()
+	if err != nil || !success {
+		return nil, fmt.Errorf("authorization event signature failed to verify")
+	}
+
+	return &event, nil
 }
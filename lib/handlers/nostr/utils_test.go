@@ -0,0 +1,41 @@
+package nostr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+)
+
+func TestCheckCreatedAt(t *testing.T) {
+	viper.Set("created_at_upper_limit", 900)
+	viper.Set("created_at_lower_limit", 86400)
+	defer viper.Set("created_at_upper_limit", nil)
+	defer viper.Set("created_at_lower_limit", nil)
+
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name      string
+		kind      int
+		createdAt int64
+		valid     bool
+	}{
+		{"now", 1, now, true},
+		{"within clock drift", 1, now + 600, true},
+		{"too far in the future", 1, now + 3600, false},
+		{"too far in the past", 1, now - 2*86400, false},
+		{"backdated gift wrap", 1059, now - 2*86400, true},
+		{"gift wrap from the future", 1059, now + 3600, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckCreatedAt(&nostr.Event{Kind: test.kind, CreatedAt: nostr.Timestamp(test.createdAt)})
+			if (err == nil) != test.valid {
+				t.Errorf("expected valid %v, got error %v", test.valid, err)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/libp2p/go-libp2p/core/network"
 
 	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/blocklist"
 	utils "github.com/HORNET-Storage/hornet-storage/lib/handlers/scionic"
 	"github.com/HORNET-Storage/hornet-storage/lib/sessions/libp2p/middleware"
 	stores "github.com/HORNET-Storage/hornet-storage/lib/stores"
@@ -93,6 +94,13 @@ func BuildUploadStreamHandler(store stores.Store, canUploadDag func(rootLeaf *me
 			return
 		}
 
+		// Refuse leaves (or leaf content) that have been blocked by the relay operator before any store sees them
+		err = blocklist.CheckLeaf(store.GetStatsStore(), &message.Leaf)
+		if err != nil {
+			write(utils.BuildErrorMessage("Not allowed to upload this", err))
+			return
+		}
+
 		rootData := &types.DagLeafData{
 			PublicKey: message.PublicKey,
 			Signature: message.Signature,
@@ -142,6 +150,12 @@ func BuildUploadStreamHandler(store stores.Store, canUploadDag func(rootLeaf *me
 				}
 			}
 
+			err = blocklist.CheckLeaf(store.GetStatsStore(), &message.Leaf)
+			if err != nil {
+				write(utils.BuildErrorMessage("Not allowed to upload this", err))
+				return
+			}
+
 			data := &types.DagLeafData{
 				Leaf: message.Leaf,
 			}
@@ -0,0 +1,311 @@
+package moderation
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+const (
+	ListBannedPubkey  = "banned_pubkey"
+	ListAllowedPubkey = "allowed_pubkey"
+	ListBannedEvent   = "banned_event"
+	ListBlockedIP     = "blocked_ip"
+)
+
+// CheckEvent returns an error if the event or its author has been banned, when restrict_to_allowed_pubkeys
+// is enabled only pubkeys on the allowed list can publish
+func CheckEvent(stats stores.StatisticsStore, event *nostr.Event) error {
+	if stats == nil {
+		return nil
+	}
+
+	banned, err := stats.IsModerated(ListBannedEvent, event.ID)
+	if err != nil {
+		return fmt.Errorf("error: failed to check moderation lists")
+	}
+	if banned {
+		return fmt.Errorf("blocked: this event has been banned")
+	}
+
+	banned, err = stats.IsModerated(ListBannedPubkey, event.PubKey)
+	if err != nil {
+		return fmt.Errorf("error: failed to check moderation lists")
+	}
+	if banned {
+		return fmt.Errorf("blocked: this pubkey has been banned")
+	}
+
+	if viper.GetBool("restrict_to_allowed_pubkeys") {
+		allowed, err := stats.IsModerated(ListAllowedPubkey, event.PubKey)
+		if err != nil {
+			return fmt.Errorf("error: failed to check moderation lists")
+		}
+		if !allowed {
+			return fmt.Errorf("restricted: this pubkey is not allowed to publish to this relay")
+		}
+	}
+
+	return nil
+}
+
+// FilterEvents removes banned events and events from banned pubkeys, the lists come from the cache
+// so a whole query result can be filtered without a lookup per event
+func FilterEvents(stats stores.StatisticsStore, events []*nostr.Event) []*nostr.Event {
+	if stats == nil || len(events) == 0 {
+		return events
+	}
+
+	content := loadModeratedContent(stats)
+
+	if len(content.bannedPubkeys) == 0 && len(content.bannedEvents) == 0 {
+		return events
+	}
+
+	filtered := []*nostr.Event{}
+	for _, event := range events {
+		if content.bannedPubkeys[event.PubKey] || content.bannedEvents[event.ID] {
+			continue
+		}
+
+		filtered = append(filtered, event)
+	}
+
+	return filtered
+}
+
+// Excluded returns the pubkeys and event ids whose events are left out of query results, either banned or
+// hidden pending review, so totals kept elsewhere can take the few moderated events off
+func Excluded(stats stores.StatisticsStore) ([]string, []string) {
+	if stats == nil {
+		return nil, nil
+	}
+
+	content := loadModeratedContent(stats)
+
+	pubkeys := map[string]bool{}
+	eventIDs := map[string]bool{}
+	for _, values := range []map[string]bool{content.bannedPubkeys, content.hiddenPubkeys} {
+		for value := range values {
+			pubkeys[value] = true
+		}
+	}
+	for _, values := range []map[string]bool{content.bannedEvents, content.hiddenEvents} {
+		for value := range values {
+			eventIDs[value] = true
+		}
+	}
+
+	return sortedValues(pubkeys), sortedValues(eventIDs)
+}
+
+// BuildQueryFilter leaves banned content and content hidden pending review out of query results, registered as a
+// query filter so REQ, COUNT and live subscriptions all apply it
+func BuildQueryFilter(stats stores.StatisticsStore) func(events []*nostr.Event) []*nostr.Event {
+	return func(events []*nostr.Event) []*nostr.Event {
+		return FilterReported(stats, FilterEvents(stats, events))
+	}
+}
+
+// Every query result is filtered with the banned lists and the hidden report targets so they're cached,
+// the cache is dropped whenever one of the lists or the status of a report target changes
+// The generation is bumped when the cache is dropped so content loaded before a change is never cached after it
+type moderatedContent struct {
+	stats         stores.StatisticsStore
+	bannedPubkeys map[string]bool
+	bannedEvents  map[string]bool
+	hiddenPubkeys map[string]bool
+	hiddenEvents  map[string]bool
+}
+
+var (
+	moderated           *moderatedContent
+	moderatedGeneration int
+	moderatedMutex      sync.RWMutex
+)
+
+func loadModeratedContent(stats stores.StatisticsStore) *moderatedContent {
+	moderatedMutex.RLock()
+	content, generation := moderated, moderatedGeneration
+	moderatedMutex.RUnlock()
+
+	if content != nil && content.stats == stats {
+		return content
+	}
+
+	content = &moderatedContent{
+		stats:         stats,
+		hiddenPubkeys: map[string]bool{},
+		hiddenEvents:  map[string]bool{},
+	}
+
+	bannedPubkeys, pubkeysErr := loadList(stats, ListBannedPubkey)
+	bannedEvents, eventsErr := loadList(stats, ListBannedEvent)
+	hidden, hiddenErr := stats.GetReportTargets(StatusHidden)
+
+	content.bannedPubkeys, content.bannedEvents = bannedPubkeys, bannedEvents
+	for _, target := range hidden {
+		if target.TargetType == TargetEvent {
+			content.hiddenEvents[target.Target] = true
+		} else {
+			content.hiddenPubkeys[target.Target] = true
+		}
+	}
+
+	// Whatever could be loaded is still applied but the lists are loaded again next time
+	if pubkeysErr != nil || eventsErr != nil || hiddenErr != nil {
+		return content
+	}
+
+	moderatedMutex.Lock()
+	if moderatedGeneration == generation {
+		moderated = content
+	}
+	moderatedMutex.Unlock()
+
+	return content
+}
+
+func clearModeratedContent() {
+	moderatedMutex.Lock()
+	moderated = nil
+	moderatedGeneration++
+	moderatedMutex.Unlock()
+}
+
+// Blocked ips are checked for every message an open connection sends so the list is cached,
+// the cache is dropped whenever the list changes
+var (
+	blockedIPs      map[string]bool
+	blockedIPsMutex sync.RWMutex
+)
+
+// IsIPBlocked reports if connections from the ip should be refused
+func IsIPBlocked(stats stores.StatisticsStore, ip string) bool {
+	if stats == nil {
+		return false
+	}
+
+	blockedIPsMutex.RLock()
+	blocked := blockedIPs
+	blockedIPsMutex.RUnlock()
+
+	if blocked == nil {
+		entries, err := stats.GetModerationEntries(ListBlockedIP)
+		if err != nil {
+			return false
+		}
+
+		blocked = map[string]bool{}
+		for _, entry := range entries {
+			blocked[entry.Value] = true
+		}
+
+		blockedIPsMutex.Lock()
+		blockedIPs = blocked
+		blockedIPsMutex.Unlock()
+	}
+
+	// Blocked ips are stored in their canonical form
+	if parsed := net.ParseIP(ip); parsed != nil {
+		ip = parsed.String()
+	}
+
+	return blocked[ip]
+}
+
+func clearBlockedIPs(list string) {
+	if list != ListBlockedIP {
+		return
+	}
+
+	blockedIPsMutex.Lock()
+	blockedIPs = nil
+	blockedIPsMutex.Unlock()
+}
+
+// Add puts a value on a management list
+func Add(stats stores.StatisticsStore, list string, value string, reason string) error {
+	value, err := normalizeValue(list, value)
+	if err != nil {
+		return err
+	}
+
+	defer clearBlockedIPs(list)
+	defer clearModeratedContent()
+
+	return stats.AddModerationEntry(&types.ModerationEntry{
+		List:   list,
+		Value:  value,
+		Reason: reason,
+	})
+}
+
+// Remove takes a value off a management list
+func Remove(stats stores.StatisticsStore, list string, value string) error {
+	value, err := normalizeValue(list, value)
+	if err != nil {
+		return err
+	}
+
+	defer clearBlockedIPs(list)
+	defer clearModeratedContent()
+
+	return stats.RemoveModerationEntry(list, value)
+}
+
+func loadList(stats stores.StatisticsStore, list string) (map[string]bool, error) {
+	values := map[string]bool{}
+
+	entries, err := stats.GetModerationEntries(list)
+	if err != nil {
+		return values, err
+	}
+
+	for _, entry := range entries {
+		values[entry.Value] = true
+	}
+
+	return values, nil
+}
+
+func sortedValues(values map[string]bool) []string {
+	sorted := make([]string, 0, len(values))
+	for value := range values {
+		sorted = append(sorted, value)
+	}
+	slices.Sort(sorted)
+
+	return sorted
+}
+
+// Pubkeys and event ids are stored as lower case hex so lookups match what's on the events
+func normalizeValue(list string, value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	switch list {
+	case ListBannedPubkey, ListAllowedPubkey, ListBannedEvent:
+		value = strings.ToLower(value)
+		if !nostr.IsValid32ByteHex(value) {
+			return "", fmt.Errorf("invalid hex value: %s", value)
+		}
+	case ListBlockedIP:
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return "", fmt.Errorf("invalid ip address: %s", value)
+		}
+		value = ip.String()
+	default:
+		return "", fmt.Errorf("unknown moderation list: %s", list)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,89 @@
+package moderation
+
+import (
+	"testing"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Only the management lists of the statistics store are implemented
+type listStats struct {
+	stores.StatisticsStore
+
+	entries map[string]map[string]string
+	loads   int
+}
+
+func newStats() *listStats {
+	return &listStats{entries: map[string]map[string]string{}}
+}
+
+func (stats *listStats) AddModerationEntry(entry *types.ModerationEntry) error {
+	if stats.entries[entry.List] == nil {
+		stats.entries[entry.List] = map[string]string{}
+	}
+
+	stats.entries[entry.List][entry.Value] = entry.Reason
+	return nil
+}
+
+func (stats *listStats) RemoveModerationEntry(list string, value string) error {
+	delete(stats.entries[list], value)
+	return nil
+}
+
+func (stats *listStats) IsModerated(list string, value string) (bool, error) {
+	_, ok := stats.entries[list][value]
+	return ok, nil
+}
+
+func (stats *listStats) GetModerationEntries(list string) ([]types.ModerationEntry, error) {
+	stats.loads++
+
+	entries := []types.ModerationEntry{}
+	for value, reason := range stats.entries[list] {
+		entries = append(entries, types.ModerationEntry{List: list, Value: value, Reason: reason})
+	}
+
+	return entries, nil
+}
+
+func TestIsIPBlocked(t *testing.T) {
+	stats := newStats()
+	clearBlockedIPs(ListBlockedIP)
+
+	steps := []struct {
+		name    string
+		change  func() error
+		ip      string
+		blocked bool
+		loads   int
+	}{
+		{"nothing blocked", nil, "10.0.0.1", false, 1},
+		{"cached", nil, "10.0.0.1", false, 1},
+		{"blocked", func() error { return Add(stats, ListBlockedIP, "10.0.0.1", "spam") }, "10.0.0.1", true, 2},
+		{"other ip", nil, "10.0.0.2", false, 2},
+		{"ipv6 in another form", func() error { return Add(stats, ListBlockedIP, "2001:db8::1", "") }, "2001:0db8:0000::1", true, 3},
+		{"other lists keep the cache", func() error { return Add(stats, ListBannedEvent, "aa", "") }, "10.0.0.1", true, 3},
+		{"unblocked", func() error { return Remove(stats, ListBlockedIP, "10.0.0.1") }, "10.0.0.1", false, 4},
+	}
+
+	for _, step := range steps {
+		if step.change != nil {
+			step.change()
+		}
+
+		if blocked := IsIPBlocked(stats, step.ip); blocked != step.blocked {
+			t.Errorf("%s: expected blocked %v, got %v", step.name, step.blocked, blocked)
+		}
+
+		if stats.loads != step.loads {
+			t.Errorf("%s: expected the list to be loaded %d times, got %d", step.name, step.loads, stats.loads)
+		}
+	}
+
+	if IsIPBlocked(nil, "10.0.0.1") {
+		t.Errorf("expected a store without moderation lists to block nothing")
+	}
+}
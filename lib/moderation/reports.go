@@ -0,0 +1,307 @@
+package moderation
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/aggregates"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+const (
+	TargetEvent  = "event"
+	TargetPubkey = "pubkey"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusHidden    = "hidden"
+	StatusAccepted  = "accepted"
+	StatusDismissed = "dismissed"
+)
+
+// Actions that can be taken when accepting the reports against a target
+const (
+	ActionDeleteEvent = "delete_event" // Delete and ban the event along with any blobs it references
+	ActionBanPubkey   = "ban_pubkey"   // Ban the reported pubkey or the author of the reported event
+)
+
+// RecordReport adds a kind 1984 report to the moderation queue, reports against an event are counted towards
+// the event only so a single note doesn't hide everything its author has published
+func RecordReport(store stores.Store, report *nostr.Event) error {
+	stats := store.GetStatsStore()
+	if stats == nil {
+		return nil
+	}
+
+	weight := reportWeight(store, report.PubKey)
+
+	var eventReports, pubkeyReports []types.Report
+	for _, tag := range report.Tags {
+		if len(tag) < 2 || (tag[0] != "e" && tag[0] != "p") {
+			continue
+		}
+
+		target, err := normalizeValue(ListBannedEvent, tag[1])
+		if err != nil {
+			continue
+		}
+
+		entry := types.Report{
+			ReportID:   report.ID,
+			TargetType: TargetPubkey,
+			Target:     target,
+			Reporter:   report.PubKey,
+			ReportType: "other",
+			Reason:     report.Content,
+			Weight:     weight,
+		}
+
+		if len(tag) >= 3 && tag[2] != "" {
+			entry.ReportType = tag[2]
+		}
+
+		if tag[0] == "e" {
+			entry.TargetType = TargetEvent
+			eventReports = append(eventReports, entry)
+		} else {
+			pubkeyReports = append(pubkeyReports, entry)
+		}
+	}
+
+	reports := pubkeyReports
+	if len(eventReports) > 0 {
+		reports = eventReports
+	}
+
+	for _, entry := range reports {
+		if err := stats.SaveReport(&entry); err != nil {
+			return fmt.Errorf("failed to save report: %v", err)
+		}
+
+		if err := updateReportTarget(stats, entry.TargetType, entry.Target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FilterReported removes events that are hidden pending review, either directly or because their author is
+func FilterReported(stats stores.StatisticsStore, events []*nostr.Event) []*nostr.Event {
+	if stats == nil || len(events) == 0 {
+		return events
+	}
+
+	content := loadModeratedContent(stats)
+
+	if len(content.hiddenPubkeys) == 0 && len(content.hiddenEvents) == 0 {
+		return events
+	}
+
+	filtered := []*nostr.Event{}
+	for _, event := range events {
+		if content.hiddenEvents[event.ID] || content.hiddenPubkeys[event.PubKey] {
+			continue
+		}
+
+		filtered = append(filtered, event)
+	}
+
+	return filtered
+}
+
+// AcceptReports closes the reports against a target by taking the given action
+func AcceptReports(store stores.Store, targetType string, target string, action string) error {
+	stats := store.GetStatsStore()
+
+	reportTarget, err := stats.GetReportTarget(targetType, target)
+	if err != nil {
+		return fmt.Errorf("no reports found for %s %s", targetType, target)
+	}
+
+	switch action {
+	case ActionDeleteEvent:
+		if targetType != TargetEvent {
+			return fmt.Errorf("only reported events can be deleted")
+		}
+
+		if err := deleteReportedEvent(store, target); err != nil {
+			return err
+		}
+	case ActionBanPubkey:
+		pubkey := target
+		if targetType == TargetEvent {
+			pubkey = ""
+
+			events, err := store.QueryEvents(nostr.Filter{IDs: []string{target}})
+			if err == nil {
+				for _, event := range events {
+					if event.ID == target {
+						pubkey = event.PubKey
+					}
+				}
+			}
+
+			if pubkey == "" {
+				return fmt.Errorf("reported event not found so its author can't be banned")
+			}
+		}
+
+		if err := Add(stats, ListBannedPubkey, pubkey, reportReason(reportTarget)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown moderation action: %s", action)
+	}
+
+	reportTarget.Status = StatusAccepted
+	reportTarget.Action = action
+
+	defer clearModeratedContent()
+
+	return stats.SaveReportTarget(reportTarget)
+}
+
+// DismissReports closes the reports against a target without taking action, hidden content is served again
+func DismissReports(stats stores.StatisticsStore, targetType string, target string) error {
+	reportTarget, err := stats.GetReportTarget(targetType, target)
+	if err != nil {
+		return fmt.Errorf("no reports found for %s %s", targetType, target)
+	}
+
+	reportTarget.Status = StatusDismissed
+	reportTarget.Action = ""
+
+	defer clearModeratedContent()
+
+	return stats.SaveReportTarget(reportTarget)
+}
+
+// The aggregate is rebuilt from the individual reports so a reporter changing their report isn't counted twice
+// Targets are hidden once they pass the threshold but reviewed targets keep their status
+func updateReportTarget(stats stores.StatisticsStore, targetType string, target string) error {
+	reports, err := stats.GetReports(targetType, target)
+	if err != nil {
+		return fmt.Errorf("failed to load reports: %v", err)
+	}
+
+	reportTarget, err := stats.GetReportTarget(targetType, target)
+	if err != nil {
+		reportTarget = &types.ReportTarget{
+			TargetType: targetType,
+			Target:     target,
+			Status:     StatusPending,
+		}
+	}
+
+	reportTarget.Score = 0
+	reportTarget.ReportCount = len(reports)
+	reportTarget.ReportTypes = map[string]int{}
+	for _, report := range reports {
+		reportTarget.Score += report.Weight
+		reportTarget.ReportTypes[report.ReportType]++
+	}
+
+	threshold := viper.GetFloat64("report_hide_threshold")
+	if reportTarget.Status == StatusPending && threshold > 0 && reportTarget.Score >= threshold {
+		reportTarget.Status = StatusHidden
+		defer clearModeratedContent()
+	}
+
+	return stats.SaveReportTarget(reportTarget)
+}
+
+// Reports from trusted pubkeys and relay admins count for more than reports from subscribers, anyone else can
+// report but their reports are only there for review as generating keys is free and would let anyone hide anything
+func reportWeight(store stores.Store, pubkey string) float64 {
+	if slices.Contains(viper.GetStringSlice("report_trusted_pubkeys"), pubkey) || slices.Contains(viper.GetStringSlice("admin_pubkeys"), pubkey) {
+		return viper.GetFloat64("report_trusted_weight")
+	}
+
+	subscriber, err := store.GetSubscriber(pubkey)
+	if err != nil || subscriber.Tier == "" || !time.Now().Before(subscriber.EndDate) {
+		return 0
+	}
+
+	return 1
+}
+
+// The event is banned as well as deleted so it can't simply be published again
+func deleteReportedEvent(store stores.Store, id string) error {
+	events, err := store.QueryEvents(nostr.Filter{IDs: []string{id}})
+	if err != nil {
+		return fmt.Errorf("failed to look up the reported event: %v", err)
+	}
+
+	for _, event := range events {
+		if event.ID != id {
+			continue
+		}
+
+		// Only uploads of the author go with the event, a blob referenced by someone else's event is theirs to keep
+		for _, hash := range blobHashes(event) {
+			if err := store.DeleteBlob(hash, event.PubKey); err != nil {
+				log.Printf("Blob %s referenced by reported event %s could not be deleted: %v", hash, id, err)
+			}
+		}
+	}
+
+	reportTarget, _ := store.GetStatsStore().GetReportTarget(TargetEvent, id)
+	if err := Add(store.GetStatsStore(), ListBannedEvent, id, reportReason(reportTarget)); err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		if event.ID != id {
+			continue
+		}
+
+		if err := aggregates.DeleteEvent(store, event); err != nil {
+			log.Printf("Reported event %s could not be deleted: %v", id, err)
+		}
+	}
+
+	return nil
+}
+
+// Blobs are referenced by x tags on file metadata events and x entries in imeta tags
+func blobHashes(event *nostr.Event) []string {
+	var hashes []string
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "x" {
+			hashes = append(hashes, tag[1])
+		}
+
+		if len(tag) >= 2 && tag[0] == "imeta" {
+			for _, entry := range tag[1:] {
+				if hash, found := strings.CutPrefix(entry, "x "); found {
+					hashes = append(hashes, hash)
+				}
+			}
+		}
+	}
+
+	return hashes
+}
+
+func reportReason(reportTarget *types.ReportTarget) string {
+	if reportTarget == nil {
+		return "reported"
+	}
+
+	var reportTypes []string
+	for reportType := range reportTarget.ReportTypes {
+		reportTypes = append(reportTypes, reportType)
+	}
+	slices.Sort(reportTypes)
+
+	return fmt.Sprintf("reported: %s", strings.Join(reportTypes, ", "))
+}
@@ -0,0 +1,278 @@
+package moderation
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Adds the report queue to the management lists
+type reportStats struct {
+	*listStats
+
+	reports     map[string]types.Report
+	targets     map[string]types.ReportTarget
+	targetLoads int
+}
+
+func newReportStats() *reportStats {
+	return &reportStats{listStats: newStats(), reports: map[string]types.Report{}, targets: map[string]types.ReportTarget{}}
+}
+
+func (stats *reportStats) SaveReport(report *types.Report) error {
+	stats.reports[report.TargetType+report.Target+report.Reporter] = *report
+	return nil
+}
+
+func (stats *reportStats) GetReports(targetType string, target string) ([]types.Report, error) {
+	reports := []types.Report{}
+	for _, report := range stats.reports {
+		if report.TargetType == targetType && report.Target == target {
+			reports = append(reports, report)
+		}
+	}
+
+	return reports, nil
+}
+
+func (stats *reportStats) SaveReportTarget(target *types.ReportTarget) error {
+	stats.targets[target.TargetType+target.Target] = *target
+	return nil
+}
+
+func (stats *reportStats) GetReportTarget(targetType string, target string) (*types.ReportTarget, error) {
+	reportTarget, ok := stats.targets[targetType+target]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+
+	return &reportTarget, nil
+}
+
+func (stats *reportStats) GetReportTargets(status string) ([]types.ReportTarget, error) {
+	stats.targetLoads++
+
+	targets := []types.ReportTarget{}
+	for _, target := range stats.targets {
+		if status == "" || target.Status == status {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets, nil
+}
+
+// Only the parts of the store used by the moderation queue are implemented
+type reportStore struct {
+	stores.Store
+
+	stats        *reportStats
+	subscribers  map[string]*types.Subscriber
+	events       []*nostr.Event
+	deletedBlobs []string
+}
+
+func (store *reportStore) GetStatsStore() stores.StatisticsStore {
+	return store.stats
+}
+
+func (store *reportStore) GetSubscriber(npub string) (*types.Subscriber, error) {
+	subscriber, ok := store.subscribers[npub]
+	if !ok {
+		return nil, fmt.Errorf("subscriber not found for npub: %s", npub)
+	}
+
+	return subscriber, nil
+}
+
+func (store *reportStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	events := []*nostr.Event{}
+	for _, event := range store.events {
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (store *reportStore) DeleteEvent(id string) error {
+	return nil
+}
+
+func (store *reportStore) DeleteBlob(hash string, publicKey string) error {
+	store.deletedBlobs = append(store.deletedBlobs, hash+":"+publicKey)
+	return nil
+}
+
+func hexKey(character string) string {
+	return strings.Repeat(character, 64)
+}
+
+func TestRecordReport(t *testing.T) {
+	viper.Set("report_hide_threshold", 5)
+	viper.Set("report_trusted_pubkeys", []string{hexKey("7")})
+	viper.Set("report_trusted_weight", 5)
+	defer viper.Set("report_hide_threshold", nil)
+	defer viper.Set("report_trusted_pubkeys", nil)
+	defer viper.Set("report_trusted_weight", nil)
+
+	subscribers := map[string]*types.Subscriber{
+		hexKey("1"): {Npub: hexKey("1"), Tier: "1 GB per month", EndDate: time.Now().Add(time.Hour)},
+		hexKey("2"): {Npub: hexKey("2"), Tier: "1 GB per month", EndDate: time.Now().Add(-time.Hour)},
+	}
+
+	tests := []struct {
+		name      string
+		reporters []string
+		score     float64
+		status    string
+	}{
+		{"subscriber", []string{hexKey("1")}, 1, StatusPending},
+		{"expired subscriber", []string{hexKey("2")}, 0, StatusPending},
+		{"anyone else", []string{hexKey("3")}, 0, StatusPending},
+		{"many keys that aren't subscribers", []string{hexKey("3"), hexKey("4"), hexKey("5"), hexKey("6"), hexKey("8"), hexKey("9")}, 0, StatusPending},
+		{"the same subscriber twice", []string{hexKey("1"), hexKey("1")}, 1, StatusPending},
+		{"trusted pubkey", []string{hexKey("7")}, 5, StatusHidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &reportStore{stats: newReportStats(), subscribers: subscribers}
+			target := hexKey("e")
+
+			for _, reporter := range test.reporters {
+				report := &nostr.Event{ID: hexKey("a"), Kind: 1984, PubKey: reporter, Tags: nostr.Tags{{"e", target, "spam"}, {"p", hexKey("f")}}}
+				if err := RecordReport(store, report); err != nil {
+					t.Fatalf("failed to record report: %v", err)
+				}
+			}
+
+			reportTarget, err := store.stats.GetReportTarget(TargetEvent, target)
+			if err != nil {
+				t.Fatalf("expected the event to be in the queue: %v", err)
+			}
+
+			if reportTarget.Score != test.score || reportTarget.Status != test.status {
+				t.Errorf("expected score %v and status %s, got %v and %s", test.score, test.status, reportTarget.Score, reportTarget.Status)
+			}
+
+			// Reports against an event aren't counted against its author
+			if _, err := store.stats.GetReportTarget(TargetPubkey, hexKey("f")); err == nil {
+				t.Errorf("expected the author not to be in the queue")
+			}
+		})
+	}
+}
+
+func TestBuildQueryFilter(t *testing.T) {
+	stats := newReportStats()
+	Add(stats, ListBannedPubkey, hexKey("1"), "")
+	Add(stats, ListBannedEvent, hexKey("a"), "")
+	stats.SaveReportTarget(&types.ReportTarget{TargetType: TargetPubkey, Target: hexKey("2"), Status: StatusHidden})
+	stats.SaveReportTarget(&types.ReportTarget{TargetType: TargetEvent, Target: hexKey("b"), Status: StatusHidden})
+	stats.SaveReportTarget(&types.ReportTarget{TargetType: TargetEvent, Target: hexKey("c"), Status: StatusDismissed})
+
+	tests := []struct {
+		name    string
+		event   *nostr.Event
+		visible bool
+	}{
+		{"unmoderated", &nostr.Event{ID: hexKey("d"), PubKey: hexKey("3")}, true},
+		{"banned pubkey", &nostr.Event{ID: hexKey("e"), PubKey: hexKey("1")}, false},
+		{"banned event", &nostr.Event{ID: hexKey("a"), PubKey: hexKey("3")}, false},
+		{"hidden pubkey", &nostr.Event{ID: hexKey("f"), PubKey: hexKey("2")}, false},
+		{"hidden event", &nostr.Event{ID: hexKey("b"), PubKey: hexKey("3")}, false},
+		{"dismissed reports", &nostr.Event{ID: hexKey("c"), PubKey: hexKey("3")}, true},
+	}
+
+	filter := BuildQueryFilter(stats)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if visible := len(filter([]*nostr.Event{test.event})) == 1; visible != test.visible {
+				t.Errorf("expected visible %v, got %v", test.visible, visible)
+			}
+		})
+	}
+}
+
+func TestBuildQueryFilterCachesModeratedContent(t *testing.T) {
+	viper.Set("report_hide_threshold", 5)
+	viper.Set("report_trusted_pubkeys", []string{hexKey("7")})
+	viper.Set("report_trusted_weight", 5)
+	defer viper.Set("report_hide_threshold", nil)
+	defer viper.Set("report_trusted_pubkeys", nil)
+	defer viper.Set("report_trusted_weight", nil)
+
+	store := &reportStore{stats: newReportStats()}
+	stats := store.stats
+	event := &nostr.Event{ID: hexKey("a"), PubKey: hexKey("1")}
+	report := &nostr.Event{ID: hexKey("b"), Kind: 1984, PubKey: hexKey("7"), Tags: nostr.Tags{{"e", event.ID, "spam"}}}
+
+	steps := []struct {
+		name    string
+		change  func() error
+		visible bool
+		loads   int
+	}{
+		{"nothing moderated", nil, true, 1},
+		{"cached", nil, true, 1},
+		{"banned", func() error { return Add(stats, ListBannedPubkey, event.PubKey, "") }, false, 2},
+		{"unbanned", func() error { return Remove(stats, ListBannedPubkey, event.PubKey) }, true, 3},
+		{"hidden by a report", func() error { return RecordReport(store, report) }, false, 4},
+		{"still hidden", nil, false, 4},
+		{"reports dismissed", func() error { return DismissReports(stats, TargetEvent, event.ID) }, true, 5},
+	}
+
+	filter := BuildQueryFilter(stats)
+
+	for _, step := range steps {
+		if step.change != nil {
+			if err := step.change(); err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+		}
+
+		if visible := len(filter([]*nostr.Event{event})) == 1; visible != step.visible {
+			t.Errorf("%s: expected visible %v, got %v", step.name, step.visible, visible)
+		}
+
+		// Each load reads the two banned lists and the hidden report targets
+		if stats.loads != step.loads*2 || stats.targetLoads != step.loads {
+			t.Errorf("%s: expected the moderated content to be loaded %d times, got %d", step.name, step.loads, stats.targetLoads)
+		}
+	}
+}
+
+func TestAcceptReportsDeletesOnlyTheAuthorsBlobs(t *testing.T) {
+	author := hexKey("1")
+	event := &nostr.Event{ID: hexKey("a"), PubKey: author, Tags: nostr.Tags{{"x", "blob"}, {"imeta", "url https://relay/media", "x media"}}}
+
+	store := &reportStore{stats: newReportStats(), events: []*nostr.Event{event}}
+	store.stats.SaveReportTarget(&types.ReportTarget{TargetType: TargetEvent, Target: event.ID, Status: StatusHidden})
+
+	if err := AcceptReports(store, TargetEvent, event.ID, ActionDeleteEvent); err != nil {
+		t.Fatalf("failed to accept reports: %v", err)
+	}
+
+	expected := []string{"blob:" + author, "media:" + author}
+	if strings.Join(store.deletedBlobs, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v deleted, got %v", expected, store.deletedBlobs)
+	}
+
+	if banned, _ := store.stats.IsModerated(ListBannedEvent, event.ID); !banned {
+		t.Errorf("expected the event to be banned")
+	}
+
+	if reportTarget, _ := store.stats.GetReportTarget(TargetEvent, event.ID); reportTarget.Status != StatusAccepted {
+		t.Errorf("expected the reports to be accepted, got %s", reportTarget.Status)
+	}
+}
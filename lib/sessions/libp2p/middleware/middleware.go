@@ -5,6 +5,7 @@ import (
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
+	manet "github.com/multiformats/go-multiaddr/net"
 )
 
 type PeerInfo struct {
@@ -13,10 +14,25 @@ type PeerInfo struct {
 	PublicKey crypto.PubKey
 }
 
+var connectionFilter func(ip string) bool
+
+// SetConnectionFilter sets the check streams go through before reaching their handler, streams from
+// ips the filter reports as blocked are reset
+func SetConnectionFilter(filter func(ip string) bool) {
+	connectionFilter = filter
+}
+
 // Examples on how to extract data from libp2p stream but not needed right now
 func SessionMiddleware(h host.Host) func(network.StreamHandler) network.StreamHandler {
 	return func(next network.StreamHandler) network.StreamHandler {
 		return func(stream network.Stream) {
+			if connectionFilter != nil {
+				if ip, err := manet.ToIP(stream.Conn().RemoteMultiaddr()); err == nil && connectionFilter(ip.String()) {
+					stream.Reset()
+					return
+				}
+			}
+
 			// Get remote peer
 			/*
 				remotePeer := stream.Conn().RemotePeer()
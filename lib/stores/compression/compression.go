@@ -0,0 +1,117 @@
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/spf13/viper"
+)
+
+// Every value written to a compressed bucket starts with a small header so the format can be detected on read
+// Values written before compression was enabled have no header and are returned as they are, JSON events can
+// never start with the header and raw values that happen to are always written with a header so reads stay unambiguous
+var magic = []byte{0x00, 'H', 'C'}
+
+const (
+	FormatNone byte = 0x00
+	FormatZstd byte = 0x01
+)
+
+var (
+	encoder     *zstd.Encoder
+	decoder     *zstd.Decoder
+	codecsOnce  sync.Once
+	codecsError error
+)
+
+func getCodecs() (*zstd.Encoder, *zstd.Decoder, error) {
+	codecsOnce.Do(func() {
+		encoder, codecsError = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		if codecsError != nil {
+			return
+		}
+
+		decoder, codecsError = zstd.NewReader(nil)
+	})
+
+	return encoder, decoder, codecsError
+}
+
+// Enabled reports if values in the bucket should be compressed, buckets are configured with
+// compression_buckets which accepts exact bucket names or prefixes ending in * such as "kind:*"
+func Enabled(bucket string) bool {
+	for _, pattern := range viper.GetStringSlice("compression_buckets") {
+		if prefix, found := strings.CutSuffix(pattern, "*"); found {
+			if strings.HasPrefix(bucket, prefix) {
+				return true
+			}
+		} else if pattern == bucket {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Encode prepares a value for storage in the bucket, values are only kept compressed when it actually saves space
+func Encode(bucket string, data []byte) ([]byte, error) {
+	if !Enabled(bucket) || len(data) < viper.GetInt("compression_min_size") {
+		if HasHeader(data) {
+			return withHeader(FormatNone, data), nil
+		}
+
+		return data, nil
+	}
+
+	encoder, _, err := getCodecs()
+	if err != nil {
+		return nil, err
+	}
+
+	compressed := encoder.EncodeAll(data, make([]byte, 0, len(data)))
+	if len(compressed)+len(magic)+1 >= len(data) {
+		return withHeader(FormatNone, data), nil
+	}
+
+	return withHeader(FormatZstd, compressed), nil
+}
+
+// Decode returns the original value regardless of how it was stored
+func Decode(data []byte) ([]byte, error) {
+	if !HasHeader(data) {
+		return data, nil
+	}
+
+	format := data[len(magic)]
+	payload := data[len(magic)+1:]
+
+	switch format {
+	case FormatNone:
+		return payload, nil
+	case FormatZstd:
+		_, decoder, err := getCodecs()
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.DecodeAll(payload, nil)
+	}
+
+	return nil, fmt.Errorf("unknown compression format: %d", format)
+}
+
+// HasHeader reports if the value was written with a compression header
+func HasHeader(data []byte) bool {
+	return len(data) > len(magic) && bytes.Equal(data[:len(magic)], magic)
+}
+
+func withHeader(format byte, data []byte) []byte {
+	value := make([]byte, 0, len(magic)+1+len(data))
+	value = append(value, magic...)
+	value = append(value, format)
+
+	return append(value, data...)
+}
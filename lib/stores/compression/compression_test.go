@@ -0,0 +1,90 @@
+package compression
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestEnabled(t *testing.T) {
+	viper.Set("compression_buckets", []string{"kind:*", "content"})
+	defer viper.Set("compression_buckets", nil)
+
+	tests := []struct {
+		bucket  string
+		enabled bool
+	}{
+		{"kind:1", true},
+		{"kind:30023", true},
+		{"content", true},
+		{"content_refs", false},
+		{"scionic_index", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.bucket, func(t *testing.T) {
+			if enabled := Enabled(test.bucket); enabled != test.enabled {
+				t.Errorf("expected enabled %v, got %v", test.enabled, enabled)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	viper.Set("compression_buckets", []string{"kind:*"})
+	viper.Set("compression_min_size", 64)
+	defer viper.Set("compression_buckets", nil)
+	defer viper.Set("compression_min_size", nil)
+
+	event := []byte(`{"kind":1,"content":"` + strings.Repeat("hello nostr ", 100) + `"}`)
+	legacyHeader := append([]byte{0x00, 'H', 'C', FormatZstd}, []byte("not compressed")...)
+
+	tests := []struct {
+		name       string
+		bucket     string
+		data       []byte
+		compressed bool
+		header     bool
+	}{
+		{"compressible value", "kind:1", event, true, true},
+		{"bucket without compression", "kind_other", event, false, false},
+		{"value below the minimum size", "kind:1", []byte(`{"kind":1}`), false, false},
+		{"random value that doesn't shrink", "kind:1", bytes.Repeat([]byte{0x8f, 0x13, 0xa7, 0x5c}, 8), false, false},
+		{"raw value starting with the header", "kind_other", legacyHeader, false, true},
+		{"empty value", "kind:1", []byte{}, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encoded, err := Encode(test.bucket, test.data)
+			if err != nil {
+				t.Fatalf("failed to encode: %v", err)
+			}
+
+			if HasHeader(encoded) != test.header {
+				t.Errorf("expected header %v, got %v", test.header, HasHeader(encoded))
+			}
+
+			if compressed := HasHeader(encoded) && encoded[len(magic)] == FormatZstd; compressed != test.compressed {
+				t.Errorf("expected compressed %v, got %v", test.compressed, compressed)
+			}
+
+			decoded, err := Decode(encoded)
+			if err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+
+			if !bytes.Equal(decoded, test.data) {
+				t.Errorf("round trip changed the value")
+			}
+		})
+	}
+}
+
+func TestDecodeUnknownFormat(t *testing.T) {
+	if _, err := Decode([]byte{0x00, 'H', 'C', 0x7f, 0x01}); err == nil {
+		t.Errorf("expected an unknown format to be refused")
+	}
+}
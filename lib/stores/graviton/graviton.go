@@ -1,14 +1,17 @@
 package graviton
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"slices"
 	"sort"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
@@ -18,7 +21,11 @@ import (
 	"github.com/spf13/viper"
 
 	stores "github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/compression"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/refs"
 	gorm "github.com/HORNET-Storage/hornet-storage/lib/stores/stats_stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/subscribers"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/usage"
 	merkle_dag "github.com/HORNET-Storage/scionic-merkletree/dag"
 
 	jsoniter "github.com/json-iterator/go"
@@ -32,9 +39,24 @@ const (
 	AddressStatusUsed      = "used"
 )
 
+// Bumped whenever the event cache buckets change so existing events are reindexed on start
+const (
+	eventIndexVersionKey = "event_index_version"
+	eventIndexVersion    = "2"
+)
+
+// Set once the owners of the blobs and scionic merkletrees stored before owners were tracked have been recorded
+const (
+	contentRefsVersionKey = "content_refs_version"
+	contentRefsVersion    = "1"
+)
+
 type GravitonStore struct {
 	Database      *graviton.Store
 	StatsDatabase stores.StatisticsStore
+
+	// Serializes changes to the owners of blobs, scionic merkletrees and content
+	refsMutex sync.Mutex
 }
 
 func (store *GravitonStore) InitStore(basepath string, args ...interface{}) error {
@@ -67,7 +89,279 @@ func (store *GravitonStore) InitStore(basepath string, args ...interface{}) erro
 		return err
 	}
 
-	return nil
+	if err := store.migrateEventIndexes(); err != nil {
+		return err
+	}
+
+	return store.migrateContentRefs()
+}
+
+// Events stored before the d and a tag indexes existed are reindexed once
+func (store *GravitonStore) migrateEventIndexes() error {
+	snapshot, err := store.Database.LoadSnapshot(0)
+	if err != nil {
+		return err
+	}
+
+	tree, err := snapshot.GetTree("mbl")
+	if err != nil {
+		return err
+	}
+
+	version, err := tree.Get([]byte(eventIndexVersionKey))
+	if err == nil && string(version) == eventIndexVersion {
+		return nil
+	}
+
+	if err := store.dropLegacyTagIndexes(); err != nil {
+		return fmt.Errorf("failed to drop the old tag indexes: %v", err)
+	}
+
+	indexed, err := store.ReindexEvents()
+	if err != nil {
+		return fmt.Errorf("failed to reindex events: %v", err)
+	}
+
+	if indexed > 0 {
+		log.Printf("Reindexed %d events", indexed)
+	}
+
+	snapshot, err = store.Database.LoadSnapshot(0)
+	if err != nil {
+		return err
+	}
+
+	tree, err = snapshot.GetTree("mbl")
+	if err != nil {
+		return err
+	}
+
+	if err := tree.Put([]byte(eventIndexVersionKey), []byte(eventIndexVersion)); err != nil {
+		return err
+	}
+
+	_, err = graviton.Commit(tree)
+	return err
+}
+
+// The d and a tag indexes used to hold a single list of event ids per value in a shared cache bucket,
+// they are emptied and taken off the master bucket list before the events are indexed again
+func (store *GravitonStore) dropLegacyTagIndexes() error {
+	snapshot, err := store.Database.LoadSnapshot(0)
+	if err != nil {
+		return err
+	}
+
+	masterBucketListTree, err := snapshot.GetTree("mbl")
+	if err != nil {
+		return err
+	}
+
+	trees := []*graviton.Tree{}
+	for _, key := range indexedTags {
+		cacheBucket := fmt.Sprintf("cache:#%s", key)
+
+		cacheTree, err := snapshot.GetTree(cacheBucket)
+		if err != nil {
+			return err
+		}
+
+		// Collect the keys first as the tree can't be modified while the cursor is in use
+		keys := [][]byte{}
+		c := cacheTree.Cursor()
+		for k, _, err := c.First(); err == nil; k, _, err = c.Next() {
+			keys = append(keys, k)
+		}
+
+		for _, k := range keys {
+			if err := cacheTree.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		if err := removeFromMasterBucketList(masterBucketListTree, "cache", cacheBucket); err != nil {
+			return err
+		}
+
+		trees = append(trees, cacheTree)
+	}
+
+	_, err = graviton.Commit(append(trees, masterBucketListTree)...)
+	return err
+}
+
+// Blobs and scionic merkletrees stored before their owners were tracked get them from the caches once, trees are
+// owned by the public key they were uploaded with, blossom blobs by the public keys they are cached against and
+// trees converted from blobs by their blob
+func (store *GravitonStore) migrateContentRefs() error {
+	snapshot, err := store.Database.LoadSnapshot(0)
+	if err != nil {
+		return err
+	}
+
+	tree, err := snapshot.GetTree("mbl")
+	if err != nil {
+		return err
+	}
+
+	version, err := tree.Get([]byte(contentRefsVersionKey))
+	if err == nil && string(version) == contentRefsVersion {
+		return nil
+	}
+
+	indexTree, err := snapshot.GetTree("scionic_index")
+	if err != nil {
+		return err
+	}
+
+	roots := map[string]string{}
+	c := indexTree.Cursor()
+	for k, v, err := c.First(); err == nil; k, v, err = c.Next() {
+		roots[string(k)] = string(v)
+	}
+
+	for root, bucket := range roots {
+		if err := store.migrateDagRefs(root, bucket); err != nil {
+			return fmt.Errorf("failed to record the owners of dag %s: %v", root, err)
+		}
+	}
+
+	cacheBuckets, err := store.GetMasterBucketList("cache")
+	if err != nil {
+		return err
+	}
+
+	for _, cacheBucket := range cacheBuckets {
+		// Only the per user buckets hold blobs, not the tag indexes or the per app caches
+		publicKey := strings.TrimPrefix(cacheBucket, "cache:")
+		if strings.ContainsAny(publicKey, ":#") {
+			continue
+		}
+
+		hashes, _ := store.getCache(publicKey, "blossom")
+		if len(hashes) == 0 {
+			continue
+		}
+
+		snapshot, err := store.Database.LoadSnapshot(0)
+		if err != nil {
+			return err
+		}
+
+		refTrees := refs.NewTrees(snapshot)
+		for _, hash := range hashes {
+			if err := refs.AddBlob(refTrees, hash, publicKey); err != nil {
+				log.Printf("Failed to record the owner of blob %s: %v", hash, err)
+			}
+		}
+
+		if _, err := graviton.Commit(refTrees.All()...); err != nil {
+			return err
+		}
+	}
+
+	snapshot, err = store.Database.LoadSnapshot(0)
+	if err != nil {
+		return err
+	}
+
+	refTrees := refs.NewTrees(snapshot)
+
+	blobDagTree, err := refTrees.Get("blob_dags")
+	if err != nil {
+		return err
+	}
+
+	refsTree, err := refTrees.Get(refs.Bucket)
+	if err != nil {
+		return err
+	}
+
+	c = blobDagTree.Cursor()
+	for k, v, err := c.First(); err == nil; k, v, err = c.Next() {
+		root := string(v)
+		if err := refs.AddDag(refTrees, root, refs.BlobSubject(string(k))); err != nil {
+			return err
+		}
+
+		if rootData, err := store.RetrieveLeaf(root, root, false); err == nil {
+			refs.Remove(refsTree, refs.DagSubject(root), rootData.PublicKey)
+		}
+	}
+
+	if _, err := graviton.Commit(refTrees.All()...); err != nil {
+		return err
+	}
+
+	snapshot, err = store.Database.LoadSnapshot(0)
+	if err != nil {
+		return err
+	}
+
+	tree, err = snapshot.GetTree("mbl")
+	if err != nil {
+		return err
+	}
+
+	if err := tree.Put([]byte(contentRefsVersionKey), []byte(contentRefsVersion)); err != nil {
+		return err
+	}
+
+	_, err = graviton.Commit(tree)
+	return err
+}
+
+func (store *GravitonStore) migrateDagRefs(root string, bucket string) error {
+	snapshot, err := store.Database.LoadSnapshot(0)
+	if err != nil {
+		return err
+	}
+
+	refTrees := refs.NewTrees(snapshot)
+
+	tree, err := refTrees.Get(bucket)
+	if err != nil {
+		return err
+	}
+
+	visited := map[string]bool{}
+	pending := []string{root}
+	for len(pending) > 0 {
+		hash := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if visited[hash] {
+			continue
+		}
+		visited[hash] = true
+
+		value, err := tree.Get([]byte(hash))
+		if err != nil || value == nil {
+			continue
+		}
+
+		var data types.DagLeafData
+		if err := cbor.Unmarshal(value, &data); err != nil {
+			continue
+		}
+
+		if err := refs.AddLeaf(refTrees, bucket, root, &data.Leaf); err != nil {
+			return err
+		}
+
+		if hash == root && data.PublicKey != "" {
+			if err := refs.AddDag(refTrees, root, data.PublicKey); err != nil {
+				return err
+			}
+		}
+
+		for _, child := range data.Leaf.Links {
+			pending = append(pending, child)
+		}
+	}
+
+	_, err = graviton.Commit(refTrees.All()...)
+	return err
 }
 
 func (store *GravitonStore) GetStatsStore() stores.StatisticsStore {
@@ -147,6 +441,9 @@ func (store *GravitonStore) StoreLeaf(root string, leafData *types.DagLeafData)
 		return fmt.Errorf("leaf has content hash but no content")
 	}
 
+	store.refsMutex.Lock()
+	defer store.refsMutex.Unlock()
+
 	snapshot, err := store.Database.LoadSnapshot(0)
 	if err != nil {
 		return err
@@ -166,7 +463,12 @@ func (store *GravitonStore) StoreLeaf(root string, leafData *types.DagLeafData)
 			return err
 		}
 
-		err = contentTree.Put(leafData.Leaf.ContentHash, leafData.Leaf.Content)
+		content, err := compression.Encode("content", leafData.Leaf.Content)
+		if err != nil {
+			return err
+		}
+
+		err = contentTree.Put(leafData.Leaf.ContentHash, content)
 		if err != nil {
 			return err
 		}
@@ -213,8 +515,20 @@ func (store *GravitonStore) StoreLeaf(root string, leafData *types.DagLeafData)
 
 	trees = append(trees, tree)
 
+	// The root holds a reference to every leaf of its tree so shared leaves are only deleted with the last tree
+	refTrees := refs.NewTrees(snapshot)
+	if err := refs.AddLeaf(refTrees, bucket, root, &leafData.Leaf); err != nil {
+		return err
+	}
+
 	// We only perform certain actions on the root leaf such as caching etc as everything should stem from the root
 	if rootLeaf.Hash == leafData.Leaf.Hash {
+		if leafData.PublicKey != "" {
+			if err := refs.AddDag(refTrees, root, leafData.PublicKey); err != nil {
+				return err
+			}
+		}
+
 		// Store bucket against root hash in the index so the bucket can always be found from the root hash
 		indexTree, err := snapshot.GetTree("scionic_index")
 		if err != nil {
@@ -277,6 +591,8 @@ func (store *GravitonStore) StoreLeaf(root string, leafData *types.DagLeafData)
 		trees = append(trees, contentTree)
 	}
 
+	trees = append(trees, refTrees.All()...)
+
 	_, err = graviton.Commit(trees...)
 	if err != nil {
 		return err
@@ -354,7 +670,7 @@ func (store *GravitonStore) RetrieveLeafContent(contentHash []byte) ([]byte, err
 	}
 
 	if len(bytes) > 0 {
-		return bytes, nil
+		return compression.Decode(bytes)
 	} else {
 		return nil, fmt.Errorf("content not found")
 	}
@@ -401,83 +717,39 @@ func (store *GravitonStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, er
 		return nil, err
 	}
 
-	// Check for any single letter tag queries per author and check cached
-	// buckets first as this should be massively quicker as the data set grows
-	for _, author := range filter.Authors {
-		for key := range filter.Tags {
-			if IsTagQueryTag(key) {
-				hashes, err := store.getCache(author, key)
-				if err == nil {
-					queryFilter := nostr.Filter{
-						IDs: hashes,
-					}
+	seen := map[string]bool{}
 
-					queryEvents, err := store.QueryEvents(queryFilter)
-					if err == nil {
-						for _, event := range queryEvents {
-							if filter.Matches(event) {
-								events = append(events, event)
-							}
-
-							for f, v := range filter.Tags {
-								if v != nil && (ContainsAny(event.Tags, f, v) || ContainsAnyWithWildcard(event.Tags, f, v)) {
-									events = append(events, event)
-								}
-							}
-						}
-					}
-				}
+	// Candidates found in the cache buckets are checked against the full filter as the
+	// buckets only narrow the search down by id, author, tag or coordinate
+	if candidates, ok := store.getCandidates(filter); ok {
+		for _, event := range store.getEvents(snapshot, candidates, filter.Kinds) {
+			if !seen[event.ID] && MatchesFilter(filter, event) {
+				seen[event.ID] = true
+				events = append(events, event)
 			}
 		}
-	}
-
-	// We should only perform the full check if there are no tags and there are no authors
-	// because if there was the above cache check should have found it which will be
-	// a lot faster than the full check below
-	if len(filter.Authors) <= 0 || len(filter.Tags) <= 0 {
-		// Convert search term to lowercase for case-insensitive comparison
-		searchTerm := strings.ToLower(filter.Search)
-
-		masterBucketList, err := store.GetMasterBucketList("kinds")
+	} else {
+		buckets, err := store.getKindBuckets(filter.Kinds)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, bucket := range masterBucketList {
-			if strings.HasPrefix(bucket, "kind") {
-				tree, err := snapshot.GetTree(bucket)
+		for _, bucket := range buckets {
+			tree, err := snapshot.GetTree(bucket)
+			if err != nil {
+				continue // Skip this bucket if there's an error
+			}
+
+			c := tree.Cursor()
+			for _, v, err := c.First(); err == nil; _, v, err = c.Next() {
+				event, err := decodeEvent(v)
 				if err != nil {
-					continue // Skip this bucket if there's an error
+					continue // Skip values that fail to decompress or unmarshal
 				}
 
-				c := tree.Cursor()
-				for _, v, err := c.First(); err == nil; _, v, err = c.Next() {
-					var event nostr.Event
-					if err := jsoniter.Unmarshal(v, &event); err != nil {
-						continue // Skip on unmarshal error
-					}
-
-					// Check tags first because pretty sure go-nostr doesn't take the # in filters into consideration
-					// and we want to use the wildcard system for the f and d tag paths
-					for f, v := range filter.Tags {
-						if v != nil && (ContainsAny(event.Tags, f, v) || ContainsAnyWithWildcard(event.Tags, f, v)) {
-							events = append(events, &event)
-							continue
-						}
-					}
-
-					// Check if the event matches the filter criteria, including kind
-					if !filter.Matches(&event) {
-						continue
-					}
-
-					// Implement search logic here, after ensuring the event matches the filter
-					if searchTerm != "" && !strings.Contains(strings.ToLower(event.Content), searchTerm) {
-						continue // If the lowercase content doesn't contain the lowercase search term, skip
-					}
-
-					// If the event passes both the filter and search, add it to the results
-					events = append(events, &event)
+				if !seen[event.ID] && MatchesFilter(filter, event) {
+					seen[event.ID] = true
+					events = append(events, event)
 				}
 			}
 		}
@@ -506,50 +778,256 @@ func (store *GravitonStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, er
 	return events, nil
 }
 
-func (store *GravitonStore) StoreEvent(event *nostr.Event) error {
-	eventData, err := jsoniter.Marshal(event)
-	if err != nil {
-		return err
-	}
+// MatchesFilter checks an event against every part of a filter, tag values may use the
+// wildcard paths for the f and d tags and the search term is matched case insensitively
+func MatchesFilter(filter nostr.Filter, event *nostr.Event) bool {
+	tags := filter.Tags
+	filter.Tags = nil
 
-	bucket := fmt.Sprintf("kind:%d", event.Kind)
+	if !filter.Matches(event) {
+		return false
+	}
 
-	trees := []*graviton.Tree{}
+	for key, values := range tags {
+		if values != nil && !ContainsAny(event.Tags, key, values) && !ContainsAnyWithWildcard(event.Tags, key, values) {
+			return false
+		}
+	}
 
-	ss, _ := store.Database.LoadSnapshot(0)
-	tree, _ := ss.GetTree(bucket)
+	if filter.Search != "" && !strings.Contains(strings.ToLower(event.Content), strings.ToLower(filter.Search)) {
+		return false
+	}
 
-	trees = append(trees, tree)
+	return true
+}
 
-	// Cache event against pubkey and kind bucket
-	_trees, err := store.cacheKey(event.PubKey, bucket, event.ID)
-	if err == nil {
-		trees = append(trees, _trees...)
+// Returns the ids of the events that could match a filter using the cache buckets, false is
+// returned when none of the buckets apply and the kind buckets have to be scanned instead
+func (store *GravitonStore) getCandidates(filter nostr.Filter) ([]string, bool) {
+	if len(filter.IDs) > 0 {
+		return filter.IDs, true
 	}
 
-	// Cache any single letter tags against pubkey and the #tag letter
-	for _, tag := range event.Tags {
-		key := tag.Key()
-		if IsSingleLetter(key) {
-			_trees, err := store.cacheKey(event.PubKey, fmt.Sprintf("#%s", key), event.ID)
-			if err == nil {
-				trees = append(trees, _trees...)
+	var candidates []string
+
+	if len(filter.Authors) > 0 {
+		tagKey := ""
+		for _, key := range sortedTagKeys(filter.Tags) {
+			if IsSingleLetter(strings.TrimPrefix(key, "#")) {
+				tagKey = strings.TrimPrefix(key, "#")
+				break
+			}
+		}
+
+		for _, author := range filter.Authors {
+			switch {
+			case tagKey != "":
+				hashes, _ := store.getCache(author, fmt.Sprintf("#%s", tagKey))
+				candidates = append(candidates, hashes...)
+			case len(filter.Kinds) > 0:
+				for _, kind := range filter.Kinds {
+					hashes, _ := store.getCache(author, fmt.Sprintf("kind:%d", kind))
+					candidates = append(candidates, hashes...)
+				}
+			default:
+				candidates = append(candidates, store.getAuthorEvents(author)...)
 			}
 		}
+
+		return candidates, true
 	}
 
-	err = tree.Put([]byte(event.ID), eventData)
-	if err != nil {
-		return err
+	// Coordinates are more selective than identifiers so they are checked first
+	for _, key := range indexedTags {
+		values, ok := filter.Tags[key]
+		if !ok {
+			values, ok = filter.Tags["#"+key]
+		}
+
+		if !ok || len(values) == 0 || slices.ContainsFunc(values, isWildcard) {
+			continue
+		}
+
+		for _, value := range values {
+			candidates = append(candidates, store.getIndexedEvents(key, value)...)
+		}
+
+		return candidates, true
 	}
 
-	masterBucketListTree, err := store.UpdateMasterBucketList("kinds", bucket)
+	return nil, false
+}
+
+// Returns the ids of the events carrying a d or a tag with the given value
+func (store *GravitonStore) getIndexedEvents(key string, value string) []string {
+	snapshot, err := store.Database.LoadSnapshot(0)
 	if err != nil {
-		return err
+		return nil
 	}
 
-	if masterBucketListTree != nil {
-		trees = append(trees, masterBucketListTree)
+	indexTree, err := snapshot.GetTree(indexBucket(key, value))
+	if err != nil {
+		return nil
+	}
+
+	var ids []string
+
+	c := indexTree.Cursor()
+	for k, _, err := c.First(); err == nil; k, _, err = c.Next() {
+		ids = append(ids, string(k))
+	}
+
+	return ids
+}
+
+// Returns every event id cached against an author across all of their kind buckets
+func (store *GravitonStore) getAuthorEvents(author string) []string {
+	snapshot, err := store.Database.LoadSnapshot(0)
+	if err != nil {
+		return nil
+	}
+
+	cacheTree, err := snapshot.GetTree(fmt.Sprintf("cache:%s", author))
+	if err != nil {
+		return nil
+	}
+
+	var hashes []string
+
+	c := cacheTree.Cursor()
+	for k, v, err := c.First(); err == nil; k, v, err = c.Next() {
+		// The author buckets are shared with the scionic merkletree caches
+		if !strings.HasPrefix(string(k), "kind:") {
+			continue
+		}
+
+		var cacheData types.CacheData
+		if err := cbor.Unmarshal(v, &cacheData); err == nil {
+			hashes = append(hashes, cacheData.Keys...)
+		}
+	}
+
+	return hashes
+}
+
+// Retrieves events directly by id, only the buckets of the given kinds are checked when kinds are provided
+func (store *GravitonStore) getEvents(snapshot *graviton.Snapshot, ids []string, kinds []int) []*nostr.Event {
+	buckets, err := store.getKindBuckets(kinds)
+	if err != nil {
+		return nil
+	}
+
+	var trees []*graviton.Tree
+	for _, bucket := range buckets {
+		if tree, err := snapshot.GetTree(bucket); err == nil {
+			trees = append(trees, tree)
+		}
+	}
+
+	var events []*nostr.Event
+	for _, id := range ids {
+		for _, tree := range trees {
+			value, err := tree.Get([]byte(id))
+			if err != nil || value == nil {
+				continue
+			}
+
+			event, err := decodeEvent(value)
+			if err == nil {
+				events = append(events, event)
+			}
+
+			break
+		}
+	}
+
+	return events
+}
+
+// Returns the kind buckets for the given kinds or every kind bucket when no kinds are provided
+func (store *GravitonStore) getKindBuckets(kinds []int) ([]string, error) {
+	if len(kinds) > 0 {
+		buckets := []string{}
+		for _, kind := range kinds {
+			buckets = append(buckets, fmt.Sprintf("kind:%d", kind))
+		}
+
+		return buckets, nil
+	}
+
+	masterBucketList, err := store.GetMasterBucketList("kinds")
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := []string{}
+	for _, bucket := range masterBucketList {
+		if strings.HasPrefix(bucket, "kind") {
+			buckets = append(buckets, bucket)
+		}
+	}
+
+	return buckets, nil
+}
+
+func decodeEvent(value []byte) (*nostr.Event, error) {
+	value, err := compression.Decode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var event nostr.Event
+	if err := jsoniter.Unmarshal(value, &event); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+func (store *GravitonStore) StoreEvent(event *nostr.Event) error {
+	eventData, err := jsoniter.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	bucket := fmt.Sprintf("kind:%d", event.Kind)
+
+	snapshot, err := store.Database.LoadSnapshot(0)
+	if err != nil {
+		return err
+	}
+
+	tree, err := snapshot.GetTree(bucket)
+	if err != nil {
+		return err
+	}
+
+	masterBucketListTree, err := snapshot.GetTree("mbl")
+	if err != nil {
+		return err
+	}
+
+	// Cache the event against the pubkey, kind and tags
+	trees, err := store.cacheEvent(snapshot, masterBucketListTree, event)
+	if err != nil {
+		return err
+	}
+
+	trees = append(trees, tree, masterBucketListTree)
+
+	eventData, err = compression.Encode(bucket, eventData)
+	if err != nil {
+		return err
+	}
+
+	err = tree.Put([]byte(event.ID), eventData)
+	if err != nil {
+		return err
+	}
+
+	err = addToMasterBucketList(masterBucketListTree, "kinds", bucket)
+	if err != nil {
+		return err
 	}
 
 	_, err = graviton.Commit(trees...)
@@ -565,33 +1043,211 @@ func (store *GravitonStore) StoreEvent(event *nostr.Event) error {
 	return nil
 }
 
+// Tags that are indexed across every author so addressable events can be found by identifier or coordinate
+var indexedTags = []string{"a", "d"}
+
+// Returns the cache buckets and keys an event id is cached under:
+// the author's kind bucket and the author's single letter tags
+func eventCacheKeys(event *nostr.Event) map[string][]string {
+	keys := map[string][]string{
+		event.PubKey: {fmt.Sprintf("kind:%d", event.Kind)},
+	}
+
+	for _, tag := range event.Tags {
+		key := tag.Key()
+		if !IsSingleLetter(key) {
+			continue
+		}
+
+		tagKey := fmt.Sprintf("#%s", key)
+		if !contains(keys[event.PubKey], tagKey) {
+			keys[event.PubKey] = append(keys[event.PubKey], tagKey)
+		}
+	}
+
+	return keys
+}
+
+// Every d and a tag value has its own index bucket with a key per event id, so common values such as an
+// empty identifier only ever have a single key added or removed instead of a list rewritten
+// The value is hashed as tag values can be longer than graviton allows bucket names to be
+func indexBucket(key string, value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return fmt.Sprintf("index:#%s:%s", key, hex.EncodeToString(hash[:]))
+}
+
+// Returns the index buckets an event id is stored under
+func eventIndexBuckets(event *nostr.Event) []string {
+	buckets := []string{}
+	for _, tag := range event.Tags {
+		if len(tag) < 2 || !slices.Contains(indexedTags, tag[0]) {
+			continue
+		}
+
+		bucket := indexBucket(tag[0], tag[1])
+		if !contains(buckets, bucket) {
+			buckets = append(buckets, bucket)
+		}
+	}
+
+	return buckets
+}
+
+// Caches an event in all of its cache buckets, each bucket is only loaded once so that several keys
+// in the same bucket are all kept when the trees are committed together
+func (store *GravitonStore) cacheEvent(snapshot *graviton.Snapshot, masterBucketListTree *graviton.Tree, event *nostr.Event) ([]*graviton.Tree, error) {
+	trees := []*graviton.Tree{}
+
+	for bucket, keys := range eventCacheKeys(event) {
+		cacheBucket := fmt.Sprintf("cache:%s", bucket)
+
+		cacheTree, err := snapshot.GetTree(cacheBucket)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			cacheData := &types.CacheData{}
+
+			value, err := cacheTree.Get([]byte(key))
+			if err == nil && value != nil {
+				if err := cbor.Unmarshal(value, cacheData); err != nil {
+					continue
+				}
+			}
+
+			if contains(cacheData.Keys, event.ID) {
+				continue
+			}
+
+			cacheData.Keys = append(cacheData.Keys, event.ID)
+
+			serializedData, err := cbor.Marshal(cacheData)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := cacheTree.Put([]byte(key), serializedData); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := addToMasterBucketList(masterBucketListTree, "cache", cacheBucket); err != nil {
+			return nil, err
+		}
+
+		trees = append(trees, cacheTree)
+	}
+
+	for _, bucket := range eventIndexBuckets(event) {
+		indexTree, err := snapshot.GetTree(bucket)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := indexTree.Put([]byte(event.ID), []byte{1}); err != nil {
+			return nil, err
+		}
+
+		trees = append(trees, indexTree)
+	}
+
+	return trees, nil
+}
+
+// ReindexEvents caches every stored event again, this fills in the d and a tag indexes for events
+// stored before they existed and any author cache entries that were never committed
+func (store *GravitonStore) ReindexEvents() (int, error) {
+	buckets, err := store.getKindBuckets(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	indexed := 0
+	for _, bucket := range buckets {
+		snapshot, err := store.Database.LoadSnapshot(0)
+		if err != nil {
+			return indexed, err
+		}
+
+		tree, err := snapshot.GetTree(bucket)
+		if err != nil {
+			continue
+		}
+
+		// Collect the events first as the cache trees are committed as we go
+		events := []*nostr.Event{}
+		c := tree.Cursor()
+		for _, v, err := c.First(); err == nil; _, v, err = c.Next() {
+			if event, err := decodeEvent(v); err == nil {
+				events = append(events, event)
+			}
+		}
+
+		for _, event := range events {
+			snapshot, err := store.Database.LoadSnapshot(0)
+			if err != nil {
+				return indexed, err
+			}
+
+			masterBucketListTree, err := snapshot.GetTree("mbl")
+			if err != nil {
+				return indexed, err
+			}
+
+			trees, err := store.cacheEvent(snapshot, masterBucketListTree, event)
+			if err != nil {
+				return indexed, err
+			}
+
+			if _, err := graviton.Commit(append(trees, masterBucketListTree)...); err != nil {
+				return indexed, err
+			}
+
+			indexed++
+		}
+	}
+
+	return indexed, nil
+}
+
 func (store *GravitonStore) DeleteEvent(eventID string) error {
 	snapshot, err := store.Database.LoadSnapshot(0)
 	if err != nil {
 		return err
 	}
 
-	event, err := store.QueryEvents(nostr.Filter{IDs: []string{eventID}})
-	if err != nil {
-		return err
+	events := store.getEvents(snapshot, []string{eventID}, nil)
+	if len(events) == 0 {
+		return fmt.Errorf("event %s not found", eventID)
 	}
 
-	// event kind number is an integer
-	kindInt, _ := strconv.ParseInt(fmt.Sprintf("%d", event[0].Kind), 10, 64)
+	event := events[0]
 
-	bucket := fmt.Sprintf("kind:%d", kindInt)
+	bucket := fmt.Sprintf("kind:%d", event.Kind)
 
 	tree, err := snapshot.GetTree(bucket)
-	if err == nil {
-		err := tree.Delete([]byte(eventID))
-		if err != nil {
-			return err
-		} else {
-			log.Println("Deleted event", eventID)
-		}
+	if err != nil {
+		return err
+	}
+
+	err = tree.Delete([]byte(eventID))
+	if err != nil {
+		return err
+	}
 
+	// Remove the event from its cache buckets so the indexes don't keep growing with deleted ids
+	trees, err := store.uncacheEvent(snapshot, event)
+	if err != nil {
+		return err
 	}
-	graviton.Commit(tree)
+
+	_, err = graviton.Commit(append(trees, tree)...)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Deleted event", eventID)
 
 	// Delete the event from the GORM SQLite database using statisticsStore
 	if err := store.StatsDatabase.DeleteEventByID(eventID); err != nil {
@@ -601,6 +1257,65 @@ func (store *GravitonStore) DeleteEvent(eventID string) error {
 	return nil
 }
 
+// Removes an event from all of its cache buckets, keys that no longer point to any events are deleted
+func (store *GravitonStore) uncacheEvent(snapshot *graviton.Snapshot, event *nostr.Event) ([]*graviton.Tree, error) {
+	trees := []*graviton.Tree{}
+
+	for bucket, keys := range eventCacheKeys(event) {
+		cacheTree, err := snapshot.GetTree(fmt.Sprintf("cache:%s", bucket))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			value, err := cacheTree.Get([]byte(key))
+			if err != nil || value == nil {
+				continue
+			}
+
+			cacheData := &types.CacheData{}
+			if err := cbor.Unmarshal(value, cacheData); err != nil {
+				continue
+			}
+
+			cacheData.Keys = slices.DeleteFunc(cacheData.Keys, func(id string) bool {
+				return id == event.ID
+			})
+
+			if len(cacheData.Keys) == 0 {
+				err = cacheTree.Delete([]byte(key))
+			} else {
+				var serializedData []byte
+				serializedData, err = cbor.Marshal(cacheData)
+				if err == nil {
+					err = cacheTree.Put([]byte(key), serializedData)
+				}
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		trees = append(trees, cacheTree)
+	}
+
+	for _, bucket := range eventIndexBuckets(event) {
+		indexTree, err := snapshot.GetTree(bucket)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := indexTree.Delete([]byte(event.ID)); err != nil {
+			return nil, err
+		}
+
+		trees = append(trees, indexTree)
+	}
+
+	return trees, nil
+}
+
 func (store *GravitonStore) CountFileLeavesByType() (map[string]int, error) {
 	snapshot, err := store.Database.LoadSnapshot(0)
 	if err != nil {
@@ -626,92 +1341,343 @@ func (store *GravitonStore) CountFileLeavesByType() (map[string]int, error) {
 				continue
 			}
 
-			if leaf.Type == merkle_dag.FileLeafType {
-				splitName := strings.Split(leaf.ItemName, ".")
-				if len(splitName) > 1 {
-					extension := strings.ToLower(splitName[len(splitName)-1])
-					fileTypeCounts[extension]++
-				}
+			if leaf.Type == merkle_dag.FileLeafType {
+				splitName := strings.Split(leaf.ItemName, ".")
+				if len(splitName) > 1 {
+					extension := strings.ToLower(splitName[len(splitName)-1])
+					fileTypeCounts[extension]++
+				}
+			}
+		}
+	}
+
+	return fileTypeCounts, nil
+}
+
+// Blossom Blobs (unchunked data)
+func (store *GravitonStore) StoreBlob(data []byte, hash []byte, publicKey string) error {
+	store.refsMutex.Lock()
+	defer store.refsMutex.Unlock()
+
+	snapshot, err := store.Database.LoadSnapshot(0)
+	if err != nil {
+		return err
+	}
+
+	refTrees := refs.NewTrees(snapshot)
+
+	contentTree, err := refTrees.Get("content")
+	if err != nil {
+		return err
+	}
+
+	encodedHash := hex.EncodeToString(hash[:])
+
+	cacheTrees, err := store.cacheKey(publicKey, "blossom", encodedHash)
+	if err != nil {
+		return err
+	}
+
+	content, err := compression.Encode("content", data)
+	if err != nil {
+		return err
+	}
+
+	contentTree.Put(hash[:], content)
+
+	// The same blob can be uploaded by several users so each of them holds a reference to it
+	if err := refs.AddBlob(refTrees, encodedHash, publicKey); err != nil {
+		return err
+	}
+
+	cacheTrees = append(cacheTrees, refTrees.All()...)
+
+	graviton.Commit(cacheTrees...)
+
+	return nil
+}
+
+func (store *GravitonStore) GetBlob(hash string) ([]byte, error) {
+	snapshot, err := store.Database.LoadSnapshot(0)
+	if err != nil {
+		return nil, err
+	}
+
+	contentTree, err := snapshot.GetTree("content")
+	if err != nil {
+		return nil, err
+	}
+
+	hashBytes, err := hex.DecodeString(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := contentTree.Get(hashBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return compression.Decode(content)
+}
+
+// Removes the reference the public key holds to a blob, the content (or the scionic merkletree the blob was
+// converted into) is only deleted once no other public key has uploaded the same blob
+func (store *GravitonStore) DeleteBlob(hash string, publicKey string) error {
+	store.refsMutex.Lock()
+	defer store.refsMutex.Unlock()
+
+	// Trees a blob was converted into have file statistics that go with them
+	root, _ := store.GetBlobDag(hash)
+
+	snapshot, err := store.Database.LoadSnapshot(0)
+	if err != nil {
+		return err
+	}
+
+	refTrees := refs.NewTrees(snapshot)
+
+	if _, err := refs.ReleaseBlob(refTrees, "scionic_index", hash, publicKey); err != nil {
+		return fmt.Errorf("failed to delete blob %s: %w", hash, err)
+	}
+
+	if err := uncacheKey(refTrees, publicKey, "blossom", hash); err != nil {
+		return err
+	}
+
+	if _, err := graviton.Commit(refTrees.All()...); err != nil {
+		return err
+	}
+
+	if root != "" {
+		store.deleteFileStatsIfRemoved(root)
+	}
+
+	return nil
+}
+
+// Blobs that have been converted into scionic merkle trees are mapped from their sha256 hash to the dag root
+// The relay signs these trees so the tree is owned by the blob instead of the relay and the blob by its uploader
+func (store *GravitonStore) SetBlobDag(hash string, root string, publicKey string) error {
+	store.refsMutex.Lock()
+	defer store.refsMutex.Unlock()
+
+	rootData, err := store.RetrieveLeaf(root, root, false)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve dag root: %v", err)
+	}
+
+	snapshot, err := store.Database.LoadSnapshot(0)
+	if err != nil {
+		return fmt.Errorf("failed to load snapshot: %v", err)
+	}
+
+	refTrees := refs.NewTrees(snapshot)
+
+	blobDagTree, err := refTrees.Get("blob_dags")
+	if err != nil {
+		return fmt.Errorf("failed to get blob dags tree: %v", err)
+	}
+
+	if err := blobDagTree.Put([]byte(hash), []byte(root)); err != nil {
+		return fmt.Errorf("failed to put blob dag: %v", err)
+	}
+
+	refsTree, err := refTrees.Get(refs.Bucket)
+	if err != nil {
+		return err
+	}
+
+	if err := refs.Add(refsTree, refs.BlobSubject(hash), publicKey); err != nil {
+		return err
+	}
+
+	if err := refs.AddDag(refTrees, root, refs.BlobSubject(hash)); err != nil {
+		return err
+	}
+
+	if _, err := refs.Remove(refsTree, refs.DagSubject(root), rootData.PublicKey); err != nil && !errors.Is(err, refs.ErrNotOwner) {
+		return err
+	}
+
+	cacheTrees, err := store.cacheKey(publicKey, "blossom", hash)
+	if err != nil {
+		return err
+	}
+
+	if _, err := graviton.Commit(append(cacheTrees, refTrees.All()...)...); err != nil {
+		return fmt.Errorf("failed to commit blob dags tree: %v", err)
+	}
+
+	return nil
+}
+
+func (store *GravitonStore) GetBlobDag(hash string) (string, error) {
+	snapshot, err := store.Database.LoadSnapshot(0)
+	if err != nil {
+		return "", fmt.Errorf("failed to load snapshot: %v", err)
+	}
+
+	blobDagTree, err := snapshot.GetTree("blob_dags")
+	if err != nil {
+		return "", fmt.Errorf("failed to get blob dags tree: %v", err)
+	}
+
+	root, err := blobDagTree.Get([]byte(hash))
+	if err != nil || root == nil {
+		return "", fmt.Errorf("no dag found for blob %s", hash)
+	}
+
+	return string(root), nil
+}
+
+// Returns the hashes of the blossom blobs uploaded by the public key
+func (store *GravitonStore) GetBlobsByPubkey(publicKey string) ([]string, error) {
+	return store.getCache(publicKey, "blossom")
+}
+
+// Returns the roots of the scionic merkletrees uploaded by the public key
+func (store *GravitonStore) GetDagsByPubkey(publicKey string) ([]string, error) {
+	snapshot, err := store.Database.LoadSnapshot(0)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheTree, err := snapshot.GetTree(fmt.Sprintf("cache:%s", publicKey))
+	if err != nil {
+		return nil, err
+	}
+
+	roots := []string{}
+
+	// Trees are cached against the user by file type, the other keys belong to events and blobs
+	c := cacheTree.Cursor()
+	for k, v, err := c.First(); err == nil; k, v, err = c.Next() {
+		key := string(k)
+		if strings.HasPrefix(key, "kind:") || strings.HasPrefix(key, "#") || key == "blossom" {
+			continue
+		}
+
+		var cacheData types.CacheData
+		if err := cbor.Unmarshal(v, &cacheData); err != nil {
+			continue
+		}
+
+		for _, root := range cacheData.Keys {
+			if !contains(roots, root) {
+				roots = append(roots, root)
 			}
 		}
 	}
 
-	return fileTypeCounts, nil
+	return roots, nil
 }
 
-// Blossom Blobs (unchunked data)
-func (store *GravitonStore) StoreBlob(data []byte, hash []byte, publicKey string) error {
-	snapshot, err := store.Database.LoadSnapshot(0)
-	if err != nil {
-		return err
+// Removes the reference the public key holds to a scionic merkletree, once no one owns the tree its leaves and
+// content are deleted unless they are shared with another tree or blob
+func (store *GravitonStore) DeleteDag(root string, publicKey string) error {
+	store.refsMutex.Lock()
+	defer store.refsMutex.Unlock()
+
+	bucket, err := store.retrieveBucket(root)
+	if err != nil || bucket == "" {
+		return fmt.Errorf("dag %s not found", root)
 	}
 
-	contentTree, err := snapshot.GetTree("content")
+	snapshot, err := store.Database.LoadSnapshot(0)
 	if err != nil {
 		return err
 	}
 
-	encodedHash := hex.EncodeToString(hash[:])
+	refTrees := refs.NewTrees(snapshot)
 
-	cacheTrees, err := store.cacheKey(publicKey, "blossom", encodedHash)
+	deleted, err := refs.ReleaseDag(refTrees, "scionic_index", root, publicKey)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to delete dag %s: %w", root, err)
 	}
 
-	contentTree.Put(hash[:], data)
+	if err := uncacheKey(refTrees, publicKey, bucket, root); err != nil {
+		return err
+	}
 
-	cacheTrees = append(cacheTrees, contentTree)
+	if _, err := graviton.Commit(refTrees.All()...); err != nil {
+		return err
+	}
 
-	graviton.Commit(cacheTrees...)
+	if deleted {
+		if err := store.StatsDatabase.DeleteFileStats(root); err != nil {
+			log.Printf("error deleting file stats for %s: %v", root, err)
+		}
+	}
 
 	return nil
 }
 
-func (store *GravitonStore) GetBlob(hash string) ([]byte, error) {
-	snapshot, err := store.Database.LoadSnapshot(0)
-	if err != nil {
-		return nil, err
-	}
-
-	contentTree, err := snapshot.GetTree("content")
-	if err != nil {
-		return nil, err
+// File statistics are removed once the tree itself has been deleted
+func (store *GravitonStore) deleteFileStatsIfRemoved(root string) {
+	if bucket, err := store.retrieveBucket(root); err == nil && bucket != "" {
+		return
 	}
 
-	hashBytes, err := hex.DecodeString(hash)
-	if err != nil {
-		return nil, err
+	if err := store.StatsDatabase.DeleteFileStats(root); err != nil {
+		log.Printf("error deleting file stats for %s: %v", root, err)
 	}
+}
 
-	content, err := contentTree.Get(hashBytes)
+// Removes every cache entry held against the public key, including the per app caches, along with its storage usage
+func (store *GravitonStore) ClearPubkeyCache(publicKey string) error {
+	cacheBuckets, err := store.GetMasterBucketList("cache")
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return content, nil
-}
-
-func (store *GravitonStore) DeleteBlob(hash string) error {
 	snapshot, err := store.Database.LoadSnapshot(0)
 	if err != nil {
 		return err
 	}
 
-	contentTree, err := snapshot.GetTree("content")
-	if err != nil {
-		return err
+	trees := []*graviton.Tree{}
+	for _, cacheBucket := range cacheBuckets {
+		if cacheBucket != fmt.Sprintf("cache:%s", publicKey) && !strings.HasPrefix(cacheBucket, fmt.Sprintf("cache:%s:", publicKey)) {
+			continue
+		}
+
+		cacheTree, err := snapshot.GetTree(cacheBucket)
+		if err != nil {
+			continue
+		}
+
+		// Collect the keys first as the tree can't be modified while the cursor is in use
+		keys := [][]byte{}
+		c := cacheTree.Cursor()
+		for k, _, err := c.First(); err == nil; k, _, err = c.Next() {
+			keys = append(keys, slices.Clone(k))
+		}
+
+		for _, key := range keys {
+			if err := cacheTree.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		trees = append(trees, cacheTree)
 	}
 
-	hashBytes, err := hex.DecodeString(hash)
-	if err != nil {
+	if _, err := graviton.Commit(trees...); err != nil {
 		return err
 	}
 
-	contentTree.Delete(hashBytes)
+	return usage.Clear(store.Database, publicKey)
+}
 
-	graviton.Commit(contentTree)
+// Returns the number of bytes the public key has uploaded to the relay
+func (store *GravitonStore) GetStorageUsage(publicKey string) (int64, error) {
+	return usage.Get(store.Database, publicKey)
+}
 
-	return nil
+// Adds the delta (which can be negative when data is deleted) to the storage usage of the public key
+func (store *GravitonStore) UpdateStorageUsage(publicKey string, delta int64) error {
+	return usage.Update(store.Database, publicKey, delta)
 }
 
 // This is used to create / update cache buckets with hashes that point to nostr notes or
@@ -787,15 +1753,49 @@ func (store *GravitonStore) getCache(bucket string, key string) ([]string, error
 					if err == nil {
 						return cacheData.Keys, nil
 					}
+
+					fmt.Printf("Failed to unmrashal cache bucket %s with key %s\n", bucket, key)
 				}
 			}
 		}
 	}
 
-	fmt.Printf("Failed to unmrashal cache bucket %s with key %s\n", bucket, key)
 	return nil, nil
 }
 
+// Removes a hash from a cache (list of hashes), the key is deleted once the list is empty
+func uncacheKey(trees *refs.Trees, bucket string, key string, root string) error {
+	cacheTree, err := trees.Get(fmt.Sprintf("cache:%s", bucket))
+	if err != nil {
+		return err
+	}
+
+	value, err := cacheTree.Get([]byte(key))
+	if err != nil || value == nil {
+		return nil
+	}
+
+	cacheData := &types.CacheData{}
+	if err := cbor.Unmarshal(value, cacheData); err != nil {
+		return nil
+	}
+
+	cacheData.Keys = slices.DeleteFunc(cacheData.Keys, func(hash string) bool {
+		return hash == root
+	})
+
+	if len(cacheData.Keys) == 0 {
+		return cacheTree.Delete([]byte(key))
+	}
+
+	serializedData, err := cbor.Marshal(cacheData)
+	if err != nil {
+		return err
+	}
+
+	return cacheTree.Put([]byte(key), serializedData)
+}
+
 // The master bucket list is a bucket that contains lists of all other buckets
 // This allows us to retrieve and itterate buckets without the need for graviton to support it
 func (store *GravitonStore) UpdateMasterBucketList(key string, bucket string) (*graviton.Tree, error) {
@@ -806,6 +1806,29 @@ func (store *GravitonStore) UpdateMasterBucketList(key string, bucket string) (*
 		return nil, err
 	}
 
+	bytes, err := tree.Get([]byte(fmt.Sprintf("mbl_%s", key)))
+	if err == nil && bytes != nil {
+		var masterBucketList []string
+		err = cbor.Unmarshal(bytes, &masterBucketList)
+		if err != nil {
+			return nil, err
+		}
+
+		if contains(masterBucketList, bucket) {
+			return nil, nil
+		}
+	}
+
+	err = addToMasterBucketList(tree, key, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+// Adds a bucket to a master bucket list using an already loaded master bucket list tree
+func addToMasterBucketList(tree *graviton.Tree, key string, bucket string) error {
 	var masterBucketList []string
 
 	bytes, err := tree.Get([]byte(fmt.Sprintf("mbl_%s", key)))
@@ -814,27 +1837,50 @@ func (store *GravitonStore) UpdateMasterBucketList(key string, bucket string) (*
 	} else {
 		err = cbor.Unmarshal(bytes, &masterBucketList)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
 	if contains(masterBucketList, bucket) {
-		return nil, nil
-	} else {
-		masterBucketList = append(masterBucketList, bucket)
+		return nil
+	}
 
-		bytes, err = cbor.Marshal(masterBucketList)
-		if err != nil {
-			return nil, err
-		}
+	masterBucketList = append(masterBucketList, bucket)
 
-		err = tree.Put([]byte(fmt.Sprintf("mbl_%s", key)), bytes)
-		if err != nil {
-			return nil, err
-		}
+	bytes, err = cbor.Marshal(masterBucketList)
+	if err != nil {
+		return err
 	}
 
-	return tree, nil
+	return tree.Put([]byte(fmt.Sprintf("mbl_%s", key)), bytes)
+}
+
+// Removes a bucket from a master bucket list using an already loaded master bucket list tree
+func removeFromMasterBucketList(tree *graviton.Tree, key string, bucket string) error {
+	bytes, err := tree.Get([]byte(fmt.Sprintf("mbl_%s", key)))
+	if bytes == nil || err != nil {
+		return nil
+	}
+
+	var masterBucketList []string
+	if err := cbor.Unmarshal(bytes, &masterBucketList); err != nil {
+		return err
+	}
+
+	if !contains(masterBucketList, bucket) {
+		return nil
+	}
+
+	masterBucketList = slices.DeleteFunc(masterBucketList, func(entry string) bool {
+		return entry == bucket
+	})
+
+	bytes, err = cbor.Marshal(masterBucketList)
+	if err != nil {
+		return err
+	}
+
+	return tree.Put([]byte(fmt.Sprintf("mbl_%s", key)), bytes)
 }
 
 // You can get an array of bucket keys by specifying which list of buckets you want
@@ -945,6 +1991,21 @@ func ContainsAnyWithWildcard(tags nostr.Tags, tagName string, values []string) b
 	return false
 }
 
+// Only the f and d tag paths support wildcards
+func isWildcard(value string) bool {
+	return strings.Contains(value, "*")
+}
+
+func sortedTagKeys(tags nostr.TagMap) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
 func matchWildcard(pattern, value string) bool {
 	patternParts := strings.Split(pattern, "/")
 	valueParts := strings.Split(value, "/")
@@ -993,37 +2054,7 @@ func ContainsAny(tags nostr.Tags, tagName string, values []string) bool {
 }
 
 func (store *GravitonStore) SaveSubscriber(subscriber *types.Subscriber) error {
-	// Load the snapshot and get the "subscribers" tree
-	snapshot, err := store.Database.LoadSnapshot(0)
-	if err != nil {
-		return fmt.Errorf("failed to load snapshot: %v", err)
-	}
-
-	subscriberTree, err := snapshot.GetTree("subscribers")
-	if err != nil {
-		return fmt.Errorf("failed to get subscribers tree: %v", err)
-	}
-
-	// Marshal the subscriber into JSON
-	subscriberData, err := json.Marshal(subscriber)
-	if err != nil {
-		return fmt.Errorf("failed to marshal subscriber: %v", err)
-	}
-
-	// Use the npub as the key for storing the subscriber
-	key := subscriber.Npub
-
-	// Store the subscriber data in the tree
-	if err := subscriberTree.Put([]byte(key), subscriberData); err != nil {
-		return fmt.Errorf("failed to put subscriber in Graviton store: %v", err)
-	}
-
-	// Commit the tree to persist the changes
-	if _, err := graviton.Commit(subscriberTree); err != nil {
-		return fmt.Errorf("failed to commit subscribers tree: %v", err)
-	}
-
-	return nil
+	return subscribers.Save(store.Database, subscriber)
 }
 
 func (store *GravitonStore) GetSubscriberByAddress(address string) (*types.Subscriber, error) {
@@ -1061,32 +2092,17 @@ func (store *GravitonStore) GetSubscriberByAddress(address string) (*types.Subsc
 }
 
 func (store *GravitonStore) GetSubscriber(npub string) (*types.Subscriber, error) {
-	snapshot, err := store.Database.LoadSnapshot(0)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load snapshot: %v", err)
-	}
-
-	subscriberTree, err := snapshot.GetTree("subscribers")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get subscribers tree: %v", err)
-	}
-
-	// Iterate over subscribers to find the one with the matching npub
-	cursor := subscriberTree.Cursor()
-	for _, v, err := cursor.First(); err == nil; _, v, err = cursor.Next() {
-		var subscriber types.Subscriber
-		if err := json.Unmarshal(v, &subscriber); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal subscriber data: %v", err)
-		}
+	return subscribers.Get(store.Database, npub)
+}
 
-		// Check if the current subscriber's npub matches the provided npub
-		if subscriber.Npub == npub {
-			return &subscriber, nil
-		}
-	}
+// Returns the subscriber the NIP-05 name is registered to
+func (store *GravitonStore) GetSubscriberByName(name string) (*types.Subscriber, error) {
+	return subscribers.GetByName(store.Database, name)
+}
 
-	// If no subscriber was found with the matching npub, return an error
-	return nil, fmt.Errorf("subscriber not found for npub: %s", npub)
+// Returns every subscriber with a registered NIP-05 name
+func (store *GravitonStore) GetNamedSubscribers() ([]*types.Subscriber, error) {
+	return subscribers.GetNamed(store.Database)
 }
 
 // AllocateBitcoinAddress allocates an available Bitcoin address to a subscriber.
@@ -1138,3 +2154,137 @@ func (store *GravitonStore) AllocateBitcoinAddress(npub string) (*types.Address,
 
 	return nil, fmt.Errorf("no available addresses")
 }
+
+// Compression applies to leaf content, blobs and events so these are the buckets that get measured and migrated
+func (store *GravitonStore) compressibleBuckets() ([]string, error) {
+	buckets := []string{"content"}
+
+	kindBuckets, err := store.GetMasterBucketList("kinds")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bucket := range kindBuckets {
+		if strings.HasPrefix(bucket, "kind") {
+			buckets = append(buckets, bucket)
+		}
+	}
+
+	return buckets, nil
+}
+
+// Measure the logical (uncompressed) and physical (stored) size of every compressible bucket
+func (store *GravitonStore) GetCompressionStats() (map[string]*types.CompressionStats, error) {
+	snapshot, err := store.Database.LoadSnapshot(0)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets, err := store.compressibleBuckets()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := map[string]*types.CompressionStats{}
+	for _, bucket := range buckets {
+		tree, err := snapshot.GetTree(bucket)
+		if err != nil {
+			continue
+		}
+
+		bucketStats := &types.CompressionStats{}
+
+		c := tree.Cursor()
+		for _, v, err := c.First(); err == nil; _, v, err = c.Next() {
+			decoded, err := compression.Decode(v)
+			if err != nil {
+				continue
+			}
+
+			bucketStats.Values++
+			bucketStats.LogicalBytes += int64(len(decoded))
+			bucketStats.PhysicalBytes += int64(len(v))
+		}
+
+		stats[bucket] = bucketStats
+	}
+
+	return stats, nil
+}
+
+// Re-encode every value in the compressible buckets using the current compression settings
+// This compresses data stored before compression was enabled and decompresses buckets it has been disabled for
+func (store *GravitonStore) MigrateCompression() (int, error) {
+	const batchSize = 1000
+
+	buckets, err := store.compressibleBuckets()
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, bucket := range buckets {
+		snapshot, err := store.Database.LoadSnapshot(0)
+		if err != nil {
+			return migrated, err
+		}
+
+		tree, err := snapshot.GetTree(bucket)
+		if err != nil {
+			continue
+		}
+
+		// Collect the keys first as the tree can't be modified while the cursor is in use
+		keys := [][]byte{}
+		c := tree.Cursor()
+		for k, _, err := c.First(); err == nil; k, _, err = c.Next() {
+			keys = append(keys, slices.Clone(k))
+		}
+
+		pending := 0
+		for _, key := range keys {
+			value, err := tree.Get(key)
+			if err != nil {
+				continue
+			}
+
+			decoded, err := compression.Decode(value)
+			if err != nil {
+				log.Printf("Skipping value %x in bucket %s: %v", key, bucket, err)
+				continue
+			}
+
+			encoded, err := compression.Encode(bucket, decoded)
+			if err != nil {
+				return migrated, err
+			}
+
+			if bytes.Equal(encoded, value) {
+				continue
+			}
+
+			if err := tree.Put(key, encoded); err != nil {
+				return migrated, err
+			}
+
+			migrated++
+			pending++
+
+			if pending >= batchSize {
+				if _, err := graviton.Commit(tree); err != nil {
+					return migrated, err
+				}
+
+				pending = 0
+			}
+		}
+
+		if pending > 0 {
+			if _, err := graviton.Commit(tree); err != nil {
+				return migrated, err
+			}
+		}
+	}
+
+	return migrated, nil
+}
@@ -0,0 +1,192 @@
+package graviton
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/deroproject/graviton"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+)
+
+func newStore(t *testing.T) *GravitonStore {
+	directory := t.TempDir()
+	viper.Set("relay_stats_db", filepath.Join(directory, "stats.db"))
+
+	store := &GravitonStore{}
+	if err := store.InitStore(filepath.Join(directory, "gravitondb")); err != nil {
+		t.Fatalf("failed to initialize store: %v", err)
+	}
+
+	return store
+}
+
+func TestDeleteBlobOwnership(t *testing.T) {
+	store := newStore(t)
+
+	data := []byte("the same file uploaded twice")
+	hash := sha256.Sum256(data)
+	encodedHash := hex.EncodeToString(hash[:])
+
+	for _, publicKey := range []string{"alice", "bob"} {
+		if err := store.StoreBlob(data, hash[:], publicKey); err != nil {
+			t.Fatalf("failed to store blob: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		publicKey string
+		deleted   bool
+		available bool
+	}{
+		{"someone who never uploaded it", "mallory", false, true},
+		{"first uploader", "alice", true, true},
+		{"last uploader", "bob", true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := store.DeleteBlob(encodedHash, test.publicKey)
+			if (err == nil) != test.deleted {
+				t.Fatalf("expected deleted %v, got error %v", test.deleted, err)
+			}
+
+			_, err = store.GetBlob(encodedHash)
+			if (err == nil) != test.available {
+				t.Errorf("expected blob available %v, got error %v", test.available, err)
+			}
+
+			blobs, _ := store.GetBlobsByPubkey(test.publicKey)
+			if test.deleted && len(blobs) != 0 {
+				t.Errorf("expected the blob to be removed from the cache of %s", test.publicKey)
+			}
+		})
+	}
+}
+
+func TestTagIndexes(t *testing.T) {
+	store := newStore(t)
+
+	author := nostr.GeneratePrivateKey()
+	pubkey, _ := nostr.GetPublicKey(author)
+	coordinate := fmt.Sprintf("30009:%s:bravery", pubkey)
+
+	newEvent := func(kind int, tags nostr.Tags) *nostr.Event {
+		event := &nostr.Event{PubKey: pubkey, Kind: kind, Tags: tags, CreatedAt: nostr.Now()}
+		event.Sign(author)
+		return event
+	}
+
+	definition := newEvent(30009, nostr.Tags{{"d", "bravery"}})
+	profile := newEvent(30008, nostr.Tags{{"d", "profile_badges"}, {"a", coordinate}})
+	other := newEvent(30008, nostr.Tags{{"d", "profile_badges"}})
+	untagged := newEvent(1, nil)
+
+	for _, event := range []*nostr.Event{definition, profile, other, untagged} {
+		if err := store.StoreEvent(event); err != nil {
+			t.Fatalf("failed to store event: %v", err)
+		}
+	}
+
+	if err := store.DeleteEvent(other.ID); err != nil {
+		t.Fatalf("failed to delete event: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   nostr.Filter
+		expected []string
+	}{
+		{"identifier", nostr.Filter{Tags: nostr.TagMap{"d": {"bravery"}}}, []string{definition.ID}},
+		{"deleted event left out", nostr.Filter{Tags: nostr.TagMap{"d": {"profile_badges"}}}, []string{profile.ID}},
+		{"coordinate", nostr.Filter{Tags: nostr.TagMap{"a": {coordinate}}}, []string{profile.ID}},
+		{"several values", nostr.Filter{Tags: nostr.TagMap{"d": {"bravery", "profile_badges"}}}, []string{definition.ID, profile.ID}},
+		{"unknown value", nostr.Filter{Tags: nostr.TagMap{"d": {"missing"}}}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if candidates, ok := store.getCandidates(test.filter); !ok || len(candidates) != len(test.expected) {
+				t.Fatalf("expected %d candidates from the index, got %v", len(test.expected), candidates)
+			}
+
+			events, err := store.QueryEvents(test.filter)
+			if err != nil {
+				t.Fatalf("failed to query events: %v", err)
+			}
+
+			var ids []string
+			for _, event := range events {
+				ids = append(ids, event.ID)
+			}
+
+			slices.Sort(ids)
+			expected := slices.Clone(test.expected)
+			slices.Sort(expected)
+
+			if !slices.Equal(ids, expected) {
+				t.Errorf("expected %v, got %v", expected, ids)
+			}
+		})
+	}
+
+	cacheBuckets, _ := store.GetMasterBucketList("cache")
+	for _, bucket := range cacheBuckets {
+		if strings.HasPrefix(bucket, "cache:#") {
+			t.Errorf("expected no shared tag cache buckets, found %s", bucket)
+		}
+	}
+}
+
+func TestMigrateLegacyTagIndexes(t *testing.T) {
+	store := newStore(t)
+
+	author := nostr.GeneratePrivateKey()
+	event := &nostr.Event{Kind: 30009, Tags: nostr.Tags{{"d", "bravery"}}, CreatedAt: nostr.Now()}
+	event.Sign(author)
+
+	if err := store.StoreEvent(event); err != nil {
+		t.Fatalf("failed to store event: %v", err)
+	}
+
+	// Put the store back into the state the shared list indexes left it in
+	snapshot, _ := store.Database.LoadSnapshot(0)
+	mbl, _ := snapshot.GetTree("mbl")
+	legacy, _ := snapshot.GetTree("cache:#d")
+	index, _ := snapshot.GetTree(indexBucket("d", "bravery"))
+
+	cacheData, _ := cbor.Marshal(types.CacheData{Keys: []string{event.ID}})
+	legacy.Put([]byte("bravery"), cacheData)
+	index.Delete([]byte(event.ID))
+	addToMasterBucketList(mbl, "cache", "cache:#d")
+	mbl.Put([]byte(eventIndexVersionKey), []byte("1"))
+
+	if _, err := graviton.Commit(mbl, legacy, index); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	if err := store.migrateEventIndexes(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	if ids := store.getIndexedEvents("d", "bravery"); !slices.Equal(ids, []string{event.ID}) {
+		t.Errorf("expected the event to be reindexed, got %v", ids)
+	}
+
+	if hashes, _ := store.getCache("#d", "bravery"); len(hashes) != 0 {
+		t.Errorf("expected the old index to be emptied, got %v", hashes)
+	}
+
+	if cacheBuckets, _ := store.GetMasterBucketList("cache"); slices.Contains(cacheBuckets, "cache:#d") {
+		t.Errorf("expected the old index to be taken off the master bucket list")
+	}
+}
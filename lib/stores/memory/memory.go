@@ -3,11 +3,13 @@ package memory
 import (
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/deroproject/graviton"
@@ -15,6 +17,9 @@ import (
 	"github.com/nbd-wtf/go-nostr"
 
 	stores "github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/refs"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/subscribers"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/usage"
 	merkle_dag "github.com/HORNET-Storage/scionic-merkletree/dag"
 
 	jsoniter "github.com/json-iterator/go"
@@ -32,6 +37,9 @@ type GravitonMemoryStore struct {
 	Database *graviton.Store
 
 	CacheConfig map[string]string
+
+	// Serializes changes to the owners of blobs, scionic merkletrees and content
+	refsMutex sync.Mutex
 }
 
 func (store *GravitonMemoryStore) InitStore(basepath string, args ...interface{}) error {
@@ -72,6 +80,16 @@ func (store *GravitonMemoryStore) GetStatsStore() stores.StatisticsStore {
 	return nil
 }
 
+// Not implemented for the Memory Store
+func (store *GravitonMemoryStore) GetCompressionStats() (map[string]*types.CompressionStats, error) {
+	return nil, fmt.Errorf("compression is not supported by the memory store")
+}
+
+// Not implemented for the Memory Store
+func (store *GravitonMemoryStore) MigrateCompression() (int, error) {
+	return 0, fmt.Errorf("compression is not supported by the memory store")
+}
+
 func (store *GravitonMemoryStore) QueryDag(filter map[string]string) ([]string, error) {
 	keys := []string{}
 
@@ -112,6 +130,9 @@ func (store *GravitonMemoryStore) StoreLeaf(root string, leafData *types.DagLeaf
 		return fmt.Errorf("leaf has content hash but no content")
 	}
 
+	store.refsMutex.Lock()
+	defer store.refsMutex.Unlock()
+
 	snapshot, err := store.Database.LoadSnapshot(0)
 	if err != nil {
 		return err
@@ -173,7 +194,18 @@ func (store *GravitonMemoryStore) StoreLeaf(root string, leafData *types.DagLeaf
 
 	trees = append(trees, tree)
 
+	refTrees := refs.NewTrees(snapshot)
+	if err := refs.AddLeaf(refTrees, bucket, root, &leafData.Leaf); err != nil {
+		return err
+	}
+
 	if rootLeaf.Hash == leafData.Leaf.Hash {
+		if leafData.PublicKey != "" {
+			if err := refs.AddDag(refTrees, root, leafData.PublicKey); err != nil {
+				return err
+			}
+		}
+
 		indexTree, err := snapshot.GetTree("root_index")
 		if err != nil {
 			return err
@@ -238,6 +270,8 @@ func (store *GravitonMemoryStore) StoreLeaf(root string, leafData *types.DagLeaf
 		trees = append(trees, contentTree)
 	}
 
+	trees = append(trees, refTrees.All()...)
+
 	_, err = graviton.Commit(trees...)
 	if err != nil {
 		return err
@@ -414,12 +448,20 @@ func (store *GravitonMemoryStore) DeleteEvent(eventID string) error {
 }
 
 func (store *GravitonMemoryStore) StoreBlob(data []byte, hash []byte, publicKey string) error {
+	store.refsMutex.Lock()
+	defer store.refsMutex.Unlock()
+
 	snapshot, _ := store.Database.LoadSnapshot(0)
-	contentTree, _ := snapshot.GetTree("content")
+	refTrees := refs.NewTrees(snapshot)
+	contentTree, _ := refTrees.Get("content")
 
 	contentTree.Put(hash[:], data)
 
-	graviton.Commit(contentTree)
+	if err := refs.AddBlob(refTrees, hex.EncodeToString(hash), publicKey); err != nil {
+		return err
+	}
+
+	graviton.Commit(refTrees.All()...)
 
 	return nil
 }
@@ -441,86 +483,140 @@ func (store *GravitonMemoryStore) GetBlob(hash string) ([]byte, error) {
 	return content, nil
 }
 
-func (store *GravitonMemoryStore) DeleteBlob(hash string) error {
+// Removes the reference the public key holds to a blob, it's only deleted once no one else uploaded it
+func (store *GravitonMemoryStore) DeleteBlob(hash string, publicKey string) error {
+	store.refsMutex.Lock()
+	defer store.refsMutex.Unlock()
+
 	snapshot, _ := store.Database.LoadSnapshot(0)
-	contentTree, _ := snapshot.GetTree("content")
+	refTrees := refs.NewTrees(snapshot)
 
-	hashBytes, err := hex.DecodeString(hash)
-	if err != nil {
-		return err
+	if _, err := refs.ReleaseBlob(refTrees, "root_index", hash, publicKey); err != nil {
+		return fmt.Errorf("failed to delete blob %s: %w", hash, err)
 	}
 
-	contentTree.Delete(hashBytes)
-
-	graviton.Commit(contentTree)
+	graviton.Commit(refTrees.All()...)
 
 	return nil
 }
 
-func (store *GravitonMemoryStore) SaveSubscriber(subscriber *types.Subscriber) error {
-	// Load the snapshot and get the "subscribers" tree
+// Blobs that have been converted into scionic merkle trees are mapped from their sha256 hash to the dag root
+// The relay signs these trees so the tree is owned by the blob instead of the relay and the blob by its uploader
+func (store *GravitonMemoryStore) SetBlobDag(hash string, root string, publicKey string) error {
+	store.refsMutex.Lock()
+	defer store.refsMutex.Unlock()
+
+	rootData, err := store.RetrieveLeaf(root, root, false)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve dag root: %v", err)
+	}
+
 	snapshot, err := store.Database.LoadSnapshot(0)
 	if err != nil {
 		return fmt.Errorf("failed to load snapshot: %v", err)
 	}
 
-	subscriberTree, err := snapshot.GetTree("subscribers")
+	refTrees := refs.NewTrees(snapshot)
+
+	blobDagTree, err := refTrees.Get("blob_dags")
 	if err != nil {
-		return fmt.Errorf("failed to get subscribers tree: %v", err)
+		return fmt.Errorf("failed to get blob dags tree: %v", err)
 	}
 
-	// Marshal the subscriber into JSON
-	subscriberData, err := json.Marshal(subscriber)
+	if err := blobDagTree.Put([]byte(hash), []byte(root)); err != nil {
+		return fmt.Errorf("failed to put blob dag: %v", err)
+	}
+
+	refsTree, err := refTrees.Get(refs.Bucket)
 	if err != nil {
-		return fmt.Errorf("failed to marshal subscriber: %v", err)
+		return err
 	}
 
-	// Use the npub as the key for storing the subscriber
-	key := subscriber.Npub
+	if err := refs.Add(refsTree, refs.BlobSubject(hash), publicKey); err != nil {
+		return err
+	}
 
-	// Store the subscriber data in the tree
-	if err := subscriberTree.Put([]byte(key), subscriberData); err != nil {
-		return fmt.Errorf("failed to put subscriber in Graviton store: %v", err)
+	if err := refs.AddDag(refTrees, root, refs.BlobSubject(hash)); err != nil {
+		return err
 	}
 
-	// Commit the tree to persist the changes
-	if _, err := graviton.Commit(subscriberTree); err != nil {
-		return fmt.Errorf("failed to commit subscribers tree: %v", err)
+	if _, err := refs.Remove(refsTree, refs.DagSubject(root), rootData.PublicKey); err != nil && !errors.Is(err, refs.ErrNotOwner) {
+		return err
+	}
+
+	if _, err := graviton.Commit(refTrees.All()...); err != nil {
+		return fmt.Errorf("failed to commit blob dags tree: %v", err)
 	}
 
 	return nil
 }
 
-func (store *GravitonMemoryStore) GetSubscriberByAddress(address string) (*types.Subscriber, error) {
+func (store *GravitonMemoryStore) GetBlobDag(hash string) (string, error) {
 	snapshot, err := store.Database.LoadSnapshot(0)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load snapshot: %v", err)
+		return "", fmt.Errorf("failed to load snapshot: %v", err)
 	}
 
-	subscriberTree, err := snapshot.GetTree("subscribers")
+	blobDagTree, err := snapshot.GetTree("blob_dags")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get subscribers tree: %v", err)
+		return "", fmt.Errorf("failed to get blob dags tree: %v", err)
 	}
 
-	// Iterate over subscribers to find the one associated with the address
-	cursor := subscriberTree.Cursor()
-	for _, v, err := cursor.First(); err == nil; _, v, err = cursor.Next() {
-		var subscriber types.Subscriber
-		if err := json.Unmarshal(v, &subscriber); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal subscriber data: %v", err)
-		}
+	root, err := blobDagTree.Get([]byte(hash))
+	if err != nil || root == nil {
+		return "", fmt.Errorf("no dag found for blob %s", hash)
+	}
 
-		// Assuming the subscriber has a list of addresses
+	return string(root), nil
+}
 
-		if subscriber.Address == address {
-			return &subscriber, nil
-		}
+// Blobs aren't cached against their uploader in the memory store
+func (store *GravitonMemoryStore) GetBlobsByPubkey(publicKey string) ([]string, error) {
+	return []string{}, nil
+}
+
+// Trees aren't reliably cached against their uploader in the memory store
+func (store *GravitonMemoryStore) GetDagsByPubkey(publicKey string) ([]string, error) {
+	return []string{}, nil
+}
+
+// Removes the reference the public key holds to a scionic merkletree, leaves and content shared with
+// another tree or blob are kept
+func (store *GravitonMemoryStore) DeleteDag(root string, publicKey string) error {
+	store.refsMutex.Lock()
+	defer store.refsMutex.Unlock()
+
+	snapshot, _ := store.Database.LoadSnapshot(0)
+	refTrees := refs.NewTrees(snapshot)
+
+	if _, err := refs.ReleaseDag(refTrees, "root_index", root, publicKey); err != nil {
+		return fmt.Errorf("failed to delete dag %s: %w", root, err)
 	}
 
-	return nil, fmt.Errorf("subscriber not found for address: %s", address)
+	graviton.Commit(refTrees.All()...)
+
+	return nil
 }
 
-func (store *GravitonMemoryStore) GetSubscriber(npub string) (*types.Subscriber, error) {
+func (store *GravitonMemoryStore) ClearPubkeyCache(publicKey string) error {
+	return usage.Clear(store.Database, publicKey)
+}
+
+// Returns the number of bytes the public key has uploaded to the relay
+func (store *GravitonMemoryStore) GetStorageUsage(publicKey string) (int64, error) {
+	return usage.Get(store.Database, publicKey)
+}
+
+// Adds the delta (which can be negative when data is deleted) to the storage usage of the public key
+func (store *GravitonMemoryStore) UpdateStorageUsage(publicKey string, delta int64) error {
+	return usage.Update(store.Database, publicKey, delta)
+}
+
+func (store *GravitonMemoryStore) SaveSubscriber(subscriber *types.Subscriber) error {
+	return subscribers.Save(store.Database, subscriber)
+}
+
+func (store *GravitonMemoryStore) GetSubscriberByAddress(address string) (*types.Subscriber, error) {
 	snapshot, err := store.Database.LoadSnapshot(0)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load snapshot: %v", err)
@@ -531,7 +627,7 @@ func (store *GravitonMemoryStore) GetSubscriber(npub string) (*types.Subscriber,
 		return nil, fmt.Errorf("failed to get subscribers tree: %v", err)
 	}
 
-	// Iterate over subscribers to find the one with the matching npub
+	// Iterate over subscribers to find the one associated with the address
 	cursor := subscriberTree.Cursor()
 	for _, v, err := cursor.First(); err == nil; _, v, err = cursor.Next() {
 		var subscriber types.Subscriber
@@ -539,14 +635,28 @@ func (store *GravitonMemoryStore) GetSubscriber(npub string) (*types.Subscriber,
 			return nil, fmt.Errorf("failed to unmarshal subscriber data: %v", err)
 		}
 
-		// Check if the current subscriber's npub matches the provided npub
-		if subscriber.Npub == npub {
+		// Assuming the subscriber has a list of addresses
+
+		if subscriber.Address == address {
 			return &subscriber, nil
 		}
 	}
 
-	// If no subscriber was found with the matching npub, return an error
-	return nil, fmt.Errorf("subscriber not found for npub: %s", npub)
+	return nil, fmt.Errorf("subscriber not found for address: %s", address)
+}
+
+func (store *GravitonMemoryStore) GetSubscriber(npub string) (*types.Subscriber, error) {
+	return subscribers.Get(store.Database, npub)
+}
+
+// Returns the subscriber the NIP-05 name is registered to
+func (store *GravitonMemoryStore) GetSubscriberByName(name string) (*types.Subscriber, error) {
+	return subscribers.GetByName(store.Database, name)
+}
+
+// Returns every subscriber with a registered NIP-05 name
+func (store *GravitonMemoryStore) GetNamedSubscribers() ([]*types.Subscriber, error) {
+	return subscribers.GetNamed(store.Database)
 }
 
 // AllocateBitcoinAddress allocates an available Bitcoin address to a subscriber.
@@ -0,0 +1,328 @@
+package refs
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/deroproject/graviton"
+	"github.com/fxamacker/cbor/v2"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	merkle_dag "github.com/HORNET-Storage/scionic-merkletree/dag"
+)
+
+// Blobs, scionic merkletree leaves and leaf content are content addressed so the same data can be stored by
+// several owners, every owner holds a reference in the refs bucket and data is only deleted with the last one:
+//   - a blob is owned by the public keys that uploaded it and owns its content
+//   - a scionic merkletree is owned by the public keys that uploaded it, or by the blob it was converted from
+//   - a leaf is owned by the roots of the trees it is part of and owns its content
+
+const (
+	Bucket        = "refs"
+	ContentBucket = "content"
+	BlobDagBucket = "blob_dags"
+)
+
+var ErrNotOwner = errors.New("not owned by the public key")
+
+func BlobSubject(hash string) string {
+	return "blob:" + hash
+}
+
+func DagSubject(root string) string {
+	return "dag:" + root
+}
+
+func LeafSubject(bucket string, hash string) string {
+	return fmt.Sprintf("leaf:%s:%s", bucket, hash)
+}
+
+func ContentSubject(hash []byte) string {
+	return "content:" + hex.EncodeToString(hash)
+}
+
+// Trees loads each tree of a snapshot once so every change made to it is kept when they are committed together
+type Trees struct {
+	snapshot *graviton.Snapshot
+	trees    map[string]*graviton.Tree
+	order    []string
+}
+
+func NewTrees(snapshot *graviton.Snapshot) *Trees {
+	return &Trees{snapshot: snapshot, trees: map[string]*graviton.Tree{}}
+}
+
+func (t *Trees) Get(bucket string) (*graviton.Tree, error) {
+	if tree, ok := t.trees[bucket]; ok {
+		return tree, nil
+	}
+
+	tree, err := t.snapshot.GetTree(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	t.trees[bucket] = tree
+	t.order = append(t.order, bucket)
+
+	return tree, nil
+}
+
+// All returns every tree that was loaded so they can be committed together
+func (t *Trees) All() []*graviton.Tree {
+	trees := make([]*graviton.Tree, 0, len(t.order))
+	for _, bucket := range t.order {
+		trees = append(trees, t.trees[bucket])
+	}
+
+	return trees
+}
+
+// Owners returns every owner holding a reference to the subject
+func Owners(tree *graviton.Tree, subject string) []string {
+	value, err := tree.Get([]byte(subject))
+	if err != nil || value == nil {
+		return nil
+	}
+
+	var cacheData types.CacheData
+	if err := cbor.Unmarshal(value, &cacheData); err != nil {
+		return nil
+	}
+
+	return cacheData.Keys
+}
+
+// Add records the owner as holding a reference to the subject, adding the same owner twice keeps one reference
+func Add(tree *graviton.Tree, subject string, owner string) error {
+	owners := Owners(tree, subject)
+	if slices.Contains(owners, owner) {
+		return nil
+	}
+
+	return put(tree, subject, append(owners, owner))
+}
+
+// Remove drops the reference the owner holds to the subject and returns how many owners are left,
+// ErrNotOwner is returned when the owner held no reference
+func Remove(tree *graviton.Tree, subject string, owner string) (int, error) {
+	owners := Owners(tree, subject)
+	if !slices.Contains(owners, owner) {
+		return len(owners), ErrNotOwner
+	}
+
+	owners = slices.DeleteFunc(owners, func(existing string) bool {
+		return existing == owner
+	})
+
+	if len(owners) == 0 {
+		return 0, tree.Delete([]byte(subject))
+	}
+
+	return len(owners), put(tree, subject, owners)
+}
+
+// AddBlob records the public key as an owner of the blob and the blob as the owner of its content
+func AddBlob(trees *Trees, hash string, publicKey string) error {
+	hashBytes, err := hex.DecodeString(hash)
+	if err != nil {
+		return err
+	}
+
+	refsTree, err := trees.Get(Bucket)
+	if err != nil {
+		return err
+	}
+
+	if err := Add(refsTree, BlobSubject(hash), publicKey); err != nil {
+		return err
+	}
+
+	return Add(refsTree, ContentSubject(hashBytes), BlobSubject(hash))
+}
+
+// AddDag records an owner of a scionic merkletree
+func AddDag(trees *Trees, root string, owner string) error {
+	refsTree, err := trees.Get(Bucket)
+	if err != nil {
+		return err
+	}
+
+	return Add(refsTree, DagSubject(root), owner)
+}
+
+// AddLeaf records the root as an owner of the leaf and the leaf as the owner of its content
+func AddLeaf(trees *Trees, bucket string, root string, leaf *merkle_dag.DagLeaf) error {
+	refsTree, err := trees.Get(Bucket)
+	if err != nil {
+		return err
+	}
+
+	subject := LeafSubject(bucket, leaf.Hash)
+	if err := Add(refsTree, subject, root); err != nil {
+		return err
+	}
+
+	if leaf.ContentHash == nil {
+		return nil
+	}
+
+	return Add(refsTree, ContentSubject(leaf.ContentHash), subject)
+}
+
+// ReleaseBlob drops the reference the public key holds to a blob, once no one owns the blob its content
+// is released along with the scionic merkletree it was converted into
+func ReleaseBlob(trees *Trees, indexBucket string, hash string, publicKey string) (bool, error) {
+	hashBytes, err := hex.DecodeString(hash)
+	if err != nil {
+		return false, err
+	}
+
+	refsTree, err := trees.Get(Bucket)
+	if err != nil {
+		return false, err
+	}
+
+	remaining, err := Remove(refsTree, BlobSubject(hash), publicKey)
+	if err != nil || remaining > 0 {
+		return false, err
+	}
+
+	if err := releaseContent(trees, hashBytes, BlobSubject(hash)); err != nil {
+		return false, err
+	}
+
+	blobDagTree, err := trees.Get(BlobDagBucket)
+	if err != nil {
+		return false, err
+	}
+
+	if root, err := blobDagTree.Get([]byte(hash)); err == nil && root != nil {
+		if _, err := ReleaseDag(trees, indexBucket, string(root), BlobSubject(hash)); err != nil && !errors.Is(err, ErrNotOwner) {
+			return false, err
+		}
+
+		blobDagTree.Delete([]byte(hash))
+	}
+
+	return true, nil
+}
+
+// ReleaseDag drops the reference an owner holds to a scionic merkletree, once no one owns the tree every leaf
+// that isn't part of another tree is deleted along with content nothing else references, true is returned
+// when the tree itself was deleted
+func ReleaseDag(trees *Trees, indexBucket string, root string, owner string) (bool, error) {
+	refsTree, err := trees.Get(Bucket)
+	if err != nil {
+		return false, err
+	}
+
+	remaining, err := Remove(refsTree, DagSubject(root), owner)
+	if err != nil || remaining > 0 {
+		return false, err
+	}
+
+	indexTree, err := trees.Get(indexBucket)
+	if err != nil {
+		return false, err
+	}
+
+	bucket, err := indexTree.Get([]byte(root))
+	if err != nil || bucket == nil {
+		return false, fmt.Errorf("dag %s not found", root)
+	}
+
+	tree, err := trees.Get(string(bucket))
+	if err != nil {
+		return false, err
+	}
+
+	// Leaves shared with another tree are kept but their children are still visited as the
+	// root holds a reference to every leaf below it
+	visited := map[string]bool{}
+	pending := []string{root}
+	for len(pending) > 0 {
+		hash := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if visited[hash] {
+			continue
+		}
+		visited[hash] = true
+
+		value, err := tree.Get([]byte(hash))
+		if err != nil || value == nil {
+			continue
+		}
+
+		var data types.DagLeafData
+		if err := cbor.Unmarshal(value, &data); err != nil {
+			continue
+		}
+
+		for _, child := range data.Leaf.Links {
+			pending = append(pending, child)
+		}
+
+		subject := LeafSubject(string(bucket), hash)
+		remaining, err := Remove(refsTree, subject, root)
+		if err != nil && !errors.Is(err, ErrNotOwner) {
+			return false, err
+		}
+
+		if remaining > 0 {
+			continue
+		}
+
+		if data.Leaf.ContentHash != nil {
+			if err := releaseContent(trees, data.Leaf.ContentHash, subject); err != nil {
+				return false, err
+			}
+		}
+
+		if err := tree.Delete([]byte(hash)); err != nil {
+			return false, err
+		}
+	}
+
+	indexTree.Delete([]byte(root))
+
+	return true, nil
+}
+
+// Content is only deleted once neither a blob nor a leaf references it
+func releaseContent(trees *Trees, hash []byte, owner string) error {
+	refsTree, err := trees.Get(Bucket)
+	if err != nil {
+		return err
+	}
+
+	remaining, err := Remove(refsTree, ContentSubject(hash), owner)
+	if err != nil && !errors.Is(err, ErrNotOwner) {
+		return err
+	}
+
+	if remaining > 0 {
+		return nil
+	}
+
+	contentTree, err := trees.Get(ContentBucket)
+	if err != nil {
+		return err
+	}
+
+	contentTree.Delete(hash)
+
+	return nil
+}
+
+func put(tree *graviton.Tree, subject string, owners []string) error {
+	serializedData, err := cbor.Marshal(&types.CacheData{Keys: owners})
+	if err != nil {
+		return err
+	}
+
+	return tree.Put([]byte(subject), serializedData)
+}
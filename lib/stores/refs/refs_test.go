@@ -0,0 +1,265 @@
+package refs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/deroproject/graviton"
+	"github.com/fxamacker/cbor/v2"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	merkle_dag "github.com/HORNET-Storage/scionic-merkletree/dag"
+)
+
+const (
+	indexBucket = "scionic_index"
+	leafBucket  = "file"
+)
+
+func newTrees(t *testing.T, database *graviton.Store) *Trees {
+	snapshot, err := database.LoadSnapshot(0)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+
+	return NewTrees(snapshot)
+}
+
+func commit(t *testing.T, trees *Trees) {
+	if _, err := graviton.Commit(trees.All()...); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+}
+
+func exists(t *testing.T, database *graviton.Store, bucket string, key []byte) bool {
+	tree, err := newTrees(t, database).Get(bucket)
+	if err != nil {
+		t.Fatalf("failed to get tree: %v", err)
+	}
+
+	value, err := tree.Get(key)
+	return err == nil && value != nil
+}
+
+func contentHash(content string) []byte {
+	hash := sha256.Sum256([]byte(content))
+	return hash[:]
+}
+
+// Stores a tree made of a root linking to the given leaves, every leaf has content named after its hash
+func storeDag(t *testing.T, database *graviton.Store, root string, owner string, leaves ...string) {
+	trees := newTrees(t, database)
+
+	tree, _ := trees.Get(leafBucket)
+	contentTree, _ := trees.Get(ContentBucket)
+	indexTree, _ := trees.Get(indexBucket)
+
+	links := map[string]string{}
+	for _, hash := range leaves {
+		links[hash] = hash
+	}
+
+	all := append([]string{root}, leaves...)
+	for _, hash := range all {
+		leaf := merkle_dag.DagLeaf{Hash: hash, ContentHash: contentHash(hash)}
+		if hash == root {
+			leaf.Links = links
+		}
+
+		data, _ := cbor.Marshal(&types.DagLeafData{Leaf: leaf})
+		tree.Put([]byte(hash), data)
+		contentTree.Put(leaf.ContentHash, []byte(hash))
+
+		if err := AddLeaf(trees, leafBucket, root, &leaf); err != nil {
+			t.Fatalf("failed to add leaf: %v", err)
+		}
+	}
+
+	indexTree.Put([]byte(root), []byte(leafBucket))
+
+	if err := AddDag(trees, root, owner); err != nil {
+		t.Fatalf("failed to add dag: %v", err)
+	}
+
+	commit(t, trees)
+}
+
+func storeBlob(t *testing.T, database *graviton.Store, content string, owner string) string {
+	trees := newTrees(t, database)
+
+	hash := contentHash(content)
+	contentTree, _ := trees.Get(ContentBucket)
+	contentTree.Put(hash, []byte(content))
+
+	if err := AddBlob(trees, hex.EncodeToString(hash), owner); err != nil {
+		t.Fatalf("failed to add blob: %v", err)
+	}
+
+	commit(t, trees)
+
+	return hex.EncodeToString(hash)
+}
+
+func TestAddRemove(t *testing.T) {
+	database, _ := graviton.NewMemStore()
+	trees := newTrees(t, database)
+	tree, _ := trees.Get(Bucket)
+
+	tests := []struct {
+		name      string
+		add       []string
+		remove    string
+		remaining int
+		err       error
+	}{
+		{"last owner", []string{"alice"}, "alice", 0, nil},
+		{"shared", []string{"alice", "bob"}, "alice", 1, nil},
+		{"added twice", []string{"alice", "alice", "bob"}, "bob", 1, nil},
+		{"not an owner", []string{"alice"}, "bob", 1, ErrNotOwner},
+		{"no owners", nil, "alice", 0, ErrNotOwner},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subject := BlobSubject(test.name)
+			for _, owner := range test.add {
+				if err := Add(tree, subject, owner); err != nil {
+					t.Fatalf("failed to add owner: %v", err)
+				}
+			}
+
+			remaining, err := Remove(tree, subject, test.remove)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+
+			if remaining != test.remaining || len(Owners(tree, subject)) != test.remaining {
+				t.Errorf("expected %d owners left, got %d", test.remaining, remaining)
+			}
+		})
+	}
+}
+
+func TestReleaseBlob(t *testing.T) {
+	database, _ := graviton.NewMemStore()
+
+	hash := storeBlob(t, database, "shared blob", "alice")
+	storeBlob(t, database, "shared blob", "bob")
+	hashBytes, _ := hex.DecodeString(hash)
+
+	tests := []struct {
+		name     string
+		owner    string
+		err      error
+		released bool
+		kept     bool
+	}{
+		{"someone who never uploaded it", "mallory", ErrNotOwner, false, true},
+		{"first uploader", "alice", nil, false, true},
+		{"first uploader again", "alice", ErrNotOwner, false, true},
+		{"last uploader", "bob", nil, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trees := newTrees(t, database)
+
+			released, err := ReleaseBlob(trees, indexBucket, hash, test.owner)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+
+			commit(t, trees)
+
+			if released != test.released {
+				t.Errorf("expected released %v, got %v", test.released, released)
+			}
+
+			if kept := exists(t, database, ContentBucket, hashBytes); kept != test.kept {
+				t.Errorf("expected content kept %v, got %v", test.kept, kept)
+			}
+		})
+	}
+}
+
+func TestReleaseDagKeepsSharedLeaves(t *testing.T) {
+	database, _ := graviton.NewMemStore()
+
+	storeDag(t, database, "root-a", "alice", "leaf-shared", "leaf-a")
+	storeDag(t, database, "root-b", "bob", "leaf-shared", "leaf-b")
+
+	// A blob with the same content as a leaf shares its content
+	storeBlob(t, database, "leaf-a", "carol")
+
+	trees := newTrees(t, database)
+	if _, err := ReleaseDag(trees, indexBucket, "root-a", "bob"); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("expected a tree owned by someone else to be refused, got %v", err)
+	}
+
+	deleted, err := ReleaseDag(trees, indexBucket, "root-a", "alice")
+	if err != nil || !deleted {
+		t.Fatalf("expected the tree to be deleted, got %v %v", deleted, err)
+	}
+
+	commit(t, trees)
+
+	tests := []struct {
+		name   string
+		bucket string
+		key    []byte
+		kept   bool
+	}{
+		{"released root", leafBucket, []byte("root-a"), false},
+		{"leaf only in the released tree", leafBucket, []byte("leaf-a"), false},
+		{"leaf shared with another tree", leafBucket, []byte("leaf-shared"), true},
+		{"content of the shared leaf", ContentBucket, contentHash("leaf-shared"), true},
+		{"content shared with a blob", ContentBucket, contentHash("leaf-a"), true},
+		{"content of the released root", ContentBucket, contentHash("root-a"), false},
+		{"index entry of the released root", indexBucket, []byte("root-a"), false},
+		{"other tree", leafBucket, []byte("root-b"), true},
+		{"other tree leaf", leafBucket, []byte("leaf-b"), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if kept := exists(t, database, test.bucket, test.key); kept != test.kept {
+				t.Errorf("expected kept %v, got %v", test.kept, kept)
+			}
+		})
+	}
+}
+
+func TestReleaseBlobReleasesItsDag(t *testing.T) {
+	database, _ := graviton.NewMemStore()
+
+	storeDag(t, database, "root", "relay", "chunk")
+
+	// The tree is handed from the relay to the blob it was converted from
+	hash := hex.EncodeToString(contentHash("large blob"))
+	trees := newTrees(t, database)
+	blobDagTree, _ := trees.Get(BlobDagBucket)
+	blobDagTree.Put([]byte(hash), []byte("root"))
+	refsTree, _ := trees.Get(Bucket)
+	Add(refsTree, BlobSubject(hash), "alice")
+	AddDag(trees, "root", BlobSubject(hash))
+	Remove(refsTree, DagSubject("root"), "relay")
+	commit(t, trees)
+
+	trees = newTrees(t, database)
+	if _, err := ReleaseBlob(trees, indexBucket, hash, "alice"); err != nil {
+		t.Fatalf("failed to release blob: %v", err)
+	}
+	commit(t, trees)
+
+	for _, key := range []string{"root", "chunk"} {
+		if exists(t, database, leafBucket, []byte(key)) {
+			t.Errorf("expected leaf %s to be deleted with the blob", key)
+		}
+	}
+
+	if exists(t, database, BlobDagBucket, []byte(hash)) {
+		t.Errorf("expected the blob dag mapping to be deleted")
+	}
+}
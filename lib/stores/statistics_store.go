@@ -61,6 +61,40 @@ type StatisticsStore interface {
 	DeleteActiveToken(token string) error
 	IsActiveToken(token string) (bool, error)
 
+	// Hash blocklist
+	AddBlockedHash(blockedHash *types.BlockedHash) error
+	RemoveBlockedHash(hash string) error
+	IsHashBlocked(hash string) (bool, error)
+	GetBlockedHashes(hashType string) ([]types.BlockedHash, error)
+	SaveBlocklistAudit(entry *types.BlocklistAudit) error
+	GetBlocklistAudit() ([]types.BlocklistAudit, error)
+
+	// Relay management lists
+	AddModerationEntry(entry *types.ModerationEntry) error
+	RemoveModerationEntry(list string, value string) error
+	IsModerated(list string, value string) (bool, error)
+	GetModerationEntries(list string) ([]types.ModerationEntry, error)
+
+	// Report moderation queue
+	SaveReport(report *types.Report) error
+	GetReports(targetType string, target string) ([]types.Report, error)
+	SaveReportTarget(target *types.ReportTarget) error
+	GetReportTarget(targetType string, target string) (*types.ReportTarget, error)
+	GetReportTargets(status string) ([]types.ReportTarget, error)
+
+	// Reaction, repost and zap aggregates
+	UpdateEventAggregate(change *types.EventAggregate) error
+	GetEventAggregate(eventID string) (*types.EventAggregate, error)
+	ClearEventAggregates() error
+
+	// Request to vanish
+	SaveVanishRequest(request *types.VanishRequest) error
+	GetVanishRequests() ([]types.VanishRequest, error)
+	GetPendingVanishRequests() ([]types.VanishRequest, error)
+	GetVanishedUntil(pubkey string) (int64, error)
+	DeletePubkeyStats(pubkey string) error
+	DeleteFileStats(hash string) error
+
 	// Statistics and storage stats
 	FetchMonthlyStorageStats() ([]types.ActivityData, error)
 	FetchNotesMediaStorageData() ([]types.BarChartData, error)
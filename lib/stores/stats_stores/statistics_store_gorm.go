@@ -46,6 +46,13 @@ func (store *GormStatisticsStore) InitStore(basepath string, args ...interface{}
 		&types.Audio{},
 		&types.PendingTransaction{},
 		&types.ActiveToken{},
+		&types.BlockedHash{},
+		&types.BlocklistAudit{},
+		&types.ModerationEntry{},
+		&types.Report{},
+		&types.ReportTarget{},
+		&types.EventAggregate{},
+		&types.VanishRequest{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database schema: %v", err)
@@ -844,3 +851,269 @@ func (store *GormStatisticsStore) IsActiveToken(token string) (bool, error) {
 	}
 	return true, nil
 }
+
+// AddBlockedHash adds a hash to the blocklist, adding a hash that is already blocked updates its reason
+func (store *GormStatisticsStore) AddBlockedHash(blockedHash *types.BlockedHash) error {
+	var existing types.BlockedHash
+	result := store.DB.Where("hash = ?", blockedHash.Hash).First(&existing)
+	if result.Error == nil {
+		return store.DB.Model(&existing).Updates(map[string]interface{}{
+			"hash_type": blockedHash.HashType,
+			"reason":    blockedHash.Reason,
+		}).Error
+	}
+	if result.Error != gorm.ErrRecordNotFound {
+		return result.Error
+	}
+
+	return store.DB.Create(blockedHash).Error
+}
+
+// RemoveBlockedHash removes a hash from the blocklist
+func (store *GormStatisticsStore) RemoveBlockedHash(hash string) error {
+	return store.DB.Where("hash = ?", hash).Delete(&types.BlockedHash{}).Error
+}
+
+// IsHashBlocked checks if an exact hash is on the blocklist
+func (store *GormStatisticsStore) IsHashBlocked(hash string) (bool, error) {
+	var count int64
+	err := store.DB.Model(&types.BlockedHash{}).Where("hash = ?", hash).Count(&count).Error
+	return count > 0, err
+}
+
+// GetBlockedHashes retrieves the blocklist, an empty hash type returns every entry
+func (store *GormStatisticsStore) GetBlockedHashes(hashType string) ([]types.BlockedHash, error) {
+	var blockedHashes []types.BlockedHash
+
+	query := store.DB.Order("timestamp desc")
+	if hashType != "" {
+		query = query.Where("hash_type = ?", hashType)
+	}
+
+	if err := query.Find(&blockedHashes).Error; err != nil {
+		return nil, err
+	}
+
+	return blockedHashes, nil
+}
+
+// SaveBlocklistAudit records a change made to the blocklist
+func (store *GormStatisticsStore) SaveBlocklistAudit(entry *types.BlocklistAudit) error {
+	return store.DB.Create(entry).Error
+}
+
+// GetBlocklistAudit retrieves every blocklist change, newest first
+func (store *GormStatisticsStore) GetBlocklistAudit() ([]types.BlocklistAudit, error) {
+	var entries []types.BlocklistAudit
+	if err := store.DB.Order("timestamp desc").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// AddModerationEntry adds a value to a relay management list or updates the reason if it's already there
+func (store *GormStatisticsStore) AddModerationEntry(entry *types.ModerationEntry) error {
+	var existing types.ModerationEntry
+	result := store.DB.Where("list = ? AND value = ?", entry.List, entry.Value).First(&existing)
+	if result.Error == nil {
+		return store.DB.Model(&existing).Update("reason", entry.Reason).Error
+	}
+	if result.Error != gorm.ErrRecordNotFound {
+		return result.Error
+	}
+
+	return store.DB.Create(entry).Error
+}
+
+// RemoveModerationEntry removes a value from a relay management list
+func (store *GormStatisticsStore) RemoveModerationEntry(list string, value string) error {
+	return store.DB.Where("list = ? AND value = ?", list, value).Delete(&types.ModerationEntry{}).Error
+}
+
+// IsModerated checks if a value is on a relay management list
+func (store *GormStatisticsStore) IsModerated(list string, value string) (bool, error) {
+	var count int64
+	err := store.DB.Model(&types.ModerationEntry{}).Where("list = ? AND value = ?", list, value).Count(&count).Error
+	return count > 0, err
+}
+
+// GetModerationEntries retrieves every value on a relay management list, newest first
+func (store *GormStatisticsStore) GetModerationEntries(list string) ([]types.ModerationEntry, error) {
+	var entries []types.ModerationEntry
+	if err := store.DB.Where("list = ?", list).Order("timestamp desc").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// SaveReport records a report, a reporter reporting the same target again replaces their earlier report
+func (store *GormStatisticsStore) SaveReport(report *types.Report) error {
+	var existing types.Report
+	result := store.DB.Where("target_type = ? AND target = ? AND reporter = ?", report.TargetType, report.Target, report.Reporter).First(&existing)
+	if result.Error == nil {
+		report.ID = existing.ID
+		return store.DB.Save(report).Error
+	}
+	if result.Error != gorm.ErrRecordNotFound {
+		return result.Error
+	}
+
+	return store.DB.Create(report).Error
+}
+
+// GetReports retrieves every report against a target, newest first
+func (store *GormStatisticsStore) GetReports(targetType string, target string) ([]types.Report, error) {
+	var reports []types.Report
+	if err := store.DB.Where("target_type = ? AND target = ?", targetType, target).Order("timestamp desc").Find(&reports).Error; err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+// SaveReportTarget creates or updates the aggregated reports for a target
+func (store *GormStatisticsStore) SaveReportTarget(target *types.ReportTarget) error {
+	return store.DB.Save(target).Error
+}
+
+// GetReportTarget retrieves the aggregated reports for a target
+func (store *GormStatisticsStore) GetReportTarget(targetType string, target string) (*types.ReportTarget, error) {
+	var reportTarget types.ReportTarget
+	if err := store.DB.Where("target_type = ? AND target = ?", targetType, target).First(&reportTarget).Error; err != nil {
+		return nil, err
+	}
+
+	return &reportTarget, nil
+}
+
+// GetReportTargets retrieves the targets with a status, or every target when the status is empty, highest score first
+func (store *GormStatisticsStore) GetReportTargets(status string) ([]types.ReportTarget, error) {
+	query := store.DB.Order("score desc")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var targets []types.ReportTarget
+	if err := query.Find(&targets).Error; err != nil {
+		return nil, err
+	}
+
+	return targets, nil
+}
+
+// UpdateEventAggregate adds the counts in the change to the totals for the event, negative counts are used
+// when events are deleted and totals that reach zero are removed
+func (store *GormStatisticsStore) UpdateEventAggregate(change *types.EventAggregate) error {
+	return store.DB.Transaction(func(tx *gorm.DB) error {
+		var aggregate types.EventAggregate
+		result := tx.Where("event_id = ?", change.EventID).First(&aggregate)
+		if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+			return result.Error
+		}
+
+		aggregate.EventID = change.EventID
+		aggregate.Reactions += change.Reactions
+		aggregate.Reposts += change.Reposts
+		aggregate.GenericReposts += change.GenericReposts
+		aggregate.Zaps += change.Zaps
+		aggregate.ZapMsats += change.ZapMsats
+
+		if aggregate.ReactionCounts == nil {
+			aggregate.ReactionCounts = map[string]int64{}
+		}
+		for content, count := range change.ReactionCounts {
+			aggregate.ReactionCounts[content] += count
+			if aggregate.ReactionCounts[content] <= 0 {
+				delete(aggregate.ReactionCounts, content)
+			}
+		}
+
+		if aggregate.Reactions <= 0 && aggregate.Reposts <= 0 && aggregate.GenericReposts <= 0 && aggregate.Zaps <= 0 {
+			return tx.Where("event_id = ?", change.EventID).Delete(&types.EventAggregate{}).Error
+		}
+
+		return tx.Save(&aggregate).Error
+	})
+}
+
+// GetEventAggregate retrieves the totals for an event, events without reactions, reposts or zaps have empty totals
+func (store *GormStatisticsStore) GetEventAggregate(eventID string) (*types.EventAggregate, error) {
+	var aggregate types.EventAggregate
+	result := store.DB.Where("event_id = ?", eventID).First(&aggregate)
+	if result.Error == gorm.ErrRecordNotFound {
+		return &types.EventAggregate{EventID: eventID, ReactionCounts: map[string]int64{}}, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &aggregate, nil
+}
+
+// ClearEventAggregates removes every aggregate so they can be rebuilt from the stored events
+func (store *GormStatisticsStore) ClearEventAggregates() error {
+	return store.DB.Where("1 = 1").Delete(&types.EventAggregate{}).Error
+}
+
+// SaveVanishRequest records a request to vanish, saving the same request again updates what was deleted
+func (store *GormStatisticsStore) SaveVanishRequest(request *types.VanishRequest) error {
+	var existing types.VanishRequest
+	result := store.DB.Where("event_id = ?", request.EventID).First(&existing)
+	if result.Error == nil {
+		request.ID = existing.ID
+		request.Timestamp = existing.Timestamp
+		return store.DB.Save(request).Error
+	}
+	if result.Error != gorm.ErrRecordNotFound {
+		return result.Error
+	}
+
+	return store.DB.Create(request).Error
+}
+
+// GetVanishRequests retrieves every request to vanish, newest first
+func (store *GormStatisticsStore) GetVanishRequests() ([]types.VanishRequest, error) {
+	var requests []types.VanishRequest
+	if err := store.DB.Order("timestamp desc").Find(&requests).Error; err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// GetPendingVanishRequests retrieves the requests to vanish that haven't been completed, oldest first
+func (store *GormStatisticsStore) GetPendingVanishRequests() ([]types.VanishRequest, error) {
+	var requests []types.VanishRequest
+	if err := store.DB.Where("completed = ?", false).Order("timestamp asc").Find(&requests).Error; err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// GetVanishedUntil returns the created_at of the latest request to vanish by a pubkey, 0 if it never asked to vanish
+func (store *GormStatisticsStore) GetVanishedUntil(pubkey string) (int64, error) {
+	var until int64
+	err := store.DB.Model(&types.VanishRequest{}).Where("pubkey = ?", pubkey).Select("COALESCE(MAX(until), 0)").Scan(&until).Error
+	return until, err
+}
+
+// DeletePubkeyStats removes the profile statistics of a pubkey, the event statistics are removed with each event
+func (store *GormStatisticsStore) DeletePubkeyStats(pubkey string) error {
+	return store.DB.Where("npub_key = ?", pubkey).Delete(&types.UserProfile{}).Error
+}
+
+// DeleteFileStats removes the file statistics recorded for a scionic merkletree root
+func (store *GormStatisticsStore) DeleteFileStats(hash string) error {
+	return store.DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&types.Photo{}, &types.Video{}, &types.Audio{}, &types.Misc{}} {
+			if err := tx.Where("hash = ?", hash).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -1,6 +1,7 @@
 package stores
 
 import (
+	"fmt"
 	"log"
 
 	types "github.com/HORNET-Storage/hornet-storage/lib"
@@ -21,6 +22,8 @@ type Store interface {
 	StoreDag(dag *types.DagData) error
 	BuildDagFromStore(root string, includeContent bool) (*types.DagData, error)
 	RetrieveLeafContent(contentHash []byte) ([]byte, error)
+	GetDagsByPubkey(publicKey string) ([]string, error)
+	DeleteDag(root string, publicKey string) error
 
 	// Nostr
 	QueryEvents(filter nostr.Filter) ([]*nostr.Event, error)
@@ -30,11 +33,25 @@ type Store interface {
 	// Blossom
 	StoreBlob(data []byte, hash []byte, publicKey string) error
 	GetBlob(hash string) ([]byte, error)
-	DeleteBlob(hash string) error
+	DeleteBlob(hash string, publicKey string) error
+	SetBlobDag(hash string, root string, publicKey string) error
+	GetBlobDag(hash string) (string, error)
+	GetBlobsByPubkey(publicKey string) ([]string, error)
+
+	// Uploads
+	GetStorageUsage(publicKey string) (int64, error)
+	UpdateStorageUsage(publicKey string, delta int64) error
+	ClearPubkeyCache(publicKey string) error
+
+	// Compression
+	GetCompressionStats() (map[string]*types.CompressionStats, error)
+	MigrateCompression() (int, error)
 
 	// Panel
 	GetSubscriber(npub string) (*types.Subscriber, error)
 	GetSubscriberByAddress(address string) (*types.Subscriber, error)
+	GetSubscriberByName(name string) (*types.Subscriber, error)
+	GetNamedSubscribers() ([]*types.Subscriber, error)
 	SaveSubscriber(subscriber *types.Subscriber) error
 	AllocateBitcoinAddress(npub string) (*types.Address, error)
 	SaveAddress(addr *types.Address) error
@@ -99,7 +116,32 @@ func BuildDagFromStore(store Store, root string, includeContent bool) (*types.Da
 	return data, nil
 }
 
+// Store every leaf of a dag, the root leaf is stored first as the other leaves are bucketed based on it
 func StoreDag(store Store, dag *types.DagData) error {
+	rootLeaf, ok := dag.Dag.Leafs[dag.Dag.Root]
+	if !ok {
+		return fmt.Errorf("dag is missing its root leaf")
+	}
+
+	rootData := &types.DagLeafData{
+		PublicKey: dag.PublicKey,
+		Signature: dag.Signature,
+		Leaf:      *rootLeaf,
+	}
+
+	if err := store.StoreLeaf(dag.Dag.Root, rootData); err != nil {
+		return err
+	}
+
+	for hash, leaf := range dag.Dag.Leafs {
+		if hash == dag.Dag.Root {
+			continue
+		}
+
+		if err := store.StoreLeaf(dag.Dag.Root, &types.DagLeafData{Leaf: *leaf}); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
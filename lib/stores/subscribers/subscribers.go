@@ -0,0 +1,177 @@
+package subscribers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/deroproject/graviton"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+)
+
+// Subscribers are kept in the subscribers bucket under their npub, the NIP-05 name registered by a subscriber
+// is indexed in the nip05_names bucket so names can be looked up without reading every subscriber, shared by
+// the graviton and memory stores so both keep the index the same way
+
+const (
+	Bucket     = "subscribers"
+	NameBucket = "nip05_names"
+)
+
+// Saving reads the previous record to update the name index so saves are serialized
+var mutex sync.Mutex
+
+// Lock is held by anything that reads a subscriber to modify and save it so concurrent updates (payments,
+// name registrations, new sessions) don't overwrite each other with stale records
+var Lock sync.Mutex
+
+// Save stores the subscriber and moves their name in the index when it changed
+func Save(database *graviton.Store, subscriber *types.Subscriber) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	snapshot, err := database.LoadSnapshot(0)
+	if err != nil {
+		return fmt.Errorf("failed to load snapshot: %v", err)
+	}
+
+	subscriberTree, err := snapshot.GetTree(Bucket)
+	if err != nil {
+		return fmt.Errorf("failed to get subscribers tree: %v", err)
+	}
+
+	nameTree, err := snapshot.GetTree(NameBucket)
+	if err != nil {
+		return fmt.Errorf("failed to get nip-05 names tree: %v", err)
+	}
+
+	// A new subscriber has no previous record to read
+	previous, _ := read(subscriberTree, subscriber.Npub)
+
+	// Only remove the previous name if it still points at this subscriber
+	if previous != nil && previous.Nip05Name != "" && previous.Nip05Name != subscriber.Nip05Name {
+		if owner, err := nameTree.Get([]byte(previous.Nip05Name)); err == nil && string(owner) == subscriber.Npub {
+			if err := nameTree.Delete([]byte(previous.Nip05Name)); err != nil {
+				return fmt.Errorf("failed to remove nip-05 name: %v", err)
+			}
+		}
+	}
+
+	if subscriber.Nip05Name != "" {
+		if err := nameTree.Put([]byte(subscriber.Nip05Name), []byte(subscriber.Npub)); err != nil {
+			return fmt.Errorf("failed to put nip-05 name: %v", err)
+		}
+	}
+
+	subscriberData, err := json.Marshal(subscriber)
+	if err != nil {
+		return fmt.Errorf("failed to marshal subscriber: %v", err)
+	}
+
+	if err := subscriberTree.Put([]byte(subscriber.Npub), subscriberData); err != nil {
+		return fmt.Errorf("failed to put subscriber in Graviton store: %v", err)
+	}
+
+	if _, err := graviton.Commit(subscriberTree, nameTree); err != nil {
+		return fmt.Errorf("failed to commit subscribers tree: %v", err)
+	}
+
+	return nil
+}
+
+// Get returns the subscriber stored under the npub
+func Get(database *graviton.Store, npub string) (*types.Subscriber, error) {
+	snapshot, err := database.LoadSnapshot(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %v", err)
+	}
+
+	subscriberTree, err := snapshot.GetTree(Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscribers tree: %v", err)
+	}
+
+	subscriber, err := read(subscriberTree, npub)
+	if err != nil {
+		return nil, fmt.Errorf("subscriber not found for npub: %s", npub)
+	}
+
+	return subscriber, nil
+}
+
+// GetByName returns the subscriber the NIP-05 name is registered to
+func GetByName(database *graviton.Store, name string) (*types.Subscriber, error) {
+	snapshot, err := database.LoadSnapshot(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %v", err)
+	}
+
+	subscriberTree, err := snapshot.GetTree(Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscribers tree: %v", err)
+	}
+
+	nameTree, err := snapshot.GetTree(NameBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nip-05 names tree: %v", err)
+	}
+
+	npub, err := nameTree.Get([]byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("name not found: %s", name)
+	}
+
+	subscriber, err := read(subscriberTree, string(npub))
+	if err != nil {
+		return nil, fmt.Errorf("subscriber not found for name: %s", name)
+	}
+
+	return subscriber, nil
+}
+
+// GetNamed returns every subscriber with a registered NIP-05 name
+func GetNamed(database *graviton.Store) ([]*types.Subscriber, error) {
+	snapshot, err := database.LoadSnapshot(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %v", err)
+	}
+
+	subscriberTree, err := snapshot.GetTree(Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscribers tree: %v", err)
+	}
+
+	nameTree, err := snapshot.GetTree(NameBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nip-05 names tree: %v", err)
+	}
+
+	named := []*types.Subscriber{}
+
+	cursor := nameTree.Cursor()
+	for _, npub, err := cursor.First(); err == nil; _, npub, err = cursor.Next() {
+		subscriber, err := read(subscriberTree, string(npub))
+		if err != nil {
+			return nil, err
+		}
+
+		named = append(named, subscriber)
+	}
+
+	return named, nil
+}
+
+func read(subscriberTree *graviton.Tree, npub string) (*types.Subscriber, error) {
+	subscriberData, err := subscriberTree.Get([]byte(npub))
+	if err != nil {
+		return nil, err
+	}
+
+	var subscriber types.Subscriber
+	if err := json.Unmarshal(subscriberData, &subscriber); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal subscriber data: %v", err)
+	}
+
+	return &subscriber, nil
+}
@@ -0,0 +1,73 @@
+package subscribers
+
+import (
+	"testing"
+
+	"github.com/deroproject/graviton"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+)
+
+func TestNameIndex(t *testing.T) {
+	database, _ := graviton.NewMemStore()
+
+	tests := []struct {
+		name       string
+		subscriber types.Subscriber
+		names      map[string]string
+	}{
+		{"register a name", types.Subscriber{Npub: "alice", Nip05Name: "alice"}, map[string]string{"alice": "alice"}},
+		{"subscriber without a name", types.Subscriber{Npub: "bob"}, map[string]string{"alice": "alice"}},
+		{"rename", types.Subscriber{Npub: "alice", Nip05Name: "al"}, map[string]string{"al": "alice"}},
+		{"second name", types.Subscriber{Npub: "bob", Nip05Name: "bob"}, map[string]string{"al": "alice", "bob": "bob"}},
+		{"save keeping the name", types.Subscriber{Npub: "bob", Nip05Name: "bob", Tier: "1 GB per month"}, map[string]string{"al": "alice", "bob": "bob"}},
+		{"release", types.Subscriber{Npub: "alice"}, map[string]string{"bob": "bob"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := Save(database, &test.subscriber); err != nil {
+				t.Fatalf("failed to save subscriber: %v", err)
+			}
+
+			named, err := GetNamed(database)
+			if err != nil {
+				t.Fatalf("failed to get named subscribers: %v", err)
+			}
+
+			if len(named) != len(test.names) {
+				t.Fatalf("expected %d names, got %d", len(test.names), len(named))
+			}
+
+			for name, npub := range test.names {
+				subscriber, err := GetByName(database, name)
+				if err != nil || subscriber.Npub != npub {
+					t.Errorf("expected %s to belong to %s, got %v (%v)", name, npub, subscriber, err)
+				}
+			}
+		})
+	}
+
+	subscriber, err := Get(database, "bob")
+	if err != nil || subscriber.Tier != "1 GB per month" {
+		t.Errorf("expected the latest record, got %v (%v)", subscriber, err)
+	}
+
+	if _, err := GetByName(database, "alice"); err == nil {
+		t.Errorf("expected a released name to be free")
+	}
+}
+
+// A name taken over by another subscriber stays theirs when the previous owner is saved again
+func TestNameTakenOver(t *testing.T) {
+	database, _ := graviton.NewMemStore()
+
+	Save(database, &types.Subscriber{Npub: "alice", Nip05Name: "shared"})
+	Save(database, &types.Subscriber{Npub: "bob", Nip05Name: "shared"})
+	Save(database, &types.Subscriber{Npub: "alice"})
+
+	subscriber, err := GetByName(database, "shared")
+	if err != nil || subscriber.Npub != "bob" {
+		t.Errorf("expected the name to belong to bob, got %v (%v)", subscriber, err)
+	}
+}
@@ -0,0 +1,105 @@
+package usage
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/deroproject/graviton"
+)
+
+// Storage usage is kept as one counter per public key in the storage_usage bucket, shared by the graviton
+// and memory stores so both count uploads and deletes the same way
+
+const Bucket = "storage_usage"
+
+// Updates are read, modified and committed so they are serialized to keep concurrent uploads from losing each other
+var mutex sync.Mutex
+
+// Get returns the number of bytes the public key has uploaded to the relay
+func Get(database *graviton.Store, publicKey string) (int64, error) {
+	snapshot, err := database.LoadSnapshot(0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load snapshot: %v", err)
+	}
+
+	usageTree, err := snapshot.GetTree(Bucket)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get storage usage tree: %v", err)
+	}
+
+	return read(usageTree, publicKey)
+}
+
+// Update adds the delta (which can be negative when data is deleted) to the storage usage of the public key
+func Update(database *graviton.Store, publicKey string, delta int64) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	snapshot, err := database.LoadSnapshot(0)
+	if err != nil {
+		return fmt.Errorf("failed to load snapshot: %v", err)
+	}
+
+	usageTree, err := snapshot.GetTree(Bucket)
+	if err != nil {
+		return fmt.Errorf("failed to get storage usage tree: %v", err)
+	}
+
+	usage, err := read(usageTree, publicKey)
+	if err != nil {
+		return err
+	}
+
+	usage += delta
+	if usage < 0 {
+		usage = 0
+	}
+
+	if err := usageTree.Put([]byte(publicKey), []byte(strconv.FormatInt(usage, 10))); err != nil {
+		return fmt.Errorf("failed to put storage usage: %v", err)
+	}
+
+	if _, err := graviton.Commit(usageTree); err != nil {
+		return fmt.Errorf("failed to commit storage usage tree: %v", err)
+	}
+
+	return nil
+}
+
+// Clear removes the storage usage of the public key
+func Clear(database *graviton.Store, publicKey string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	snapshot, err := database.LoadSnapshot(0)
+	if err != nil {
+		return fmt.Errorf("failed to load snapshot: %v", err)
+	}
+
+	usageTree, err := snapshot.GetTree(Bucket)
+	if err != nil {
+		return fmt.Errorf("failed to get storage usage tree: %v", err)
+	}
+
+	// Public keys that never uploaded anything have no usage to remove
+	usageTree.Delete([]byte(publicKey))
+
+	_, err = graviton.Commit(usageTree)
+	return err
+}
+
+func read(usageTree *graviton.Tree, publicKey string) (int64, error) {
+	value, err := usageTree.Get([]byte(publicKey))
+	if err != nil || value == nil {
+		// Nothing has been uploaded by this public key yet
+		return 0, nil
+	}
+
+	usage, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse storage usage: %v", err)
+	}
+
+	return usage, nil
+}
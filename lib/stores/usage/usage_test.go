@@ -0,0 +1,105 @@
+package usage
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/deroproject/graviton"
+)
+
+func newDatabase(t *testing.T) *graviton.Store {
+	database, err := graviton.NewMemStore()
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	return database
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		deltas []int64
+		usage  int64
+	}{
+		{"nothing uploaded", nil, 0},
+		{"uploads add up", []int64{100, 250}, 350},
+		{"deletes are subtracted", []int64{100, 250, -100}, 250},
+		{"usage never goes negative", []int64{100, -500}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			database := newDatabase(t)
+
+			for _, delta := range test.deltas {
+				if err := Update(database, "alice", delta); err != nil {
+					t.Fatalf("failed to update usage: %v", err)
+				}
+			}
+
+			usage, err := Get(database, "alice")
+			if err != nil {
+				t.Fatalf("failed to get usage: %v", err)
+			}
+
+			if usage != test.usage {
+				t.Errorf("expected usage %d, got %d", test.usage, usage)
+			}
+		})
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	database := newDatabase(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := Update(database, "alice", 10); err != nil {
+				t.Errorf("failed to update usage: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	usage, err := Get(database, "alice")
+	if err != nil {
+		t.Fatalf("failed to get usage: %v", err)
+	}
+
+	if usage != 500 {
+		t.Errorf("expected usage 500 after concurrent uploads, got %d", usage)
+	}
+}
+
+func TestClear(t *testing.T) {
+	database := newDatabase(t)
+
+	if err := Update(database, "alice", 100); err != nil {
+		t.Fatalf("failed to update usage: %v", err)
+	}
+
+	if err := Update(database, "bob", 100); err != nil {
+		t.Fatalf("failed to update usage: %v", err)
+	}
+
+	if err := Clear(database, "alice"); err != nil {
+		t.Fatalf("failed to clear usage: %v", err)
+	}
+
+	if err := Clear(database, "carol"); err != nil {
+		t.Fatalf("clearing a public key without usage failed: %v", err)
+	}
+
+	if usage, _ := Get(database, "alice"); usage != 0 {
+		t.Errorf("expected cleared usage to be 0, got %d", usage)
+	}
+
+	if usage, _ := Get(database, "bob"); usage != 100 {
+		t.Errorf("expected other usage to be kept, got %d", usage)
+	}
+}
@@ -14,9 +14,13 @@ import (
 
 	"github.com/HORNET-Storage/go-hornet-storage-lib/lib"
 	"github.com/HORNET-Storage/go-hornet-storage-lib/lib/connmgr"
+	"github.com/HORNET-Storage/hornet-storage/lib/aggregates"
+	"github.com/HORNET-Storage/hornet-storage/lib/blocklist"
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
 	"github.com/HORNET-Storage/hornet-storage/lib/signing"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
 	stores_graviton "github.com/HORNET-Storage/hornet-storage/lib/stores/graviton"
+	"github.com/HORNET-Storage/hornet-storage/lib/vanish"
 	"github.com/illuzen/go-negentropy"
 	"github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p/core/host"
@@ -24,6 +28,8 @@ import (
 	"github.com/libp2p/go-libp2p/core/peer"
 	libp2pquic "github.com/libp2p/go-libp2p/p2p/transport/quic"
 	"github.com/nbd-wtf/go-nostr"
+
+	merkle_dag "github.com/HORNET-Storage/scionic-merkletree/dag"
 )
 
 // TODO: where is this supposed to come from? config file?
@@ -82,6 +88,9 @@ func InitiateEventSync(stream network.Stream, filter nostr.Filter, hostId string
 	if err != nil {
 		return err
 	}
+
+	// Protected events are never offered to other relays
+	events = lib_nostr.WithoutProtected(events)
 	log.Printf("%s has %d events", hostId, len(events))
 
 	// vector conforms to Storage interface, fill it with events
@@ -118,7 +127,7 @@ func GetScionicRoot(event *nostr.Event) (string, bool) {
 	return "", false
 }
 
-func DownloadDag(root string) {
+func DownloadDag(store stores.Store, root string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 180*time.Second)
 	defer cancel()
 
@@ -173,11 +182,44 @@ func DownloadDag(root string) {
 
 	log.Println("Dag verified correctly")
 
+	// Refuse trees that contain blocked leaves even if the root itself wasn't blocked
+	for _, leaf := range dag.Leafs {
+		if err := blocklist.CheckLeaf(store.GetStatsStore(), leaf); err != nil {
+			conMgr.Disconnect("default")
+			return err
+		}
+	}
+
 	// Disconnect client as we no longer need it
 	err = conMgr.Disconnect("default")
 	if err != nil {
 		log.Printf("Could not disconnect from hornet storage: %v", err)
 	}
+
+	return nil
+}
+
+// Check the blob hashes and scionic roots referenced by an event against the blocklist
+func checkEventBlocklist(store stores.Store, event *nostr.Event) error {
+	for _, tag := range event.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+
+		switch tag[0] {
+		case "x", "ox", "blossom_hash":
+			if err := blocklist.CheckHash(store.GetStatsStore(), tag[1]); err != nil {
+				return err
+			}
+		case "scionic_root":
+			// Scionic hashes are blocked without their label, the same way leaves are checked
+			if err := blocklist.CheckHash(store.GetStatsStore(), merkle_dag.GetHash(tag[1])); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
 }
 
 func listenNegentropy(neg *negentropy.Negentropy, stream network.Stream, hostId string, store stores.Store, initiator bool) error {
@@ -223,6 +265,8 @@ func listenNegentropy(neg *negentropy.Negentropy, stream network.Stream, hostId
 			if err != nil {
 				return err
 			}
+
+			events = lib_nostr.WithoutProtected(events)
 			log.Printf("%s has %d events", hostId, len(events))
 
 			vector, err := LoadEventVector(events)
@@ -275,6 +319,7 @@ func listenNegentropy(neg *negentropy.Negentropy, stream network.Stream, hostId
 					if err != nil {
 						return err
 					}
+					haveEvents = lib_nostr.WithoutProtected(haveEvents)
 					//log.Println(haveEvents)
 
 					// Marshal the array of events to JSON
@@ -338,7 +383,25 @@ func listenNegentropy(neg *negentropy.Negentropy, stream network.Stream, hostId
 				return err
 			}
 			for _, event := range newEvents {
-				err := store.StoreEvent(event)
+				// Protected events can only come from their author, another relay is never a valid source
+				if lib_nostr.IsProtected(event) {
+					log.Printf("Skipping protected event %s received from %s", event.ID, stream.Conn().RemotePeer())
+					continue
+				}
+
+				// Events deleted by a request to vanish are never taken back from other relays
+				if err := vanish.CheckEvent(store.GetStatsStore(), event); err != nil {
+					log.Printf("Skipping event %s: %v", event.ID, err)
+					continue
+				}
+
+				// Don't ingest events that reference blocked blobs or scionic trees
+				if err := checkEventBlocklist(store, event); err != nil {
+					log.Printf("Skipping event %s: %v", event.ID, err)
+					continue
+				}
+
+				err := aggregates.StoreEvent(store, event)
 				if err != nil {
 					log.Printf("Could not store event %+v skipping", event)
 					continue
@@ -351,7 +414,10 @@ func listenNegentropy(neg *negentropy.Negentropy, stream network.Stream, hostId
 						continue
 					}
 
-					DownloadDag(root)
+					if err := DownloadDag(store, root); err != nil {
+						log.Printf("Removing event %s: %v", event.ID, err)
+						aggregates.DeleteEvent(store, event)
+					}
 				}
 			}
 			if final {
@@ -376,6 +442,7 @@ func listenNegentropy(neg *negentropy.Negentropy, stream network.Stream, hostId
 			if err != nil {
 				return err
 			}
+			haveEvents = lib_nostr.WithoutProtected(haveEvents)
 
 			// Marshal the array of events to JSON
 			haveBytes, err := json.Marshal(haveEvents)
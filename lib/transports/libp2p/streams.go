@@ -0,0 +1,51 @@
+package libp2p
+
+import (
+	"encoding/json"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+)
+
+// BuildStreamHandler serves a nostr handler over libp2p streams
+// Auth isn't supported over libp2p so restricted kinds are never served to these streams and protected
+// events are refused before they reach the handler
+func BuildStreamHandler(handler lib_nostr.KindHandler) network.StreamHandler {
+	return func(stream network.Stream) {
+		defer stream.Close()
+
+		write := func(messageType string, params ...interface{}) {
+			response := lib_nostr.BuildResponse(messageType, params)
+
+			if len(response) > 0 {
+				stream.Write(response)
+			}
+		}
+
+		// The message is read up front so events can be checked before the handler sees them,
+		// a read error is left for the handler to report
+		var rawMessage json.RawMessage
+		err := json.NewDecoder(stream).Decode(&rawMessage)
+
+		if err == nil {
+			if env, ok := nostr.ParseMessage(rawMessage).(*nostr.EventEnvelope); ok {
+				if err := lib_nostr.CheckProtected(&env.Event, ""); err != nil {
+					lib_nostr.RejectError(write, env.Event.ID, err)
+					return
+				}
+			}
+		}
+
+		read := func() ([]byte, error) {
+			if err != nil {
+				return nil, err
+			}
+
+			return rawMessage, nil
+		}
+
+		handler(read, lib_nostr.RestrictReads(write, ""))
+	}
+}
@@ -0,0 +1,90 @@
+package libp2p
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+)
+
+// Only reading, writing and closing are implemented
+type testStream struct {
+	network.Stream
+
+	input  *bytes.Reader
+	output bytes.Buffer
+	closed bool
+}
+
+func (stream *testStream) Read(data []byte) (int, error) {
+	return stream.input.Read(data)
+}
+
+func (stream *testStream) Write(data []byte) (int, error) {
+	return stream.output.Write(data)
+}
+
+func (stream *testStream) Close() error {
+	stream.closed = true
+	return nil
+}
+
+func TestBuildStreamHandler(t *testing.T) {
+	event := func(tags nostr.Tags) string {
+		envelope, _ := nostr.EventEnvelope{Event: nostr.Event{ID: strings.Repeat("1", 64), Kind: 1, PubKey: strings.Repeat("a", 64), Tags: tags}}.MarshalJSON()
+		return string(envelope)
+	}
+
+	tests := []struct {
+		name     string
+		message  string
+		handled  bool
+		readable bool
+		response string
+	}{
+		{"event", event(nostr.Tags{{"t", "nostr"}}), true, true, ""},
+		{"protected event", event(nostr.Tags{{"-"}}), false, true, `["OK","` + strings.Repeat("1", 64) + `",false,"auth-required: this event may only be published by its author"]`},
+		{"request", `["REQ","sub",{"kinds":[1]}]`, true, true, ""},
+		{"unreadable message", `["EVENT"`, true, false, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handled := false
+			handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+				handled = true
+
+				data, err := read()
+				if !test.readable {
+					if err == nil {
+						t.Errorf("expected the read error to reach the handler")
+					}
+					return
+				}
+
+				if err != nil || string(data) != test.message {
+					t.Errorf("expected the handler to read the message, got %s (%v)", data, err)
+				}
+			}
+
+			stream := &testStream{input: bytes.NewReader([]byte(test.message))}
+			BuildStreamHandler(handler)(stream)
+
+			if handled != test.handled {
+				t.Errorf("expected handled %v, got %v", test.handled, handled)
+			}
+
+			if response := strings.TrimSpace(stream.output.String()); response != test.response {
+				t.Errorf("expected response %s, got %s", test.response, response)
+			}
+
+			if !stream.closed {
+				t.Errorf("expected the stream to be closed")
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/HORNET-Storage/hornet-storage/lib/sessions"
 	"github.com/HORNET-Storage/hornet-storage/lib/signing"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/subscribers"
 )
 
 const (
@@ -27,38 +28,33 @@ const (
 )
 
 func handleAuthMessage(c *websocket.Conn, env *nostr.AuthEnvelope, challenge string, state *connectionState, store stores.Store) {
-	write := func(messageType string, params ...interface{}) {
-		response := lib_nostr.BuildResponse(messageType, params)
-		if len(response) > 0 {
-			handleIncomingMessage(c, response)
-		}
-	}
+	write := buildWriter(c)
 
 	log.Printf("Handling auth message for user with pubkey: %s", env.Event.PubKey)
 
 	if env.Event.Kind != 22242 {
 		log.Printf("Invalid auth event kind: %d", env.Event.Kind)
-		write("OK", env.Event.ID, false, "Error auth event kind must be 22242")
+		lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, "auth event kind must be 22242")
 		return
 	}
 
 	isValid, errMsg := lib_nostr.AuthTimeCheck(env.Event.CreatedAt.Time().Unix())
 	if !isValid {
 		log.Printf("Auth time check failed: %s", errMsg)
-		write("OK", env.Event.ID, false, errMsg)
+		lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, errMsg)
 		return
 	}
 
 	success, err := env.Event.CheckSignature()
 	if err != nil {
 		log.Printf("Failed to check signature: %v", err)
-		write("NOTICE", "Failed to check signature")
+		lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, "failed to check signature")
 		return
 	}
 
 	if !success {
 		log.Printf("Signature verification failed for user: %s", env.Event.PubKey)
-		write("OK", env.Event.ID, false, "Signature failed to verify")
+		lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, "signature failed to verify")
 		return
 	}
 
@@ -71,7 +67,7 @@ func handleAuthMessage(c *websocket.Conn, env *nostr.AuthEnvelope, challenge str
 				hasChallengeTag = true
 				if tag[1] != challenge {
 					log.Printf("Challenge mismatch for user %s. Expected: %s, Got: %s", env.Event.PubKey, challenge, tag[1])
-					write("OK", env.Event.ID, false, "Error checking session challenge")
+					lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, "challenge does not match the one sent to this connection")
 					return
 				}
 			}
@@ -80,10 +76,17 @@ func handleAuthMessage(c *websocket.Conn, env *nostr.AuthEnvelope, challenge str
 
 	if !hasRelayTag || !hasChallengeTag {
 		log.Printf("Missing required tags for user %s. Has relay tag: %v, Has challenge tag: %v", env.Event.PubKey, hasRelayTag, hasChallengeTag)
-		write("OK", env.Event.ID, false, "Error event does not have required tags")
+		lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixInvalid, "auth event must have 'relay' and 'challenge' tags")
 		return
 	}
 
+	// The pubkey is proven at this point so restricted events can be read regardless of subscription status
+	state.pubkey = env.Event.PubKey
+	setListenerPubkey(c, env.Event.PubKey)
+
+	// Creating the subscriber must not overwrite a record saved in the meantime
+	subscribers.Lock.Lock()
+
 	// Retrieve the subscriber using their npub
 	subscriber, err := store.GetSubscriber(env.Event.PubKey)
 	if err != nil {
@@ -97,8 +100,9 @@ func handleAuthMessage(c *websocket.Conn, env *nostr.AuthEnvelope, challenge str
 		}
 		err = store.SaveSubscriber(subscriber)
 		if err != nil {
+			subscribers.Lock.Unlock()
 			log.Printf("Failed to create new subscriber for %s: %v", env.Event.PubKey, err)
-			write("NOTICE", "Failed to create new subscriber")
+			lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to create new subscriber")
 			return
 		}
 		log.Printf("Created new subscriber for %s", env.Event.PubKey)
@@ -106,6 +110,8 @@ func handleAuthMessage(c *websocket.Conn, env *nostr.AuthEnvelope, challenge str
 		log.Printf("Retrieved existing subscriber for %s", env.Event.PubKey)
 	}
 
+	subscribers.Lock.Unlock()
+
 	// Check if the subscription is active
 	if subscriber.Tier != "" && time.Now().Before(subscriber.EndDate) {
 		log.Printf("Subscriber %s has an active subscription until %s", subscriber.Npub, subscriber.EndDate)
@@ -119,7 +125,7 @@ func handleAuthMessage(c *websocket.Conn, env *nostr.AuthEnvelope, challenge str
 	err = sessions.CreateSession(env.Event.PubKey)
 	if err != nil {
 		log.Printf("Failed to create session for %s: %v", env.Event.PubKey, err)
-		write("NOTICE", "Failed to create session")
+		lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to create session")
 		return
 	}
 
@@ -137,12 +143,12 @@ func handleAuthMessage(c *websocket.Conn, env *nostr.AuthEnvelope, challenge str
 	err = CreateNIP88Event(privateKey, env.Event.PubKey, store)
 	if err != nil {
 		log.Printf("Failed to create/update NIP-88 event for %s: %v", env.Event.PubKey, err)
-		write("NOTICE", "Failed to create/update NIP-88 event: %v", err)
+		lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "failed to create the subscription event")
 		return
 	}
 
 	log.Printf("Successfully created/updated NIP-88 event for %s", env.Event.PubKey)
-	write("OK", env.Event.ID, true, "NIP-88 event successfully created/updated")
+	lib_nostr.Accept(write, env.Event.ID, "NIP-88 event successfully created/updated")
 
 	if !state.authenticated {
 		log.Printf("Session established but subscription inactive for %s", env.Event.PubKey)
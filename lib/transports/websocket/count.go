@@ -9,16 +9,20 @@ import (
 	"github.com/nbd-wtf/go-nostr"
 
 	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/count"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
 )
 
-func handleCountMessage(c *websocket.Conn, env *nostr.CountEnvelope, challenge string) {
+func handleCountMessage(c *websocket.Conn, env *nostr.CountEnvelope, challenge string, state *connectionState, store stores.Store) {
 	var json = jsoniter.ConfigCompatibleWithStandardLibrary
-	handler := lib_nostr.GetHandler("count")
 
-	if handler != nil {
+	if lib_nostr.GetHandler("count") != nil {
+		// Only what the connection could read with a REQ is counted so the handler is built for its pubkey
+		handler := count.BuildAuthenticatedCountsHandler(store, state.pubkey)
+
 		_, cancelFunc := context.WithCancel(context.Background())
 
-		setListener(env.SubscriptionID, c, env.Filters, cancelFunc)
+		setListener(env.SubscriptionID, c, env.Filters, cancelFunc, state.pubkey)
 
 		response := lib_nostr.BuildResponse("AUTH", challenge)
 		if len(response) > 0 {
@@ -11,13 +11,22 @@ import (
 	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
 )
 
-func handleEventMessage(c *websocket.Conn, env *nostr.EventEnvelope) {
+func handleEventMessage(c *websocket.Conn, env *nostr.EventEnvelope, state *connectionState) {
 	settings, err := lib_nostr.LoadRelaySettings()
 	if err != nil {
 		log.Printf("Failed to load relay settings: %v", err)
 	}
 
-	if settings.Mode == "unlimited" {
+	// Protected events are only accepted from a connection authenticated as their author
+	if err := lib_nostr.CheckProtected(&env.Event, state.pubkey); err != nil {
+		lib_nostr.RejectError(buildWriter(c), env.Event.ID, err)
+		return
+	}
+
+	// Ephemeral events are broadcast without being stored whatever the mode
+	if lib_nostr.IsEphemeralKind(env.Kind) {
+		handleEphemeralEvent(c, env)
+	} else if settings.Mode == "unlimited" {
 		handleUnlimitedModeEvent(c, env)
 	} else if settings.Mode == "smart" {
 		handleSmartModeEvent(c, env)
@@ -32,19 +41,13 @@ func handleUnlimitedModeEvent(c *websocket.Conn, env *nostr.EventEnvelope) {
 		return json.Marshal(env)
 	}
 
-	write := func(messageType string, params ...interface{}) {
-		response := lib_nostr.BuildResponse(messageType, params)
-		if len(response) > 0 {
-			handleIncomingMessage(c, response)
-		}
-	}
+	// Subscribers only receive the event once the handler has accepted it
+	write := lib_nostr.BroadcastOnAccept(buildWriter(c), &env.Event)
 
 	if handler != nil {
-		notifyListeners(&env.Event)
-
 		handler(read, write)
 	} else {
-		write("OK", env.Event.ID, false, "Universal handler not supported")
+		lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixError, "this relay can't store events right now")
 	}
 }
 
@@ -52,22 +55,49 @@ func handleSmartModeEvent(c *websocket.Conn, env *nostr.EventEnvelope) {
 	var json = jsoniter.ConfigCompatibleWithStandardLibrary
 	handler := lib_nostr.GetHandler(fmt.Sprintf("kind/%d", env.Kind))
 
+	// Kinds without a handler of their own may still have a policy, such as kinds enabled through DynamicKinds
+	if handler == nil {
+		handler = lib_nostr.GetHandler("policy")
+	}
+
 	read := func() ([]byte, error) {
 		return json.Marshal(env)
 	}
 
-	write := func(messageType string, params ...interface{}) {
-		response := lib_nostr.BuildResponse(messageType, params)
-		if len(response) > 0 {
-			handleIncomingMessage(c, response)
-		}
-	}
+	// Subscribers only receive the event once the handler has accepted it
+	write := lib_nostr.BroadcastOnAccept(buildWriter(c), &env.Event)
 
 	if handler != nil {
-		notifyListeners(&env.Event)
+		handler(read, write)
+	} else {
+		lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixBlocked, fmt.Sprintf("kind %d is not supported by this relay", env.Kind))
+	}
+}
+
+func handleEphemeralEvent(c *websocket.Conn, env *nostr.EventEnvelope) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	handler := lib_nostr.GetHandler("ephemeral")
+
+	read := func() ([]byte, error) {
+		return json.Marshal(env)
+	}
+
+	write := buildWriter(c)
 
+	// The handler broadcasts the event itself once it has been validated
+	if handler != nil {
 		handler(read, write)
 	} else {
-		write("OK", env.Event.ID, false, "Kind not supported")
+		lib_nostr.Reject(write, env.Event.ID, lib_nostr.PrefixBlocked, "ephemeral events are not supported by this relay")
+	}
+}
+
+// buildWriter returns a writer that sends the responses of a handler over the websocket connection
+func buildWriter(c *websocket.Conn) lib_nostr.KindWriter {
+	return func(messageType string, params ...interface{}) {
+		response := lib_nostr.BuildResponse(messageType, params)
+		if len(response) > 0 {
+			handleIncomingMessage(c, response)
+		}
 	}
 }
@@ -12,6 +12,8 @@ import (
 	"github.com/gofiber/contrib/websocket"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/puzpuzpuz/xsync/v3"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
 )
 
 // Global map to hold all listeners indexed by WebSocket connections and subscription IDs.
@@ -23,7 +25,7 @@ var globalChallenge atomic.Value
 const challengeLength = 32
 
 // SetListener sets a new listener with given ID, WebSocket connection, filters, and cancel function.
-func setListener(id string, ws *websocket.Conn, filters nostr.Filters, cancel context.CancelFunc) {
+func setListener(id string, ws *websocket.Conn, filters nostr.Filters, cancel context.CancelFunc, pubkey string) {
 	conData, _ := listeners.LoadOrCompute(ws, func() ListenerData {
 		return ListenerData{
 			challenge:     "",
@@ -33,6 +35,7 @@ func setListener(id string, ws *websocket.Conn, filters nostr.Filters, cancel co
 
 	conData.subscriptions.Store(id, &Subscription{filters: filters, cancel: cancel})
 	conData.authenticated = false
+	conData.pubkey = pubkey
 	listeners.Store(ws, conData)
 }
 
@@ -59,10 +62,18 @@ func removeListener(ws *websocket.Conn) {
 
 // NotifyListeners notifies all listeners with an event if it matches their filters.
 func notifyListeners(event *nostr.Event) {
+	// Events left out of query results, such as hidden channel messages, aren't delivered live either
+	if len(lib_nostr.FilterQueryResults([]*nostr.Event{event})) == 0 {
+		return
+	}
+
 	listeners.Range(func(ws *websocket.Conn, conData ListenerData) bool {
 		if !conData.authenticated {
 			return true // Skip notification if not authenticated
 		}
+		if !lib_nostr.CanReadEvent(event, conData.pubkey) {
+			return true // Restricted events only go to their author and recipients
+		}
 		conData.subscriptions.Range(func(id string, listener *Subscription) bool {
 			if !listener.filters.Match(event) {
 				return true
@@ -97,6 +108,14 @@ func AuthenticateConnection(ws *websocket.Conn) error {
 	return nil
 }
 
+// Record the pubkey a connection authenticated as so existing subscriptions can receive restricted events
+func setListenerPubkey(ws *websocket.Conn, pubkey string) {
+	if conData, ok := listeners.Load(ws); ok {
+		conData.pubkey = pubkey
+		listeners.Store(ws, conData)
+	}
+}
+
 // Generate the global challenge
 func generateGlobalChallenge() (string, error) {
 	bytes := make([]byte, challengeLength)
@@ -2,23 +2,42 @@ package websocket
 
 import (
 	"context"
+	"fmt"
+	"log"
 
 	jsoniter "github.com/json-iterator/go"
 
 	"github.com/gofiber/contrib/websocket"
 	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
 
 	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
 )
 
-func handleReqMessage(c *websocket.Conn, env *nostr.ReqEnvelope) {
+func handleReqMessage(c *websocket.Conn, env *nostr.ReqEnvelope, state *connectionState) {
 	var json = jsoniter.ConfigCompatibleWithStandardLibrary
 	handler := lib_nostr.GetHandler("filter")
 
 	if handler != nil {
+		// Direct messages and gift wraps are only served to their author and recipients
+		if reason := lib_nostr.CheckReadAccess(env.Filters, state.pubkey); reason != "" {
+			if err := sendWebSocketMessage(c, nostr.ClosedEnvelope{SubscriptionID: env.SubscriptionID, Reason: reason}); err != nil {
+				log.Printf("Error sending 'CLOSED' envelope over WebSocket: %v", err)
+			}
+			return
+		}
+
+		// Enforce the subscription and filter limits advertised in the relay information document
+		if reason := checkRequestLimits(c, env); reason != "" {
+			if err := sendWebSocketMessage(c, nostr.ClosedEnvelope{SubscriptionID: env.SubscriptionID, Reason: reason}); err != nil {
+				log.Printf("Error sending 'CLOSED' envelope over WebSocket: %v", err)
+			}
+			return
+		}
+
 		_, cancelFunc := context.WithCancel(context.Background())
 
-		setListener(env.SubscriptionID, c, env.Filters, cancelFunc)
+		setListener(env.SubscriptionID, c, env.Filters, cancelFunc, state.pubkey)
 
 		read := func() ([]byte, error) {
 			return json.Marshal(env)
@@ -31,6 +50,23 @@ func handleReqMessage(c *websocket.Conn, env *nostr.ReqEnvelope) {
 			}
 		}
 
-		handler(read, write)
+		handler(read, lib_nostr.RestrictReads(write, state.pubkey))
+	}
+}
+
+func checkRequestLimits(c *websocket.Conn, env *nostr.ReqEnvelope) string {
+	if maxFilters := viper.GetInt("max_filters"); maxFilters > 0 && len(env.Filters) > maxFilters {
+		return lib_nostr.Reason(lib_nostr.PrefixError, fmt.Sprintf("too many filters, the limit is %d", maxFilters))
 	}
+
+	// Replacing an existing subscription doesn't count towards the limit
+	if maxSubscriptions := viper.GetInt("max_subscriptions"); maxSubscriptions > 0 {
+		if conData, ok := listeners.Load(c); ok {
+			if _, exists := conData.subscriptions.Load(env.SubscriptionID); !exists && conData.subscriptions.Size() >= maxSubscriptions {
+				return lib_nostr.Reason(lib_nostr.PrefixError, fmt.Sprintf("too many subscriptions, the limit is %d", maxSubscriptions))
+			}
+		}
+	}
+
+	return ""
 }
@@ -7,6 +7,8 @@ import (
 	"github.com/gofiber/contrib/websocket"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
 )
 
 func sendWebSocketMessage(ws *websocket.Conn, msg interface{}) error {
@@ -105,19 +107,22 @@ func handleIncomingMessage(ws *websocket.Conn, jsonMessage []byte) {
 			return
 		}
 
-		// No reason provided in this message format; handling accordingly.
-		// You could define a default or conditional reason based on success, if needed.
+		// The reason is passed through untouched so clients receive the machine readable prefix
 		var reason string
-		if !success {
-			// Define how you want to handle a false success scenario. Maybe a default reason or additional handling.
-			reason = "Operation failed" // Placeholder; adjust based on your requirements or context.
+		if len(messageSlice) > 3 {
+			reason, _ = messageSlice[3].(string)
+		}
+
+		// Handlers always give a reason when they refuse an event but fall back to a generic one just in case
+		if !success && reason == "" {
+			reason = lib_nostr.Reason(lib_nostr.PrefixError, "the event was not accepted")
 		}
 
 		// Constructing the OKEnvelope with the provided data.
 		okEnvelope := nostr.OKEnvelope{
 			EventID: eventID,
 			OK:      success,
-			Reason:  reason, // Note: This will be empty if success is true, based on current message format.
+			Reason:  reason,
 		}
 		// Sending the constructed OKEnvelope.
 		sendWebSocketMessage(ws, okEnvelope)
@@ -146,6 +151,19 @@ func handleIncomingMessage(ws *websocket.Conn, jsonMessage []byte) {
 			log.Printf("Error sending 'COUNT' envelope over WebSocket: %v", err)
 		}
 
+	case "CLOSED":
+		// Assuming the CLOSED message includes a machine readable reason as the third element
+		if len(messageSlice) < 3 {
+			log.Println("Expected data for 'CLOSED' message type is missing.")
+			return
+		}
+		reason, ok := messageSlice[2].(string)
+		if !ok {
+			log.Println("Expected reason for 'CLOSED' message type is not a string.")
+			return
+		}
+		sendWebSocketMessage(ws, nostr.ClosedEnvelope{SubscriptionID: subID, Reason: reason})
+
 	case "AUTH":
 		if len(messageSlice) < 2 {
 			log.Println("Expected data for 'AUTH' message type is missing.")
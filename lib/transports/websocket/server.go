@@ -22,16 +22,36 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/blossom"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nip05"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nip86"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nip96"
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/addressable"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/badges"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/count"
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
 )
 
 type connectionState struct {
 	authenticated bool
+	pubkey        string
 }
 
 func BuildServer(store stores.Store) *fiber.App {
 	app := fiber.New()
 
+	// Events accepted by a handler and ephemeral events are delivered to the subscriptions
+	lib_nostr.RegisterBroadcaster(notifyListeners)
+
+	// Refuse connections from ips blocked through relay management
+	app.Use(func(c *fiber.Ctx) error {
+		if moderation.IsIPBlocked(store.GetStatsStore(), c.IP()) {
+			return c.SendStatus(fiber.StatusForbidden)
+		}
+		return c.Next()
+	})
+
 	// Middleware for handling relay information requests
 	app.Use(handleRelayInfoRequests)
 
@@ -43,6 +63,11 @@ func BuildServer(store stores.Store) *fiber.App {
 
 		state := &connectionState{authenticated: false}
 
+		// Messages larger than the advertised limit close the connection
+		if maxMessageLength := viper.GetInt64("max_message_length"); maxMessageLength > 0 {
+			c.SetReadLimit(maxMessageLength)
+		}
+
 		// Send the AUTH challenge immediately upon connection
 		authChallenge := []interface{}{"AUTH", challenge}
 		jsonAuth, err := json.Marshal(authChallenge)
@@ -63,6 +88,30 @@ func BuildServer(store stores.Store) *fiber.App {
 	server := blossom.NewServer(store)
 	server.SetupRoutes(app)
 
+	// Enable nip-96 routes for http file uploads from nostr clients
+	nip96Server := nip96.NewServer(store)
+	nip96Server.SetupRoutes(app)
+
+	// Enable nip-05 names for relay subscribers
+	nip05Server := nip05.NewServer(store)
+	nip05Server.SetupRoutes(app)
+
+	// Resolve the badges a user displays in a single request
+	badgesServer := badges.NewServer(store)
+	badgesServer.SetupRoutes(app)
+
+	// List an author's addressable events and look events up by naddr
+	addressableServer := addressable.NewServer(store)
+	addressableServer.SetupRoutes(app)
+
+	// Reaction, repost and zap totals for an event
+	aggregatesServer := count.NewServer(store)
+	aggregatesServer.SetupRoutes(app)
+
+	// Enable nip-86 relay management requests sent to the relay url
+	nip86Server := nip86.NewServer(store)
+	nip86Server.SetupRoutes(app)
+
 	return app
 }
 
@@ -107,14 +156,22 @@ func handleRelayInfoRequests(c *fiber.Ctx) error {
 }
 
 func GetRelayInfo() NIP11RelayInfo {
+	// The document is built from the live settings and registered handlers, the same data is used for kind 411
+	info := lib_nostr.BuildRelayInformation()
+
 	relayInfo := NIP11RelayInfo{
-		Name:          viper.GetString("RelayName"),
-		Description:   viper.GetString("RelayDescription"),
-		Pubkey:        viper.GetString("RelayPubkey"),
-		Contact:       viper.GetString("RelayContact"),
-		SupportedNIPs: viper.GetIntSlice("RelaySupportedNips"),
-		Software:      viper.GetString("RelaySoftware"),
-		Version:       viper.GetString("RelayVersion"),
+		Name:          info.Name,
+		Description:   info.Description,
+		Pubkey:        info.Pubkey,
+		Contact:       info.Contact,
+		SupportedNIPs: info.SupportedNIPs,
+		Software:      info.Software,
+		Version:       info.Version,
+		Icon:          info.Icon,
+		PaymentsURL:   info.PaymentsURL,
+		Limitation:    info.Limitation,
+		Retention:     info.Retention,
+		Fees:          info.Fees,
 	}
 
 	privKey, _, err := signing.DeserializePrivateKey(viper.GetString("key"))
@@ -220,14 +277,26 @@ func processWebSocketMessage(c *websocket.Conn, challenge string, state *connect
 		return fmt.Errorf("read error: %w", err)
 	}
 
+	// Connections opened before their ip was blocked are closed on their next message
+	if moderation.IsIPBlocked(store.GetStatsStore(), c.IP()) {
+		return fmt.Errorf("ip %s has been blocked", c.IP())
+	}
+
 	rawMessage := nostr.ParseMessage(message)
 
+	// Relays that require auth only answer AUTH until the connection has authenticated
+	if lib_nostr.AuthRequired() && state.pubkey == "" {
+		if handled := rejectUnauthenticated(c, rawMessage); handled {
+			return nil
+		}
+	}
+
 	switch env := rawMessage.(type) {
 	case *nostr.EventEnvelope:
-		handleEventMessage(c, env)
+		handleEventMessage(c, env, state)
 
 	case *nostr.ReqEnvelope:
-		handleReqMessage(c, env)
+		handleReqMessage(c, env, state)
 
 	case *nostr.AuthEnvelope:
 		handleAuthMessage(c, env, challenge, state, store)
@@ -236,7 +305,7 @@ func processWebSocketMessage(c *websocket.Conn, challenge string, state *connect
 		handleCloseMessage(c, env)
 
 	case *nostr.CountEnvelope:
-		handleCountMessage(c, env, challenge)
+		handleCountMessage(c, env, challenge, state, store)
 
 	default:
 		firstComma := bytes.Index(message, []byte{','})
@@ -250,3 +319,27 @@ func processWebSocketMessage(c *websocket.Conn, challenge string, state *connect
 
 	return nil
 }
+
+// Send the auth-required response for requests that need an authenticated connection, true is returned when
+// the message was answered and shouldn't be handled
+func rejectUnauthenticated(c *websocket.Conn, rawMessage nostr.Envelope) bool {
+	reason := lib_nostr.Reason(lib_nostr.PrefixAuthRequired, "this relay requires authentication")
+
+	var response interface{}
+	switch env := rawMessage.(type) {
+	case *nostr.EventEnvelope:
+		response = nostr.OKEnvelope{EventID: env.Event.ID, OK: false, Reason: reason}
+	case *nostr.ReqEnvelope:
+		response = nostr.ClosedEnvelope{SubscriptionID: env.SubscriptionID, Reason: reason}
+	case *nostr.CountEnvelope:
+		response = nostr.ClosedEnvelope{SubscriptionID: env.SubscriptionID, Reason: reason}
+	default:
+		return false
+	}
+
+	if err := sendWebSocketMessage(c, response); err != nil {
+		log.Printf("Error sending auth-required response over WebSocket: %v", err)
+	}
+
+	return true
+}
@@ -9,19 +9,26 @@ import (
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/puzpuzpuz/xsync/v3"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
 )
 
 // TODO: maybe we should move this into a different package since we use it in the sync package as well
 // It certainly shouldn't be here, that's for sure
 type NIP11RelayInfo struct {
-	Name            string           `json:"name,omitempty"`
-	Description     string           `json:"description,omitempty"`
-	Pubkey          string           `json:"pubkey,omitempty"`
-	Contact         string           `json:"contact,omitempty"`
-	SupportedNIPs   []int            `json:"supported_nips,omitempty"`
-	Software        string           `json:"software,omitempty"`
-	Version         string           `json:"version,omitempty"`
-	HornetExtension *HornetExtension `json:"hornet_extension,omitempty"` // custom extension for p2p context
+	Name            string                     `json:"name,omitempty"`
+	Description     string                     `json:"description,omitempty"`
+	Pubkey          string                     `json:"pubkey,omitempty"`
+	Contact         string                     `json:"contact,omitempty"`
+	SupportedNIPs   []int                      `json:"supported_nips,omitempty"`
+	Software        string                     `json:"software,omitempty"`
+	Version         string                     `json:"version,omitempty"`
+	Icon            string                     `json:"icon,omitempty"`
+	PaymentsURL     string                     `json:"payments_url,omitempty"`
+	Limitation      *lib_nostr.RelayLimitation `json:"limitation,omitempty"`
+	Retention       []lib_nostr.RelayRetention `json:"retention,omitempty"`
+	Fees            *lib_nostr.RelayFees       `json:"fees,omitempty"`
+	HornetExtension *HornetExtension           `json:"hornet_extension,omitempty"` // custom extension for p2p context
 }
 
 type HornetExtension struct {
@@ -53,6 +60,7 @@ type Subscription struct {
 type ListenerData struct {
 	authenticated bool
 	challenge     string
+	pubkey        string // Pubkey proven with AUTH, empty until the connection authenticates
 	subscriptions *xsync.MapOf[string, *Subscription]
 }
 
@@ -273,12 +273,13 @@ type Address struct {
 // }
 
 type Subscriber struct {
-	Npub              string    `json:"npub"`                // The unique public key of the subscriber
-	Tier              string    `json:"tier"`                // The subscription tier the user has selected
-	StartDate         time.Time `json:"start_date"`          // When the subscription started
-	EndDate           time.Time `json:"end_date"`            // When the subscription ends
-	Address           string    `json:"address"`             // The address associated with the subscription
-	LastTransactionID string    `json:"last_transaction_id"` // The ID of the last processed transaction
+	Npub              string    `json:"npub"`                 // The unique public key of the subscriber
+	Tier              string    `json:"tier"`                 // The subscription tier the user has selected
+	StartDate         time.Time `json:"start_date"`           // When the subscription started
+	EndDate           time.Time `json:"end_date"`             // When the subscription ends
+	Address           string    `json:"address"`              // The address associated with the subscription
+	LastTransactionID string    `json:"last_transaction_id"`  // The ID of the last processed transaction
+	Nip05Name         string    `json:"nip05_name,omitempty"` // The NIP-05 name registered on this relay
 }
 
 type UserChallenge struct {
@@ -329,6 +330,96 @@ type Libp2pStream struct {
 	Ctx    context.Context
 }
 
+type BlockedHash struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Hash      string    `gorm:"uniqueIndex;not null" json:"hash"`
+	HashType  string    `gorm:"index;not null" json:"hash_type"` // sha256, scionic or dhash
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `gorm:"autoCreateTime" json:"timestamp"`
+}
+
+type BlocklistAudit struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Action    string    `gorm:"not null" json:"action"` // add, remove or import
+	Hash      string    `json:"hash"`
+	HashType  string    `json:"hash_type"`
+	Reason    string    `json:"reason"`
+	Actor     string    `json:"actor"`
+	Timestamp time.Time `gorm:"autoCreateTime" json:"timestamp"`
+}
+
+// ModerationEntry is a value on one of the relay management lists such as banned pubkeys or blocked ips
+type ModerationEntry struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	List      string    `gorm:"uniqueIndex:idx_moderation_list_value;not null" json:"list"` // banned_pubkey, allowed_pubkey, banned_event or blocked_ip
+	Value     string    `gorm:"uniqueIndex:idx_moderation_list_value;not null" json:"value"`
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `gorm:"autoCreateTime" json:"timestamp"`
+}
+
+// Report is a single NIP-56 report counted towards a target, each reporter is counted once per target
+type Report struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ReportID   string    `gorm:"not null" json:"report_id"`                                   // The id of the kind 1984 event
+	TargetType string    `gorm:"uniqueIndex:idx_report_reporter;not null" json:"target_type"` // event or pubkey
+	Target     string    `gorm:"uniqueIndex:idx_report_reporter;not null" json:"target"`
+	Reporter   string    `gorm:"uniqueIndex:idx_report_reporter;not null" json:"reporter"`
+	ReportType string    `json:"report_type"` // nudity, malware, profanity, illegal, spam, impersonation or other
+	Reason     string    `json:"reason"`
+	Weight     float64   `json:"weight"`
+	Timestamp  time.Time `gorm:"autoCreateTime" json:"timestamp"`
+}
+
+// ReportTarget aggregates the reports against an event or pubkey for the moderation queue
+type ReportTarget struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	TargetType  string         `gorm:"uniqueIndex:idx_report_target;not null" json:"target_type"`
+	Target      string         `gorm:"uniqueIndex:idx_report_target;not null" json:"target"`
+	Score       float64        `json:"score"` // Sum of the report weights
+	ReportCount int            `json:"report_count"`
+	ReportTypes map[string]int `gorm:"serializer:json" json:"report_types"`
+	Status      string         `gorm:"index;not null" json:"status"` // pending, hidden, accepted or dismissed
+	Action      string         `json:"action,omitempty"`             // What was done when the reports were accepted
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// EventAggregate holds running totals of the reactions, reposts and zaps an event has received
+type EventAggregate struct {
+	EventID        string           `gorm:"primaryKey" json:"event_id"`
+	Reactions      int64            `json:"reactions"`
+	ReactionCounts map[string]int64 `gorm:"serializer:json" json:"reaction_counts"` // Keyed by the reaction content such as + or an emoji
+	Reposts        int64            `json:"reposts"`                                // Kind 6 reposts
+	GenericReposts int64            `json:"generic_reposts"`                        // Kind 16 reposts
+	Zaps           int64            `json:"zaps"`
+	ZapMsats       int64            `json:"zap_msats"`
+	UpdatedAt      time.Time        `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// VanishRequest records a NIP-62 request to vanish and what was deleted for it, events by the pubkey
+// created before the request are refused afterwards
+// Requests are deleted in the background and picked up again after a restart until they are completed
+type VanishRequest struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	EventID       string    `gorm:"uniqueIndex;not null" json:"event_id"` // The id of the kind 62 event
+	Pubkey        string    `gorm:"index;not null" json:"pubkey"`
+	Until         int64     `json:"until"`  // The created_at of the request, everything up to it is deleted
+	Relay         string    `json:"relay"`  // The relay the request was addressed to or ALL_RELAYS
+	Reason        string    `json:"reason"` // The content of the request
+	Event         string    `json:"event"`  // The request itself as json
+	EventsDeleted int       `json:"events_deleted"`
+	BlobsDeleted  int       `json:"blobs_deleted"`
+	DagsDeleted   int       `json:"dags_deleted"`
+	Completed     bool      `gorm:"index" json:"completed"` // Set once everything has been deleted
+	Timestamp     time.Time `gorm:"autoCreateTime" json:"timestamp"`
+}
+
+type CompressionStats struct {
+	Values        int   `json:"values"`
+	LogicalBytes  int64 `json:"logical_bytes"`  // Size of the values before compression
+	PhysicalBytes int64 `json:"physical_bytes"` // Size of the values as stored
+}
+
 type SubscriptionTier struct {
 	DataLimit string
 	Price     string
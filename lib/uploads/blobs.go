@@ -0,0 +1,162 @@
+package uploads
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/ipfs/go-cid"
+	"github.com/spf13/viper"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/signing"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	merkle_dag "github.com/HORNET-Storage/scionic-merkletree/dag"
+)
+
+// StoreBlob stores a blob in the blob store, blobs larger than the configured threshold are converted into
+// a relay signed scionic merkle tree instead so they can be chunked and replicated like any other dag
+func StoreBlob(store stores.Store, data []byte, mimeType string, publicKey string) (string, error) {
+	hash := sha256.Sum256(data)
+	encodedHash := hex.EncodeToString(hash[:])
+
+	threshold := viper.GetInt64("blob_dag_threshold")
+	if threshold <= 0 || int64(len(data)) <= threshold {
+		return encodedHash, store.StoreBlob(data, hash[:], publicKey)
+	}
+
+	root, err := storeBlobAsDag(store, data, encodedHash, mimeType)
+	if err != nil {
+		return "", err
+	}
+
+	if err := store.SetBlobDag(encodedHash, root, publicKey); err != nil {
+		return "", err
+	}
+
+	log.Printf("Stored blob %s as scionic merkle tree %s", encodedHash, root)
+
+	return encodedHash, nil
+}
+
+// GetBlob retrieves a blob from the blob store or reassembles it from the scionic merkle tree it was converted into
+func GetBlob(store stores.Store, hash string) ([]byte, error) {
+	data, err := store.GetBlob(hash)
+	if err == nil && data != nil {
+		return data, nil
+	}
+
+	root, err := store.GetBlobDag(hash)
+	if err != nil {
+		return nil, fmt.Errorf("blob not found: %s", hash)
+	}
+
+	dagData, err := store.BuildDagFromStore(root, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetDagFileContent(&dagData.Dag)
+}
+
+// Reassemble the content of a single file dag, chunks are ordered by their label
+func GetDagFileContent(dag *merkle_dag.Dag) ([]byte, error) {
+	rootLeaf, ok := dag.Leafs[dag.Root]
+	if !ok {
+		return nil, fmt.Errorf("dag is missing its root leaf")
+	}
+
+	if len(rootLeaf.Links) == 0 {
+		return rootLeaf.Content, nil
+	}
+
+	links := []string{}
+	for _, link := range rootLeaf.Links {
+		links = append(links, link)
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		labelI, _ := strconv.Atoi(merkle_dag.GetLabel(links[i]))
+		labelJ, _ := strconv.Atoi(merkle_dag.GetLabel(links[j]))
+
+		return labelI < labelJ
+	})
+
+	var content []byte
+	for _, link := range links {
+		chunk, ok := dag.Leafs[link]
+		if !ok {
+			return nil, fmt.Errorf("dag is missing chunk %s", link)
+		}
+
+		content = append(content, chunk.Content...)
+	}
+
+	return content, nil
+}
+
+// The dag builder works from the file system so the blob is written to a temporary file named after its hash
+func storeBlobAsDag(store stores.Store, data []byte, hash string, mimeType string) (string, error) {
+	directory, err := os.MkdirTemp("", "blob-dag-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(directory)
+
+	fileName := hash
+	if extensions, err := mime.ExtensionsByType(mimeType); err == nil && len(extensions) > 0 {
+		fileName += extensions[0]
+	}
+
+	path := filepath.Join(directory, fileName)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+
+	dag, err := merkle_dag.CreateDag(path, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to create dag: %v", err)
+	}
+
+	if err := dag.Verify(); err != nil {
+		return "", fmt.Errorf("failed to verify dag: %v", err)
+	}
+
+	privateKey, publicKey, err := signing.DeserializePrivateKey(viper.GetString("private_key"))
+	if err != nil {
+		return "", fmt.Errorf("failed to deserialize relay private key: %v", err)
+	}
+
+	contentID, err := cid.Parse(dag.Root)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := signing.SignCID(contentID, privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign dag root: %v", err)
+	}
+
+	serializedPublicKey, err := signing.SerializePublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	dagData := &types.DagData{
+		PublicKey: *serializedPublicKey,
+		Signature: hex.EncodeToString(signature.Serialize()),
+		Dag:       *dag,
+	}
+
+	if err := store.StoreDag(dagData); err != nil {
+		return "", fmt.Errorf("failed to store dag: %v", err)
+	}
+
+	return dag.Root, nil
+}
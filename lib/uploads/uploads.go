@@ -0,0 +1,137 @@
+package uploads
+
+import (
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/scionic"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Upload policy shared by the blossom, nip-96 and scionic upload paths so that a file
+// rejected by one of them can't simply be uploaded through another
+
+// CheckUpload ensures the file is within the upload size limit, that the file type is permitted
+// by the relay settings and that the uploader has enough quota left for the file
+// The size can be 0 when it is not known yet (scionic uploads only receive the root leaf up front)
+func CheckUpload(store stores.Store, publicKey string, fileName string, mimeType string, size int64) error {
+	maxSize := MaxUploadSize()
+	if maxSize > 0 && size > maxSize {
+		return fmt.Errorf("file size %d exceeds the maximum upload size of %d bytes", size, maxSize)
+	}
+
+	if !isFileTypePermitted(fileName, mimeType) {
+		return fmt.Errorf("file type is not permitted by this relay")
+	}
+
+	if !viper.GetBool("enforce_storage_quotas") {
+		return nil
+	}
+
+	quota, err := GetStorageQuota(store, publicKey)
+	if err != nil {
+		return err
+	}
+
+	usage, err := store.GetStorageUsage(publicKey)
+	if err != nil {
+		return fmt.Errorf("failed to get storage usage: %v", err)
+	}
+
+	if usage+size > quota || (size == 0 && usage >= quota) {
+		return fmt.Errorf("storage quota exceeded (%d of %d bytes used)", usage, quota)
+	}
+
+	return nil
+}
+
+// RecordUpload adds a successful upload to the storage usage of the uploader
+func RecordUpload(store stores.Store, publicKey string, size int64) error {
+	return store.UpdateStorageUsage(publicKey, size)
+}
+
+// RecordDelete removes a deleted upload from the storage usage of the uploader
+func RecordDelete(store stores.Store, publicKey string, size int64) error {
+	return store.UpdateStorageUsage(publicKey, -size)
+}
+
+// MaxUploadSize returns the maximum size in bytes of a single upload, 0 means unlimited
+func MaxUploadSize() int64 {
+	return viper.GetInt64("max_upload_size")
+}
+
+// GetStorageQuota returns the number of bytes the public key is allowed to store based on their subscription tier
+func GetStorageQuota(store stores.Store, publicKey string) (int64, error) {
+	subscriber, err := store.GetSubscriber(publicKey)
+	if err != nil {
+		return 0, fmt.Errorf("no active subscription found")
+	}
+
+	if time.Now().After(subscriber.EndDate) {
+		return 0, fmt.Errorf("subscription has expired")
+	}
+
+	return ParseDataLimit(subscriber.Tier)
+}
+
+// ParseDataLimit converts a subscription tier data limit such as "5 GB per month" into bytes
+func ParseDataLimit(limit string) (int64, error) {
+	fields := strings.Fields(limit)
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("invalid data limit: %s", limit)
+	}
+
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid data limit: %s", limit)
+	}
+
+	var multiplier float64
+	switch strings.ToUpper(fields[1]) {
+	case "B":
+		multiplier = 1
+	case "KB":
+		multiplier = 1 << 10
+	case "MB":
+		multiplier = 1 << 20
+	case "GB":
+		multiplier = 1 << 30
+	case "TB":
+		multiplier = 1 << 40
+	default:
+		return 0, fmt.Errorf("invalid data limit unit: %s", fields[1])
+	}
+
+	return int64(value * multiplier), nil
+}
+
+// When there is no file name the extension is derived from the mime type instead
+func isFileTypePermitted(fileName string, mimeType string) bool {
+	if filepath.Ext(fileName) != "" || mimeType == "" {
+		return scionic.IsFilePermitted(fileName)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+
+	extensions, err := mime.ExtensionsByType(mediaType)
+	if err != nil || len(extensions) == 0 {
+		return scionic.IsFilePermitted(fileName)
+	}
+
+	for _, extension := range extensions {
+		if scionic.IsFilePermitted("file" + extension) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package uploads
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/memory"
+)
+
+func TestParseDataLimit(t *testing.T) {
+	tests := []struct {
+		limit    string
+		expected int64
+		valid    bool
+	}{
+		{"5 GB per month", 5 << 30, true},
+		{"500 MB per month", 500 << 20, true},
+		{"1.5 TB", 3 << 39, true},
+		{"10 kb", 10 << 10, true},
+		{"100 B", 100, true},
+		{"GB", 0, false},
+		{"five GB", 0, false},
+		{"5 PB", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.limit, func(t *testing.T) {
+			limit, err := ParseDataLimit(test.limit)
+			if (err == nil) != test.valid {
+				t.Fatalf("expected valid %v, got error %v", test.valid, err)
+			}
+
+			if limit != test.expected {
+				t.Errorf("expected %d bytes, got %d", test.expected, limit)
+			}
+		})
+	}
+}
+
+func TestStoreBlobAsDagIsOwnedByUploader(t *testing.T) {
+	viper.Set("private_key", nostr.GeneratePrivateKey())
+	viper.Set("blob_dag_threshold", 16)
+	defer viper.Set("private_key", "")
+	defer viper.Set("blob_dag_threshold", 0)
+
+	store := &memory.GravitonMemoryStore{}
+	if err := store.InitStore(""); err != nil {
+		t.Fatalf("failed to initialize store: %v", err)
+	}
+
+	data := bytes.Repeat([]byte("larger than the threshold "), 10)
+
+	hash, err := StoreBlob(store, data, "text/plain", "alice")
+	if err != nil {
+		t.Fatalf("failed to store blob: %v", err)
+	}
+
+	if _, err := store.GetBlobDag(hash); err != nil {
+		t.Fatalf("expected the blob to be converted into a dag: %v", err)
+	}
+
+	if err := store.DeleteBlob(hash, "bob"); err == nil {
+		t.Fatalf("expected someone who never uploaded the blob to be refused")
+	}
+
+	if content, err := GetBlob(store, hash); err != nil || !bytes.Equal(content, data) {
+		t.Fatalf("expected the blob to be kept, got error %v", err)
+	}
+
+	if err := store.DeleteBlob(hash, "alice"); err != nil {
+		t.Fatalf("failed to delete blob: %v", err)
+	}
+
+	if _, err := GetBlob(store, hash); err == nil {
+		t.Errorf("expected the blob and its dag to be deleted with the last owner")
+	}
+}
@@ -0,0 +1,268 @@
+package vanish
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/aggregates"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+const (
+	KindVanishRequest = 62
+	KindGiftWrap      = 1059
+
+	// Relay tag value for requests addressed to every relay
+	AllRelays = "ALL_RELAYS"
+)
+
+// IsAddressedToRelay checks the relay tags of a request to vanish include ALL_RELAYS or one of the urls in relay_urls
+func IsAddressedToRelay(request *nostr.Event) bool {
+	relayUrls := viper.GetStringSlice("relay_urls")
+
+	for _, tag := range request.Tags {
+		if len(tag) < 2 || tag[0] != "relay" {
+			continue
+		}
+
+		if tag[1] == AllRelays {
+			return true
+		}
+
+		for _, url := range relayUrls {
+			if normalizeUrl(tag[1]) == normalizeUrl(url) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Events are deleted in batches and the progress is saved after each of them
+const batchSize = 500
+
+// The worker is woken whenever a request is recorded, pending requests are kept in the statistics store
+// so a wake up that is missed while the worker is busy is picked up with the rest
+var wake = make(chan struct{}, 1)
+
+// Request records a request to vanish and hands it to the worker, events by the pubkey created before the
+// request are refused from then on even though deleting what was stored happens in the background
+func Request(store stores.Store, request *nostr.Event) (*types.VanishRequest, error) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+	stats := store.GetStatsStore()
+	if stats == nil {
+		return nil, fmt.Errorf("requests to vanish need a statistics store")
+	}
+
+	raw, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &types.VanishRequest{
+		EventID: request.ID,
+		Pubkey:  request.PubKey,
+		Until:   int64(request.CreatedAt),
+		Relay:   relayTag(request),
+		Reason:  request.Content,
+		Event:   string(raw),
+	}
+
+	if err := stats.SaveVanishRequest(record); err != nil {
+		return nil, fmt.Errorf("failed to record the request to vanish: %v", err)
+	}
+
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+
+	return record, nil
+}
+
+// ProcessRequests deletes what was stored by the pubkeys that asked to vanish, requests left unfinished by a
+// restart are resumed first and new requests are processed one at a time as they are recorded
+func ProcessRequests(store stores.Store) {
+	stats := store.GetStatsStore()
+	if stats == nil {
+		return
+	}
+
+	for {
+		requests, err := stats.GetPendingVanishRequests()
+		if err != nil {
+			log.Printf("Error loading pending requests to vanish: %v", err)
+		}
+
+		for i := range requests {
+			if err := Vanish(store, &requests[i]); err != nil {
+				log.Printf("Request to vanish %s is incomplete and will be resumed: %v", requests[i].EventID, err)
+			}
+		}
+
+		<-wake
+	}
+}
+
+// Vanish deletes everything a pubkey has stored on the relay up to the created_at of its request to vanish,
+// this includes its events, gift wraps sent to it, its blossom blobs, its scionic merkletrees, its cache entries
+// and its statistics, the request is marked as completed once nothing is left
+// Deleting what is already gone is a no-op so an interrupted request can be processed again
+func Vanish(store stores.Store, record *types.VanishRequest) error {
+	stats := store.GetStatsStore()
+	if stats == nil {
+		return fmt.Errorf("requests to vanish need a statistics store")
+	}
+
+	until := nostr.Timestamp(record.Until)
+	filters := []nostr.Filter{
+		{Authors: []string{record.Pubkey}, Until: &until, Limit: batchSize},
+		{Kinds: []int{KindGiftWrap}, Tags: nostr.TagMap{"p": []string{record.Pubkey}}, Until: &until, Limit: batchSize},
+	}
+
+	incomplete := false
+	for _, filter := range filters {
+		complete, err := deleteEvents(store, record, filter)
+		if err != nil {
+			return err
+		}
+
+		incomplete = incomplete || !complete
+	}
+
+	dags, err := store.GetDagsByPubkey(record.Pubkey)
+	if err != nil {
+		log.Printf("Error listing scionic merkletrees of vanished pubkey %s: %v", record.Pubkey, err)
+		incomplete = true
+	}
+
+	blobs, err := store.GetBlobsByPubkey(record.Pubkey)
+	if err != nil {
+		log.Printf("Error listing blobs of vanished pubkey %s: %v", record.Pubkey, err)
+		incomplete = true
+	}
+
+	// Blobs and trees that other pubkeys uploaded as well are kept for them, blobs that were converted into
+	// a scionic merkletree release the tree along with the blob
+	for _, hash := range blobs {
+		if err := store.DeleteBlob(hash, record.Pubkey); err != nil {
+			log.Printf("Error deleting blob %s of vanished pubkey %s: %v", hash, record.Pubkey, err)
+			incomplete = true
+			continue
+		}
+
+		record.BlobsDeleted++
+	}
+
+	for _, root := range dags {
+		if err := store.DeleteDag(root, record.Pubkey); err != nil {
+			log.Printf("Error deleting scionic merkletree %s of vanished pubkey %s: %v", root, record.Pubkey, err)
+			incomplete = true
+			continue
+		}
+
+		record.DagsDeleted++
+	}
+
+	if err := store.ClearPubkeyCache(record.Pubkey); err != nil {
+		log.Printf("Error clearing the cache of vanished pubkey %s: %v", record.Pubkey, err)
+		incomplete = true
+	}
+
+	if err := stats.DeletePubkeyStats(record.Pubkey); err != nil {
+		log.Printf("Error deleting the statistics of vanished pubkey %s: %v", record.Pubkey, err)
+		incomplete = true
+	}
+
+	record.Completed = !incomplete
+	if err := stats.SaveVanishRequest(record); err != nil {
+		return fmt.Errorf("failed to update the request to vanish: %v", err)
+	}
+
+	if incomplete {
+		return fmt.Errorf("not everything could be deleted")
+	}
+
+	log.Printf("Pubkey %s vanished: %d events, %d blobs and %d scionic merkletrees deleted", record.Pubkey, record.EventsDeleted, record.BlobsDeleted, record.DagsDeleted)
+
+	return nil
+}
+
+// Deletes the events matching the filter a batch at a time, the progress is saved after every batch
+// The events that fail to delete are skipped and false is returned so the request is resumed later
+func deleteEvents(store stores.Store, record *types.VanishRequest, filter nostr.Filter) (bool, error) {
+	failed := map[string]bool{}
+
+	for {
+		events, err := store.QueryEvents(filter)
+		if err != nil {
+			return false, fmt.Errorf("failed to query events: %v", err)
+		}
+
+		deleted := 0
+		for _, event := range events {
+			if failed[event.ID] || !filter.Matches(event) || event.ID == record.EventID {
+				continue
+			}
+
+			if err := aggregates.DeleteEvent(store, event); err != nil {
+				log.Printf("Error deleting event %s of vanished pubkey %s: %v", event.ID, record.Pubkey, err)
+				failed[event.ID] = true
+				continue
+			}
+
+			deleted++
+		}
+
+		if deleted == 0 {
+			return len(failed) == 0, nil
+		}
+
+		record.EventsDeleted += deleted
+		if err := store.GetStatsStore().SaveVanishRequest(record); err != nil {
+			return false, fmt.Errorf("failed to save the progress of the request to vanish: %v", err)
+		}
+	}
+}
+
+// CheckEvent refuses events from a pubkey that asked to vanish when they were created before the request
+func CheckEvent(stats stores.StatisticsStore, event *nostr.Event) error {
+	if stats == nil || event.Kind == KindVanishRequest {
+		return nil
+	}
+
+	until, err := stats.GetVanishedUntil(event.PubKey)
+	if err != nil {
+		log.Printf("Error checking if %s has vanished: %v", event.PubKey, err)
+		return nil
+	}
+
+	if until > 0 && int64(event.CreatedAt) <= until {
+		return fmt.Errorf("blocked: the author of this event has asked to vanish from this relay")
+	}
+
+	return nil
+}
+
+func relayTag(request *nostr.Event) string {
+	relays := []string{}
+	for _, tag := range request.Tags {
+		if len(tag) >= 2 && tag[0] == "relay" {
+			relays = append(relays, tag[1])
+		}
+	}
+
+	return strings.Join(relays, ",")
+}
+
+func normalizeUrl(url string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(url)), "/")
+}
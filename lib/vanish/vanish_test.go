@@ -0,0 +1,277 @@
+package vanish
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// Only the requests to vanish of the statistics store are implemented
+type vanishStats struct {
+	stores.StatisticsStore
+
+	mutex    sync.Mutex
+	requests map[string]types.VanishRequest
+	saves    int
+}
+
+func (stats *vanishStats) SaveVanishRequest(request *types.VanishRequest) error {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	stats.requests[request.EventID] = *request
+	stats.saves++
+	return nil
+}
+
+func (stats *vanishStats) GetPendingVanishRequests() ([]types.VanishRequest, error) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	pending := []types.VanishRequest{}
+	for _, request := range stats.requests {
+		if !request.Completed {
+			pending = append(pending, request)
+		}
+	}
+
+	return pending, nil
+}
+
+func (stats *vanishStats) request(eventID string) types.VanishRequest {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	return stats.requests[eventID]
+}
+
+func (stats *vanishStats) DeletePubkeyStats(pubkey string) error {
+	return nil
+}
+
+func (stats *vanishStats) UpdateEventAggregate(change *types.EventAggregate) error {
+	return nil
+}
+
+// Keeps events, blobs and scionic merkletrees in memory, deletes of the ids in failing return an error
+type vanishStore struct {
+	stores.Store
+
+	mutex   sync.Mutex
+	stats   *vanishStats
+	events  map[string]*nostr.Event
+	blobs   map[string][]string
+	dags    map[string][]string
+	failing map[string]bool
+}
+
+func newStore() *vanishStore {
+	return &vanishStore{
+		stats:   &vanishStats{requests: map[string]types.VanishRequest{}},
+		events:  map[string]*nostr.Event{},
+		blobs:   map[string][]string{},
+		dags:    map[string][]string{},
+		failing: map[string]bool{},
+	}
+}
+
+func (store *vanishStore) GetStatsStore() stores.StatisticsStore {
+	return store.stats
+}
+
+func (store *vanishStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	events := []*nostr.Event{}
+	for _, event := range store.events {
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	slices.SortFunc(events, func(a, b *nostr.Event) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[:filter.Limit]
+	}
+
+	return events, nil
+}
+
+func (store *vanishStore) DeleteEvent(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.failing[id] {
+		return fmt.Errorf("failed to delete %s", id)
+	}
+
+	delete(store.events, id)
+	return nil
+}
+
+func (store *vanishStore) GetBlobsByPubkey(pubkey string) ([]string, error) {
+	return store.blobs[pubkey], nil
+}
+
+func (store *vanishStore) GetDagsByPubkey(pubkey string) ([]string, error) {
+	return store.dags[pubkey], nil
+}
+
+func (store *vanishStore) DeleteBlob(hash string, pubkey string) error {
+	store.blobs[pubkey] = slices.DeleteFunc(store.blobs[pubkey], func(value string) bool { return value == hash })
+	return nil
+}
+
+func (store *vanishStore) DeleteDag(root string, pubkey string) error {
+	store.dags[pubkey] = slices.DeleteFunc(store.dags[pubkey], func(value string) bool { return value == root })
+	return nil
+}
+
+func (store *vanishStore) ClearPubkeyCache(pubkey string) error {
+	return nil
+}
+
+func (store *vanishStore) add(event *nostr.Event) {
+	store.events[event.ID] = event
+}
+
+func (store *vanishStore) remaining() int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return len(store.events)
+}
+
+func hexKey(value int) string {
+	return fmt.Sprintf("%064x", value)
+}
+
+func TestVanish(t *testing.T) {
+	pubkey := hexKey(1)
+	other := hexKey(2)
+
+	tests := []struct {
+		name      string
+		events    int
+		failing   int
+		completed bool
+		deleted   int
+	}{
+		{"a single batch", 10, 0, true, 10},
+		{"several batches", 2*batchSize + 20, 0, true, 2*batchSize + 20},
+		{"failed deletes are resumed later", batchSize + 5, 3, false, batchSize + 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newStore()
+
+			for i := 0; i < test.events; i++ {
+				store.add(&nostr.Event{ID: hexKey(1000 + i), PubKey: pubkey, Kind: 1, CreatedAt: 10})
+			}
+			for i := 0; i < test.failing; i++ {
+				store.failing[hexKey(1000+i)] = true
+			}
+
+			// Kept: created after the request, or by someone else and not a gift wrap to the pubkey
+			store.add(&nostr.Event{ID: hexKey(10), PubKey: pubkey, Kind: 1, CreatedAt: 30})
+			store.add(&nostr.Event{ID: hexKey(11), PubKey: other, Kind: 1, CreatedAt: 10, Tags: nostr.Tags{{"p", pubkey}}})
+			// Deleted: a gift wrap sent to the pubkey
+			store.add(&nostr.Event{ID: hexKey(12), PubKey: other, Kind: KindGiftWrap, CreatedAt: 10, Tags: nostr.Tags{{"p", pubkey}}})
+
+			store.blobs[pubkey] = []string{"blob"}
+			store.dags[pubkey] = []string{"root"}
+
+			record, err := Request(store, &nostr.Event{ID: hexKey(3), PubKey: pubkey, Kind: KindVanishRequest, CreatedAt: 20})
+			if err != nil {
+				t.Fatalf("failed to record request: %v", err)
+			}
+
+			err = Vanish(store, record)
+			if (err == nil) != test.completed {
+				t.Fatalf("expected completed %v, got error %v", test.completed, err)
+			}
+
+			saved := store.stats.request(record.EventID)
+			if saved.Completed != test.completed || saved.EventsDeleted != test.deleted+1 {
+				t.Fatalf("expected completed %v with %d events deleted, got %v with %d", test.completed, test.deleted+1, saved.Completed, saved.EventsDeleted)
+			}
+
+			if remaining := store.remaining(); remaining != test.failing+2 {
+				t.Errorf("expected %d events left, got %d", test.failing+2, remaining)
+			}
+
+			if saved.BlobsDeleted != 1 || saved.DagsDeleted != 1 {
+				t.Errorf("expected the blob and tree to be released, got %d and %d", saved.BlobsDeleted, saved.DagsDeleted)
+			}
+
+			if test.completed {
+				return
+			}
+
+			// Processing the request again once the deletes succeed finishes it off
+			store.failing = map[string]bool{}
+			if err := Vanish(store, &saved); err != nil {
+				t.Fatalf("failed to resume: %v", err)
+			}
+
+			if saved := store.stats.request(record.EventID); !saved.Completed || store.remaining() != 2 {
+				t.Errorf("expected the resumed request to complete, %d events left", store.remaining())
+			}
+		})
+	}
+}
+
+func TestProcessRequests(t *testing.T) {
+	store := newStore()
+
+	// Left pending by a restart
+	interrupted := hexKey(1)
+	store.add(&nostr.Event{ID: hexKey(100), PubKey: interrupted, Kind: 1, CreatedAt: 10})
+	store.stats.requests[hexKey(3)] = types.VanishRequest{EventID: hexKey(3), Pubkey: interrupted, Until: 20}
+
+	go ProcessRequests(store)
+
+	waitFor(t, func() bool { return store.stats.request(hexKey(3)).Completed })
+
+	// Recorded while the worker is running
+	requested := hexKey(2)
+	store.mutex.Lock()
+	store.add(&nostr.Event{ID: hexKey(101), PubKey: requested, Kind: 1, CreatedAt: 10})
+	store.mutex.Unlock()
+
+	if _, err := Request(store, &nostr.Event{ID: hexKey(4), PubKey: requested, Kind: KindVanishRequest, CreatedAt: 20}); err != nil {
+		t.Fatalf("failed to record request: %v", err)
+	}
+
+	waitFor(t, func() bool { return store.stats.request(hexKey(4)).Completed })
+
+	if remaining := store.remaining(); remaining != 0 {
+		t.Errorf("expected every event to be deleted, %d left", remaining)
+	}
+}
+
+func waitFor(t *testing.T, done func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the request to vanish to complete")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
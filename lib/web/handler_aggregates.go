@@ -0,0 +1,22 @@
+package web
+
+import (
+	"log"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/aggregates"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Recalculates the reaction, repost and zap totals from the stored events
+func rebuildAggregates(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Rebuild aggregates request received")
+
+	rebuilt, err := aggregates.Rebuild(store)
+	if err != nil {
+		log.Printf("Error rebuilding aggregates: %v", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
+	}
+
+	return c.JSON(fiber.Map{"events": rebuilt})
+}
@@ -0,0 +1,112 @@
+package web
+
+import (
+	"fmt"
+	"log"
+
+	types "github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/blocklist"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/gofiber/fiber/v2"
+)
+
+type blockHashRequest struct {
+	Hash     string `json:"hash"`
+	HashType string `json:"hash_type"`
+	Reason   string `json:"reason"`
+}
+
+func getBlocklist(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Get blocklist request received")
+
+	blockedHashes, err := store.GetStatsStore().GetBlockedHashes(c.Query("type"))
+	if err != nil {
+		log.Printf("Error fetching blocklist: %v", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
+	}
+
+	return c.JSON(blockedHashes)
+}
+
+func addToBlocklist(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Add to blocklist request received")
+
+	var request blockHashRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	if request.HashType == "" {
+		request.HashType = blocklist.HashTypeSha256
+	}
+
+	err := blocklist.Add(store.GetStatsStore(), request.HashType, request.Hash, request.Reason, getPanelActor(c))
+	if err != nil {
+		log.Printf("Error adding hash to blocklist: %v", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func removeFromBlocklist(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Remove from blocklist request received")
+
+	err := blocklist.Remove(store.GetStatsStore(), c.Params("hash"), getPanelActor(c))
+	if err != nil {
+		log.Printf("Error removing hash from blocklist: %v", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// Import a text file of hashes uploaded as the "file" form field
+func importBlocklist(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Import blocklist request received")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Blocklist file expected")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Failed to read blocklist file")
+	}
+	defer file.Close()
+
+	imported, err := blocklist.Import(store.GetStatsStore(), file, getPanelActor(c))
+	if err != nil {
+		log.Printf("Error importing blocklist: %v", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
+	}
+
+	return c.JSON(fiber.Map{"imported": imported})
+}
+
+func getBlocklistAudit(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Get blocklist audit request received")
+
+	entries, err := store.GetStatsStore().GetBlocklistAudit()
+	if err != nil {
+		log.Printf("Error fetching blocklist audit: %v", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
+	}
+
+	return c.JSON(entries)
+}
+
+// The panel user making the change is taken from the claims set by the jwt middleware
+func getPanelActor(c *fiber.Ctx) string {
+	claims, ok := c.Locals("user").(*types.JWTClaims)
+	if !ok {
+		return "unknown"
+	}
+
+	if claims.Email != "" {
+		return claims.Email
+	}
+
+	return fmt.Sprintf("user:%d", claims.UserID)
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"log"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Reports the logical and physical size of each compressible bucket
+func getCompressionStats(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Compression stats request received")
+
+	stats, err := store.GetCompressionStats()
+	if err != nil {
+		log.Printf("Error fetching compression stats: %v", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
+	}
+
+	var logicalBytes, physicalBytes int64
+	for _, bucketStats := range stats {
+		logicalBytes += bucketStats.LogicalBytes
+		physicalBytes += bucketStats.PhysicalBytes
+	}
+
+	return c.JSON(fiber.Map{
+		"buckets":        stats,
+		"logical_bytes":  logicalBytes,
+		"physical_bytes": physicalBytes,
+	})
+}
+
+// Re-encodes existing data using the current compression settings
+func migrateCompression(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Compression migration request received")
+
+	migrated, err := store.MigrateCompression()
+	if err != nil {
+		log.Printf("Error migrating compression: %v", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
+	}
+
+	return c.JSON(fiber.Map{"migrated": migrated})
+}
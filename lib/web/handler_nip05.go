@@ -0,0 +1,65 @@
+package web
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nip05"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+type nip05NameRequest struct {
+	Name   string `json:"name"`
+	Pubkey string `json:"pubkey"`
+}
+
+func getNip05Names(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Get nip-05 names request received")
+
+	subscribers, err := store.GetNamedSubscribers()
+	if err != nil {
+		log.Printf("Error fetching nip-05 names: %v", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
+	}
+
+	names := make([]nip05NameRequest, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		names = append(names, nip05NameRequest{Name: subscriber.Nip05Name, Pubkey: subscriber.Npub})
+	}
+
+	return c.JSON(names)
+}
+
+// The panel can assign reserved names and replaces any name the subscriber already had
+func setNip05Name(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Set nip-05 name request received")
+
+	var request nip05NameRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	if err := nip05.Register(store, request.Pubkey, request.Name); err != nil {
+		log.Printf("Error setting nip-05 name: %v", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func removeNip05Name(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Remove nip-05 name request received")
+
+	subscriber, err := nip05.GetSubscriberByName(store, c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).SendString(err.Error())
+	}
+
+	if err := nip05.Release(store, subscriber.Npub); err != nil {
+		log.Printf("Error removing nip-05 name: %v", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
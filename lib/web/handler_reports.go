@@ -0,0 +1,75 @@
+package web
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+type acceptReportsRequest struct {
+	Action string `json:"action"`
+}
+
+// Lists reported events and pubkeys, filtered by the status query parameter when it's set
+func getReportQueue(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Get report queue request received")
+
+	targets, err := store.GetStatsStore().GetReportTargets(c.Query("status"))
+	if err != nil {
+		log.Printf("Error fetching report queue: %v", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
+	}
+
+	return c.JSON(targets)
+}
+
+func getReportDetails(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Get report details request received")
+
+	target, err := store.GetStatsStore().GetReportTarget(c.Params("type"), c.Params("target"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).SendString("No reports found")
+	}
+
+	reports, err := store.GetStatsStore().GetReports(c.Params("type"), c.Params("target"))
+	if err != nil {
+		log.Printf("Error fetching reports: %v", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
+	}
+
+	return c.JSON(fiber.Map{
+		"target":  target,
+		"reports": reports,
+	})
+}
+
+func acceptReports(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Accept reports request received")
+
+	var request acceptReportsRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	err := moderation.AcceptReports(store, c.Params("type"), c.Params("target"), request.Action)
+	if err != nil {
+		log.Printf("Error accepting reports: %v", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func dismissReports(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Dismiss reports request received")
+
+	if err := moderation.DismissReports(store.GetStatsStore(), c.Params("type"), c.Params("target")); err != nil {
+		log.Printf("Error dismissing reports: %v", err)
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
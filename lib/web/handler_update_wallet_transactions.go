@@ -19,6 +19,7 @@ import (
 	types "github.com/HORNET-Storage/hornet-storage/lib"
 	"github.com/HORNET-Storage/hornet-storage/lib/signing"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/subscribers"
 )
 
 func updateWalletTransactions(c *fiber.Ctx, store stores.Store) error {
@@ -130,6 +131,10 @@ func processSubscriptionPayment(store stores.Store, address string, transaction
 		return fmt.Errorf("failed to fetch subscription tiers: %v", err)
 	}
 
+	// The subscriber is read, extended and saved whole so other updates have to wait
+	subscribers.Lock.Lock()
+	defer subscribers.Lock.Unlock()
+
 	// Retrieve the subscriber associated with the address by finding their npub
 	subscriber, err := store.GetSubscriberByAddress(address)
 	if err != nil {
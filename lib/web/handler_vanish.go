@@ -0,0 +1,26 @@
+package web
+
+import (
+	"log"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Lists the requests to vanish that have been processed and what was deleted for each of them
+func getVanishRequests(c *fiber.Ctx, store stores.Store) error {
+	log.Println("Vanish requests request received")
+
+	stats := store.GetStatsStore()
+	if stats == nil {
+		return c.Status(fiber.StatusNotImplemented).SendString("Requests to vanish are not available")
+	}
+
+	requests, err := stats.GetVanishRequests()
+	if err != nil {
+		log.Printf("Error fetching vanish requests: %v", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
+	}
+
+	return c.JSON(requests)
+}
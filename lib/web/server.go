@@ -111,6 +111,66 @@ func StartServer(store stores.Store) error {
 	})
 	secured.Post("/refresh-token", refreshToken)
 
+	// Hash blocklist management
+	secured.Get("/blocklist", func(c *fiber.Ctx) error {
+		return getBlocklist(c, store)
+	})
+	secured.Post("/blocklist", func(c *fiber.Ctx) error {
+		return addToBlocklist(c, store)
+	})
+	secured.Delete("/blocklist/:hash", func(c *fiber.Ctx) error {
+		return removeFromBlocklist(c, store)
+	})
+	secured.Post("/blocklist/import", func(c *fiber.Ctx) error {
+		return importBlocklist(c, store)
+	})
+	secured.Get("/blocklist/audit", func(c *fiber.Ctx) error {
+		return getBlocklistAudit(c, store)
+	})
+
+	// Moderation queue built from NIP-56 reports
+	secured.Get("/reports", func(c *fiber.Ctx) error {
+		return getReportQueue(c, store)
+	})
+	secured.Get("/reports/:type/:target", func(c *fiber.Ctx) error {
+		return getReportDetails(c, store)
+	})
+	secured.Post("/reports/:type/:target/accept", func(c *fiber.Ctx) error {
+		return acceptReports(c, store)
+	})
+	secured.Post("/reports/:type/:target/dismiss", func(c *fiber.Ctx) error {
+		return dismissReports(c, store)
+	})
+
+	// NIP-05 names hosted for subscribers
+	secured.Get("/nip05", func(c *fiber.Ctx) error {
+		return getNip05Names(c, store)
+	})
+	secured.Post("/nip05", func(c *fiber.Ctx) error {
+		return setNip05Name(c, store)
+	})
+	secured.Delete("/nip05/:name", func(c *fiber.Ctx) error {
+		return removeNip05Name(c, store)
+	})
+
+	// Reaction, repost and zap aggregates
+	secured.Post("/aggregates/rebuild", func(c *fiber.Ctx) error {
+		return rebuildAggregates(c, store)
+	})
+
+	// Audit log of NIP-62 requests to vanish
+	secured.Get("/vanish", func(c *fiber.Ctx) error {
+		return getVanishRequests(c, store)
+	})
+
+	// At-rest compression
+	secured.Get("/compression-stats", func(c *fiber.Ctx) error {
+		return getCompressionStats(c, store)
+	})
+	secured.Post("/compression/migrate", func(c *fiber.Ctx) error {
+		return migrateCompression(c, store)
+	})
+
 	port := viper.GetString("port")
 	p, err := strconv.Atoi(port)
 	if err != nil {
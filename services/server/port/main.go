@@ -4,10 +4,10 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"encoding/json"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
@@ -23,41 +23,45 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/spf13/viper"
 
-	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/protocol"
 
 	fiber_websocket "github.com/gofiber/contrib/websocket"
 
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation"
 	"github.com/HORNET-Storage/hornet-storage/lib/sessions/libp2p/middleware"
 	"github.com/HORNET-Storage/hornet-storage/lib/signing"
 	"github.com/HORNET-Storage/hornet-storage/lib/transports/libp2p"
 
+	"github.com/HORNET-Storage/hornet-storage/lib/aggregates"
+	"github.com/HORNET-Storage/hornet-storage/lib/blocklist"
+	"github.com/HORNET-Storage/hornet-storage/lib/uploads"
+	"github.com/HORNET-Storage/hornet-storage/lib/vanish"
 	"github.com/HORNET-Storage/hornet-storage/lib/web"
 
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/count"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/ephemeral"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/filter"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind0"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/groups"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind1"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind10000"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind10001"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind10002"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind10050"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind1059"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind1063"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind1984"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind3"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30000"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30008"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30009"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30023"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30079"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind40"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind41"
 	kind411creator "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind411"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind42"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind43"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind44"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind5"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind6"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind7"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind62"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind8"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind9372"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind9373"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind9735"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind9802"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/lists"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/policy"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/universal"
 
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/scionic/download"
@@ -80,11 +84,52 @@ func init() {
 	viper.SetDefault("RelayName", "HORNETS")
 	viper.SetDefault("RelayDescription", "The best relay ever.")
 	viper.SetDefault("RelayPubkey", "")
-	viper.SetDefault("RelaySupportedNips", []int{1, 11, 2, 9, 18, 23, 24, 25, 51, 56, 57, 42, 45, 50, 65, 116})
 	viper.SetDefault("RelayContact", "support@hornets.net")
 	viper.SetDefault("RelaySoftware", "golang")
 	viper.SetDefault("RelayVersion", "0.0.1")
 	viper.SetDefault("RelayDHTkey", "")
+	viper.SetDefault("RelayIcon", "")
+	viper.SetDefault("RelayPaymentsUrl", "")
+	viper.SetDefault("nip94_auto_generate", false)
+	viper.SetDefault("max_upload_size", 104857600) // 100 MB, 0 disables the limit
+	viper.SetDefault("enforce_storage_quotas", false)
+	viper.SetDefault("blob_dag_threshold", 0) // Blobs larger than this many bytes are stored as scionic dags, 0 disables
+	viper.SetDefault("blocklist_file", "")
+	viper.SetDefault("blocklist_dhash_threshold", 10)   // Maximum hamming distance for an image to match a blocked dhash
+	viper.SetDefault("compression_buckets", []string{}) // Bucket names or prefixes such as "content" or "kind:*"
+	viper.SetDefault("compression_min_size", 256)       // Values smaller than this many bytes are never compressed
+	viper.SetDefault("compression_migrate_on_start", false)
+	viper.SetDefault("aggregates_rebuild_on_start", false)
+	viper.SetDefault("restricted_read_kinds", []int{4, 14, 1059})  // Only served to the authenticated author or p tagged recipients
+	viper.SetDefault("gift_wrap_retention_days", 0)                // Gift wraps older than this are deleted, 0 keeps them until they expire
+	viper.SetDefault("min_pow_difficulty", 0)                      // NIP-13 leading zero bits required for every event, 0 disables
+	viper.SetDefault("min_pow_difficulty_kinds", map[string]int{}) // Per kind minimum such as {"1": 20}
+	viper.SetDefault("min_pow_difficulty_non_subscribers", 0)      // Minimum for authors without an active subscription
+	viper.SetDefault("max_message_length", 0)                      // Largest websocket message accepted in bytes, 0 disables
+	viper.SetDefault("max_subscriptions", 0)                       // Open subscriptions allowed per connection, 0 disables
+	viper.SetDefault("max_filters", 0)                             // Filters allowed in a single REQ, 0 disables
+	viper.SetDefault("created_at_lower_limit", 0)                  // Seconds in the past an event may be dated, 0 disables
+	viper.SetDefault("created_at_upper_limit", 900)                // Seconds in the future an event may be dated
+	viper.SetDefault("max_content_length", 131072)                 // Longest event content accepted in bytes, 0 disables
+	viper.SetDefault("max_event_tags", 5000)                       // Most tags an event may have, 0 disables
+	viper.SetDefault("max_tag_value_length", 4096)                 // Longest value accepted in any tag in bytes except zap receipt descriptions, 0 disables
+	viper.SetDefault("max_event_size", 262144)                     // Largest serialized event accepted in bytes, 0 disables
+	viper.SetDefault("admin_pubkeys", []string{})                  // Hex pubkeys allowed to use the NIP-86 management api
+	viper.SetDefault("auth_required", false)                       // Websocket connections must authenticate with NIP-42 before sending EVENT, REQ or COUNT
+	viper.SetDefault("restrict_to_allowed_pubkeys", false)         // Only accept events from pubkeys on the allowed list
+	viper.SetDefault("allow_group_creation", true)                 // Anyone can create a NIP-29 group with kind 9007
+	viper.SetDefault("group_late_publication_window", 3600)        // Group events older than this many seconds are refused, 0 disables
+	viper.SetDefault("nip05_relays", []string{})                   // Relays advertised for every name, defaults to this relay
+	viper.SetDefault("nip05_require_active_subscription", true)    // Only serve names of subscribers with an active subscription
+	viper.SetDefault("zap_verify_lnurl_pubkey", false)             // Check zap receipts are signed by the nostrPubkey of the recipient's lnurl server
+	viper.SetDefault("report_hide_threshold", 10)                  // Reported content is hidden pending review once its report weight reaches this, 0 disables
+	viper.SetDefault("report_trusted_pubkeys", []string{})         // Pubkeys whose reports count for more, admin_pubkeys are always trusted
+	viper.SetDefault("report_trusted_weight", 5)                   // Weight of a trusted report, subscriber reports count as 1 and other reports are only kept for review
+	viper.SetDefault("relay_urls", []string{})                     // Urls this relay is reached at, requests to vanish must name one of them or ALL_RELAYS
+	viper.SetDefault("kind_policies", map[string]interface{}{})    // Per kind policies such as {"31990": {"storage": "addressable", "required_tags": ["d"]}}
+
+	// NIP-05 names only the panel admin can assign
+	viper.SetDefault("nip05_reserved_names", []string{"_", "admin", "administrator", "root", "relay", "support", "help", "abuse", "postmaster", "webmaster"})
 
 	// Set default relay settings (including Mode)
 	viper.SetDefault("relay_settings", map[string]interface{}{
@@ -214,10 +259,46 @@ func main() {
 		log.Fatal(err)
 	}
 
-	// Create and store kind 411 event
-	if err := kind411creator.CreateKind411Event(privateKey, publicKey, store); err != nil {
-		log.Printf("Failed to create kind 411 event: %v", err)
-		return
+	// Refuse libp2p streams from ips blocked through relay management
+	middleware.SetConnectionFilter(func(ip string) bool {
+		return moderation.IsIPBlocked(store.GetStatsStore(), ip)
+	})
+
+	// Bring existing data in line with the configured compression buckets
+	if viper.GetBool("compression_migrate_on_start") {
+		migrated, err := store.MigrateCompression()
+		if err != nil {
+			log.Printf("Failed to migrate compression: %v", err)
+		} else {
+			log.Printf("Re-encoded %d values with the current compression settings", migrated)
+		}
+	}
+
+	// Recalculate the reaction, repost and zap totals from the stored events
+	if viper.GetBool("aggregates_rebuild_on_start") {
+		rebuilt, err := aggregates.Rebuild(store)
+		if err != nil {
+			log.Printf("Failed to rebuild aggregates: %v", err)
+		} else {
+			log.Printf("Rebuilt aggregates from %d events", rebuilt)
+		}
+	}
+
+	// Import the configured blocklist file so known bad hashes are blocked from the start
+	if blocklistFile := viper.GetString("blocklist_file"); blocklistFile != "" {
+		file, err := os.Open(blocklistFile)
+		if err != nil {
+			log.Printf("Failed to open blocklist file: %v", err)
+		} else {
+			imported, err := blocklist.Import(store.GetStatsStore(), file, "config")
+			if err != nil {
+				log.Printf("Failed to import blocklist file: %v", err)
+			} else if imported > 0 {
+				log.Printf("Imported %d blocklist entries from %s", imported, blocklistFile)
+			}
+
+			file.Close()
+		}
 	}
 
 	// Stream Handlers
@@ -247,10 +328,39 @@ func main() {
 		}
 
 		err = signing.VerifyCIDSignature(parsedSignature, contentID, publicKey)
-		return err == nil
+		if err != nil {
+			return false
+		}
+
+		// The full size of the dag isn't known until every leaf has arrived so only the file type
+		// and remaining quota can be checked here, the upload is recorded once it completes
+		err = uploads.CheckUpload(store, *pubKey, rootLeaf.ItemName, "", 0)
+		if err != nil {
+			log.Printf("Rejected dag upload %s: %v", rootLeaf.Hash, err)
+			return false
+		}
+
+		return true
 	}
 
-	handleUpload := func(dag *merkle_dag.Dag, pubKey *string) {}
+	handleUpload := func(dag *merkle_dag.Dag, pubKey *string) {
+		var size int64
+		for _, leaf := range dag.Leafs {
+			size += int64(len(leaf.Content))
+		}
+
+		if err := uploads.RecordUpload(store, *pubKey, size); err != nil {
+			log.Printf("Failed to record storage usage for %s: %v", *pubKey, err)
+		}
+
+		// Publish a relay signed NIP-94 event describing the uploaded file if enabled
+		if viper.GetBool("nip94_auto_generate") {
+			_, err := kind1063.CreateDagMetadataEvent(store, dag, *pubKey)
+			if err != nil {
+				log.Printf("Failed to create file metadata event for dag %s: %v", dag.Root, err)
+			}
+		}
+	}
 
 	upload.AddUploadHandlerForLibp2p(ctx, host, store, canUpload, handleUpload)
 
@@ -293,40 +403,96 @@ func main() {
 
 	}
 
+	// Event validation checks subscriptions and the relay management lists
+	nostr.SetValidationStore(store)
+
+	// Load the NIP-29 groups hosted on this relay and enforce their membership
+	if err := groups.Initialize(store); err != nil {
+		log.Printf("Failed to load groups: %v", err)
+	}
+
 	// Register Our Nostr Stream Handlers
 	if settings.Mode == "unlimited" {
 		log.Println("Using universal stream handler because Mode set to 'unlimited'")
 		nostr.RegisterHandler("universal", universal.BuildUniversalHandler(store))
 	} else if settings.Mode == "smart" {
 		log.Println("Using specific stream handlers because Mode set to 'smart'")
-		nostr.RegisterHandler("kind/0", kind0.BuildKind0Handler(store))
 		nostr.RegisterHandler("kind/1", kind1.BuildKind1Handler(store))
-		nostr.RegisterHandler("kind/3", kind3.BuildKind3Handler(store))
 		nostr.RegisterHandler("kind/5", kind5.BuildKind5Handler(store))
-		nostr.RegisterHandler("kind/6", kind6.BuildKind6Handler(store))
-		nostr.RegisterHandler("kind/7", kind7.BuildKind7Handler(store))
 		nostr.RegisterHandler("kind/8", kind8.BuildKind8Handler(store))
+		nostr.RegisterHandler("kind/40", kind40.BuildKind40Handler(store))
+		nostr.RegisterHandler("kind/41", kind41.BuildKind41Handler(store))
+		nostr.RegisterHandler("kind/42", kind42.BuildKind42Handler(store))
+		nostr.RegisterHandler("kind/43", kind43.BuildKind43Handler(store))
+		nostr.RegisterHandler("kind/44", kind44.BuildKind44Handler(store))
+		nostr.RegisterHandler("kind/62", kind62.BuildKind62Handler(store))
+		nostr.RegisterHandler("kind/1063", kind1063.BuildKind1063Handler(store))
+		nostr.RegisterHandler("kind/1059", kind1059.BuildKind1059Handler(store))
 		nostr.RegisterHandler("kind/1984", kind1984.BuildKind1984Handler(store))
 		nostr.RegisterHandler("kind/9735", kind9735.BuildKind9735Handler(store))
-		nostr.RegisterHandler("kind/9372", kind9372.BuildKind9372Handler(store))
-		nostr.RegisterHandler("kind/9373", kind9373.BuildKind9373Handler(store))
-		nostr.RegisterHandler("kind/9802", kind9802.BuildKind9802Handler(store))
+		for kind := groups.KindPutUser; kind <= 9020; kind++ {
+			nostr.RegisterHandler("kind/"+strconv.Itoa(kind), groups.BuildModerationHandler(store))
+		}
+		nostr.RegisterHandler("kind/9021", groups.BuildJoinRequestHandler(store))
+		nostr.RegisterHandler("kind/9022", groups.BuildLeaveRequestHandler(store))
 		nostr.RegisterHandler("kind/10000", kind10000.BuildKind10000Handler(store))
-		nostr.RegisterHandler("kind/10001", kind10001.BuildKind10001Handler(store))
-		nostr.RegisterHandler("kind/10002", kind10002.BuildKind10002Handler(store))
+		nostr.RegisterHandler("kind/10050", kind10050.BuildKind10050Handler(store))
 		nostr.RegisterHandler("kind/11011", kind11011.BuildKind11011Handler(store))
-		nostr.RegisterHandler("kind/30000", kind30000.BuildKind30000Handler(store))
 		nostr.RegisterHandler("kind/30008", kind30008.BuildKind30008Handler(store))
-		nostr.RegisterHandler("kind/30009", kind30009.BuildKind30009Handler(store))
-		nostr.RegisterHandler("kind/30023", kind30023.BuildKind30023Handler(store))
-		nostr.RegisterHandler("kind/30079", kind30079.BuildKind30079Handler(store))
+		for _, kind := range lists.Kinds() {
+			nostr.RegisterHandler("kind/"+strconv.Itoa(kind), lists.BuildListHandler(store))
+		}
+
+		// Every other kind goes through the shared pipeline described by its policy and the policy handler also
+		// serves kinds enabled through DynamicKinds, a policy for a kind with its own handler above would never
+		// be applied so the relay refuses to start rather than silently ignore the operator's rules
+		if err := policy.LoadPolicies(); err != nil {
+			log.Fatalf("Failed to load kind policies: %v", err)
+		}
+
+		policyHandler := policy.BuildHandler(store)
+		for _, kindPolicy := range policy.Policies() {
+			name := "kind/" + strconv.Itoa(kindPolicy.Kind)
+			if nostr.GetHandler(name) != nil {
+				log.Fatalf("Kind %d has its own handler so its entry in kind_policies can't be applied, remove it", kindPolicy.Kind)
+			}
+
+			nostr.RegisterHandler(name, policyHandler)
+		}
+		nostr.RegisterHandler("policy", policyHandler)
 	} else {
 		log.Fatalf("Unknown settings mode: %s, exiting", settings.Mode)
 	}
 
+	// Banned events and pubkeys are never served even if they were stored before the ban,
+	// reported content over the hide threshold is held back until it has been reviewed
+	nostr.RegisterQueryFilter(moderation.BuildQueryFilter(store.GetStatsStore()))
+
+	// Channel messages hidden or muted by the channel creator are left out of query results
+	nostr.RegisterQueryFilter(kind43.BuildHiddenMessageFilter(store))
+
+	// Remove gift wraps once they expire or pass the retention period, only when this relay accepts them
+	if nostr.GetHandler("kind/1059") != nil {
+		go kind1059.ExpireGiftWraps(store)
+	}
+
+	// Delete what was stored by pubkeys that asked to vanish, resuming requests a restart interrupted
+	if nostr.GetHandler("kind/62") != nil {
+		go vanish.ProcessRequests(store)
+	}
+
+	// Ephemeral events are accepted in every mode and broadcast without being stored
+	nostr.RegisterHandler("ephemeral", ephemeral.BuildEphemeralHandler())
+
 	nostr.RegisterHandler("filter", filter.BuildFilterHandler(store))
 	nostr.RegisterHandler("count", count.BuildCountsHandler(store))
 
+	// Create and store kind 411 event, the supported nips come from the handlers registered above
+	if err := kind411creator.CreateKind411Event(privateKey, publicKey, store); err != nil {
+		log.Printf("Failed to create kind 411 event: %v", err)
+		return
+	}
+
 	// Auth event not supported for the libp2p connections yet
 	//nostr.RegisterHandler("auth", auth.BuildAuthHandler(store))
 
@@ -334,33 +500,7 @@ func main() {
 	for kind := range nostr.GetHandlers() {
 		handler := nostr.GetHandler(kind)
 
-		wrapper := func(stream network.Stream) {
-			read := func() ([]byte, error) {
-				decoder := json.NewDecoder(stream)
-
-				var rawMessage json.RawMessage
-				err := decoder.Decode(&rawMessage)
-				if err != nil {
-					return nil, err
-				}
-
-				return rawMessage, nil
-			}
-
-			write := func(messageType string, params ...interface{}) {
-				response := nostr.BuildResponse(messageType, params)
-
-				if len(response) > 0 {
-					stream.Write(response)
-				}
-			}
-
-			handler(read, write)
-
-			stream.Close()
-		}
-
-		host.SetStreamHandler(protocol.ID("/nostr/event/"+kind), middleware.SessionMiddleware(host)(wrapper))
+		host.SetStreamHandler(protocol.ID("/nostr/event/"+kind), middleware.SessionMiddleware(host)(libp2p.BuildStreamHandler(handler)))
 	}
 
 	// Web Panel
@@ -17,22 +17,14 @@ import (
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/nbd-wtf/go-nostr"
 
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind0"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind1"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind10000"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind1984"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind3"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30000"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30008"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30009"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30023"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind5"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind6"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind7"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind8"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind9372"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind9373"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind9735"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/policy"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/universal"
 	"github.com/HORNET-Storage/hornet-storage/lib/signing"
 	sync "github.com/HORNET-Storage/hornet-storage/lib/sync"
@@ -154,23 +146,28 @@ func setupStore(basepath string) *stores_graviton.GravitonStore {
 		return nil
 	}
 
+	if err := policy.LoadPolicies(); err != nil {
+		return nil
+	}
+	policyHandler := policy.BuildHandler(store)
+
 	handlers.RegisterHandler("universal", universal.BuildUniversalHandler(store))
-	handlers.RegisterHandler("kind/0", kind0.BuildKind0Handler(store))
+	handlers.RegisterHandler("kind/0", policyHandler)
 	handlers.RegisterHandler("kind/1", kind1.BuildKind1Handler(store))
-	handlers.RegisterHandler("kind/3", kind3.BuildKind3Handler(store))
+	handlers.RegisterHandler("kind/3", policyHandler)
 	handlers.RegisterHandler("kind/5", kind5.BuildKind5Handler(store))
-	handlers.RegisterHandler("kind/6", kind6.BuildKind6Handler(store))
-	handlers.RegisterHandler("kind/7", kind7.BuildKind7Handler(store))
+	handlers.RegisterHandler("kind/6", policyHandler)
+	handlers.RegisterHandler("kind/7", policyHandler)
 	handlers.RegisterHandler("kind/8", kind8.BuildKind8Handler(store))
 	handlers.RegisterHandler("kind/1984", kind1984.BuildKind1984Handler(store))
 	handlers.RegisterHandler("kind/9735", kind9735.BuildKind9735Handler(store))
-	handlers.RegisterHandler("kind/9372", kind9372.BuildKind9372Handler(store))
-	handlers.RegisterHandler("kind/9373", kind9373.BuildKind9373Handler(store))
-	handlers.RegisterHandler("kind/30023", kind30023.BuildKind30023Handler(store))
+	handlers.RegisterHandler("kind/9372", policyHandler)
+	handlers.RegisterHandler("kind/9373", policyHandler)
+	handlers.RegisterHandler("kind/30023", policyHandler)
 	handlers.RegisterHandler("kind/10000", kind10000.BuildKind10000Handler(store))
-	handlers.RegisterHandler("kind/30000", kind30000.BuildKind30000Handler(store))
+	handlers.RegisterHandler("kind/30000", policyHandler)
 	handlers.RegisterHandler("kind/30008", kind30008.BuildKind30008Handler(store))
-	handlers.RegisterHandler("kind/30009", kind30009.BuildKind30009Handler(store))
+	handlers.RegisterHandler("kind/30009", policyHandler)
 
 	return store
 }